@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2018-02-01/web"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+var appServicePlanPattern = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)/resourceGroups/([^/]+)/providers/Microsoft\.Web/serverfarms/([^/]+)$`)
+
+// isAppServicePlanID decides if a string is the resource ID of an App Service plan, for example:
+// /subscriptions/{subscription}/resourceGroups/{group}/providers/Microsoft.Web/serverfarms/{name}
+func isAppServicePlanID(subject string) bool {
+	return appServicePlanPattern.MatchString(subject)
+}
+
+// getAppServicePlan reads an existing App Service plan from Azure Resource Manager.
+func (s *session) getAppServicePlan(ctx context.Context, authorizer autorest.Authorizer, id string) (plan web.AppServicePlan, err error) {
+	parts := appServicePlanPattern.FindStringSubmatch(id)
+	if parts == nil {
+		err = fmt.Errorf("%q is not the resource ID of an app service plan", id)
+		return
+	}
+	subscriptionID, resourceGroup, name := parts[1], parts[2], parts[3]
+
+	plan, err = newAppServicePlansClient(s, subscriptionID, authorizer).Get(ctx, resourceGroup, name)
+	if err != nil {
+		err = fmt.Errorf("unable to read app service plan %q: %v", id, err)
+	} else if plan.Response.Response != nil && plan.StatusCode == http.StatusNotFound {
+		err = fmt.Errorf("app service plan %q does not exist", id)
+	}
+	return
+}
+
+// normalizeRegion allows the display name of an Azure Region, like "West US 2", to be compared to its name, like
+// "westus2".
+func normalizeRegion(region string) string {
+	return strings.ToLower(strings.Replace(region, " ", "", -1))
+}
+
+// checkAppServicePlan ensures that a site created in location can run a container for hostOS in plan. Sites must be in
+// the same region as their plan. Linux containers can only be run in Linux plans, which are "reserved", and Windows
+// containers can only be run in plans which isolate them with Hyper-V.
+func checkAppServicePlan(plan web.AppServicePlan, location, hostOS string) error {
+	if normalizeRegion(to.String(plan.Location)) != normalizeRegion(location) {
+		return fmt.Errorf("app service plan is in %q, but the site would be created in %q", to.String(plan.Location), location)
+	}
+
+	var reserved, hyperV bool
+	if plan.AppServicePlanProperties != nil {
+		reserved, hyperV = to.Bool(plan.Reserved), to.Bool(plan.IsXenon)
+	}
+
+	if strings.EqualFold(hostOS, HostOSWindows) {
+		if !hyperV {
+			return fmt.Errorf("app service plan is not a Windows container plan, which is required to run Windows containers")
+		}
+	} else if !reserved {
+		return fmt.Errorf("app service plan is not a Linux plan, which is required to run containers")
+	}
+
+	if plan.Sku != nil && strings.EqualFold(to.String(plan.Sku.Tier), "Dynamic") {
+		return fmt.Errorf("app service plan has pricing tier %q, which can't host containers", to.String(plan.Sku.Name))
+	}
+	return nil
+}
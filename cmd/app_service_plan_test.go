@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2018-02-01/web"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func Test_isAppServicePlanID(t *testing.T) {
+	testCases := map[string]bool{
+		"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/shared/providers/Microsoft.Web/serverfarms/shared-plan": true,
+		"/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/shared/providers/microsoft.web/serverFarms/shared-plan": true,
+		"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/shared/providers/Microsoft.Web/sites/contoso":           false,
+		"shared-plan": false,
+	}
+
+	for subject, want := range testCases {
+		if got := isAppServicePlanID(subject); got != want {
+			t.Logf("%q got: %v want: %v", subject, got, want)
+			t.Fail()
+		}
+	}
+}
+
+type fakeAppServicePlansClient struct {
+	plans map[string]web.AppServicePlan
+	got   []string
+}
+
+func (f *fakeAppServicePlansClient) Get(ctx context.Context, resourceGroupName string, name string) (web.AppServicePlan, error) {
+	f.got = append(f.got, resourceGroupName+"/"+name)
+	if plan, ok := f.plans[resourceGroupName+"/"+name]; ok {
+		plan.Response = autorest.Response{Response: &http.Response{StatusCode: http.StatusOK}}
+		return plan, nil
+	}
+	return web.AppServicePlan{Response: autorest.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}}, nil
+}
+
+func Test_getAppServicePlan(t *testing.T) {
+	const id = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/shared/providers/Microsoft.Web/serverfarms/shared-plan"
+
+	plans := &fakeAppServicePlansClient{plans: map[string]web.AppServicePlan{
+		"shared/shared-plan": {
+			Location:                 to.StringPtr("West US 2"),
+			Sku:                      &web.SkuDescription{Name: to.StringPtr("P1v2"), Tier: to.StringPtr("PremiumV2")},
+			AppServicePlanProperties: &web.AppServicePlanProperties{Reserved: to.BoolPtr(true)},
+		},
+	}}
+
+	var gotSubscription string
+	originalClient := newAppServicePlansClient
+	defer func() {
+		newAppServicePlansClient = originalClient
+	}()
+	newAppServicePlansClient = func(s *session, subscriptionID string, authorizer autorest.Authorizer) appServicePlansClient {
+		gotSubscription = subscriptionID
+		return plans
+	}
+
+	plan, err := newSession(azure.PublicCloud, nil).getAppServicePlan(context.Background(), autorest.NullAuthorizer{}, id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if want := "00000000-0000-0000-0000-000000000000"; gotSubscription != want {
+		t.Logf("got subscription: %q want: %q", gotSubscription, want)
+		t.Fail()
+	}
+
+	if err = checkAppServicePlan(plan, "westus2", HostOSLinux); err != nil {
+		t.Error(err)
+	}
+
+	if err = checkAppServicePlan(plan, "eastus", HostOSLinux); err == nil {
+		t.Log("expected an error when the plan is in another region")
+		t.Fail()
+	}
+
+	if _, err = newSession(azure.PublicCloud, nil).getAppServicePlan(context.Background(), autorest.NullAuthorizer{}, id+"-missing"); err == nil {
+		t.Log("expected an error for a plan that doesn't exist")
+		t.Fail()
+	}
+}
+
+func Test_checkAppServicePlan(t *testing.T) {
+	windows := web.AppServicePlan{
+		Location:                 to.StringPtr("westus2"),
+		Sku:                      &web.SkuDescription{Tier: to.StringPtr("Standard")},
+		AppServicePlanProperties: &web.AppServicePlanProperties{},
+	}
+	if err := checkAppServicePlan(windows, "westus2", HostOSLinux); err == nil {
+		t.Log("expected an error for a plan which isn't Linux")
+		t.Fail()
+	}
+
+	if err := checkAppServicePlan(windows, "westus2", HostOSWindows); err == nil {
+		t.Log("expected an error for a Windows plan which can't run containers")
+		t.Fail()
+	}
+
+	windows.IsXenon = to.BoolPtr(true)
+	if err := checkAppServicePlan(windows, "westus2", HostOSWindows); err != nil {
+		t.Error(err)
+	}
+
+	consumption := web.AppServicePlan{
+		Location:                 to.StringPtr("westus2"),
+		Sku:                      &web.SkuDescription{Name: to.StringPtr("Y1"), Tier: to.StringPtr("Dynamic")},
+		AppServicePlanProperties: &web.AppServicePlanProperties{Reserved: to.BoolPtr(true)},
+	}
+	if err := checkAppServicePlan(consumption, "westus2", HostOSLinux); err == nil {
+		t.Log("expected an error for a consumption plan")
+		t.Fail()
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// parseAppSettings interprets values of the form "key=value" as App Settings. Keys must not be empty, and must not be
+// repeated; like App Service, keys which differ only by case are considered the same.
+func parseAppSettings(values []string) (map[string]string, error) {
+	settings := make(map[string]string, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, raw := range values {
+		separator := strings.Index(raw, "=")
+		if separator <= 0 {
+			return nil, fmt.Errorf("app setting %q is not of the form key=value", raw)
+		}
+
+		key, value := raw[:separator], raw[separator+1:]
+		if _, ok := seen[strings.ToUpper(key)]; ok {
+			return nil, fmt.Errorf("app setting %q was provided more than once", key)
+		}
+		seen[strings.ToUpper(key)] = struct{}{}
+		settings[key] = value
+	}
+	return settings, nil
+}
+
+// appSettingsParameter formats App Settings as the template expects them, sorted by key so that the parameters
+// are the same each time they are generated.
+func appSettingsParameter(settings map[string]string) []map[string]string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	formatted := make([]map[string]string, 0, len(keys))
+	for _, key := range keys {
+		formatted = append(formatted, map[string]string{
+			"name":  key,
+			"value": settings[key],
+		})
+	}
+	return formatted
+}
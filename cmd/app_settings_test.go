@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseAppSettings(t *testing.T) {
+	got, err := parseAppSettings([]string{"GO_ENV=staging", "ADDR=0.0.0.0", "GREETING=a=b", "EMPTY="})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := map[string]string{
+		"GO_ENV":   "staging",
+		"ADDR":     "0.0.0.0",
+		"GREETING": "a=b",
+		"EMPTY":    "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Logf("got: %v want: %v", got, want)
+		t.Fail()
+	}
+
+	for _, invalid := range [][]string{
+		{"GO_ENV"},
+		{"=production"},
+		{"PORT=3000", "PORT=8080"},
+		{"port=3000", "PORT=8080"},
+	} {
+		if _, err := parseAppSettings(invalid); err == nil {
+			t.Logf("expected an error for: %v", invalid)
+			t.Fail()
+		}
+	}
+}
+
+func Test_appSettingsParameter(t *testing.T) {
+	got := appSettingsParameter(map[string]string{"PORT": "3000", "ADDR": "0.0.0.0"})
+	want := []map[string]string{
+		{"name": "ADDR", "value": "0.0.0.0"},
+		{"name": "PORT", "value": "3000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Logf("got: %v want: %v", got, want)
+		t.Fail()
+	}
+}
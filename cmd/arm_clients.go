@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/mysql/mgmt/2017-12-01/mysql"
+	"github.com/Azure/azure-sdk-for-go/services/postgresql/mgmt/2017-12-01/postgresql"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2018-02-01/web"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// resourceGroupsClient is the subset of `resources.GroupsClient` used while provisioning.
+type resourceGroupsClient interface {
+	CheckExistence(ctx context.Context, resourceGroupName string) (autorest.Response, error)
+	Get(ctx context.Context, resourceGroupName string) (resources.Group, error)
+	CreateOrUpdate(ctx context.Context, resourceGroupName string, parameters resources.Group) (resources.Group, error)
+}
+
+// deploymentsClient is the subset of `resources.DeploymentsClient` used while provisioning. Waiting on a deployment is
+// part of the interface, rather than left to the future, so that the whole exchange can be replaced in tests.
+type deploymentsClient interface {
+	CreateOrUpdate(ctx context.Context, resourceGroupName string, deploymentName string, parameters resources.Deployment) (resources.DeploymentsCreateOrUpdateFuture, error)
+	WaitForCompletion(ctx context.Context, future resources.DeploymentsCreateOrUpdateFuture) error
+	Get(ctx context.Context, resourceGroupName string, deploymentName string) (resources.DeploymentExtended, error)
+}
+
+// appServicePlansClient is the subset of `web.AppServicePlansClient` used to check an existing App Service plan.
+type appServicePlansClient interface {
+	Get(ctx context.Context, resourceGroupName string, name string) (web.AppServicePlan, error)
+}
+
+// databaseServersClient changes the administrator password of an Azure Database for PostgreSQL or MySQL server, and
+// waits for the change to be applied. The two services have distinct clients and futures, so each is adapted to it.
+type databaseServersClient interface {
+	SetAdministratorPassword(ctx context.Context, resourceGroupName string, serverName string, password string) error
+}
+
+// siteConfigClient is the subset of `web.AppsClient` used to update the settings of a site.
+type siteConfigClient interface {
+	ListApplicationSettings(ctx context.Context, resourceGroupName string, name string) (web.StringDictionary, error)
+	UpdateApplicationSettings(ctx context.Context, resourceGroupName string, name string, appSettings web.StringDictionary) (web.StringDictionary, error)
+	ListConnectionStrings(ctx context.Context, resourceGroupName string, name string) (web.ConnectionStringDictionary, error)
+	UpdateConnectionStrings(ctx context.Context, resourceGroupName string, name string, connectionStrings web.ConnectionStringDictionary) (web.ConnectionStringDictionary, error)
+}
+
+// waitingDeploymentsClient satisfies `deploymentsClient` using Azure Resource Manager.
+type waitingDeploymentsClient struct {
+	resources.DeploymentsClient
+}
+
+// CreateOrUpdate starts a deployment. A Template Spec is deployed by reference, using templateSpecDeploymentsAPIVersion.
+// Otherwise, or once the request is prepared, the SDK is left to do the work.
+func (client waitingDeploymentsClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, deploymentName string, parameters resources.Deployment) (resources.DeploymentsCreateOrUpdateFuture, error) {
+	id, ok := templateSpecLink(parameters.Properties)
+	if !ok {
+		return client.DeploymentsClient.CreateOrUpdate(ctx, resourceGroupName, deploymentName, parameters)
+	}
+
+	pathParameters := map[string]interface{}{
+		"deploymentName":    autorest.Encode("path", deploymentName),
+		"resourceGroupName": autorest.Encode("path", resourceGroupName),
+		"subscriptionId":    autorest.Encode("path", client.SubscriptionID),
+	}
+
+	req, err := autorest.CreatePreparer(
+		autorest.AsContentType("application/json; charset=utf-8"),
+		autorest.AsPut(),
+		autorest.WithBaseURL(client.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourcegroups/{resourceGroupName}/providers/Microsoft.Resources/deployments/{deploymentName}", pathParameters),
+		autorest.WithJSON(newTemplateSpecDeployment(id, parameters.Properties)),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": templateSpecDeploymentsAPIVersion}),
+	).Prepare((&http.Request{}).WithContext(ctx))
+	if err != nil {
+		return resources.DeploymentsCreateOrUpdateFuture{}, err
+	}
+	return client.CreateOrUpdateSender(req)
+}
+
+func (client waitingDeploymentsClient) WaitForCompletion(ctx context.Context, future resources.DeploymentsCreateOrUpdateFuture) error {
+	return future.WaitForCompletion(ctx, client.Client)
+}
+
+// postgresServersClient satisfies `databaseServersClient` using Azure Database for PostgreSQL.
+type postgresServersClient struct {
+	postgresql.ServersClient
+}
+
+func (client postgresServersClient) SetAdministratorPassword(ctx context.Context, resourceGroupName string, serverName string, password string) error {
+	future, err := client.Update(ctx, resourceGroupName, serverName, postgresql.ServerUpdateParameters{
+		ServerUpdateParametersProperties: &postgresql.ServerUpdateParametersProperties{
+			AdministratorLoginPassword: to.StringPtr(password),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletion(ctx, client.Client)
+}
+
+// mysqlServersClient satisfies `databaseServersClient` using Azure Database for MySQL.
+type mysqlServersClient struct {
+	mysql.ServersClient
+}
+
+func (client mysqlServersClient) SetAdministratorPassword(ctx context.Context, resourceGroupName string, serverName string, password string) error {
+	future, err := client.Update(ctx, resourceGroupName, serverName, mysql.ServerUpdateParameters{
+		ServerUpdateParametersProperties: &mysql.ServerUpdateParametersProperties{
+			AdministratorLoginPassword: to.StringPtr(password),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletion(ctx, client.Client)
+}
+
+// These variables create the clients used to talk to Azure Resource Manager. Tests replace them with fakes.
+var (
+	newResourceGroupsClient = func(s *session, subscriptionID string, authorizer autorest.Authorizer) resourceGroupsClient {
+		groups := resources.NewGroupsClient(subscriptionID)
+		groups.Authorizer = authorizer
+		groups.Sender = s.httpClient
+		groups.AddToUserAgent(userAgent)
+		return groups
+	}
+
+	newDeploymentsClient = func(s *session, subscriptionID string, authorizer autorest.Authorizer) deploymentsClient {
+		deployments := resources.NewDeploymentsClient(subscriptionID)
+		deployments.Authorizer = authorizer
+		deployments.Sender = s.httpClient
+		deployments.AddToUserAgent(userAgent)
+		return waitingDeploymentsClient{deployments}
+	}
+
+	newProvidersClient = func(s *session, subscriptionID string, authorizer autorest.Authorizer) providerClient {
+		providers := resources.NewProvidersClient(subscriptionID)
+		providers.Authorizer = authorizer
+		providers.Sender = s.httpClient
+		providers.AddToUserAgent(userAgent)
+		return providers
+	}
+
+	newAppServicePlansClient = func(s *session, subscriptionID string, authorizer autorest.Authorizer) appServicePlansClient {
+		plans := web.NewAppServicePlansClientWithBaseURI(strings.TrimSuffix(s.environment.ResourceManagerEndpoint, "/"), subscriptionID)
+		plans.Authorizer = authorizer
+		plans.Sender = s.httpClient
+		plans.AddToUserAgent(userAgent)
+		return plans
+	}
+
+	newDatabaseServersClient = func(s *session, dbType string, subscriptionID string, authorizer autorest.Authorizer) databaseServersClient {
+		baseURI := strings.TrimSuffix(s.environment.ResourceManagerEndpoint, "/")
+		if dbType == "mysql" {
+			servers := mysql.NewServersClientWithBaseURI(baseURI, subscriptionID)
+			servers.Authorizer = authorizer
+			servers.Sender = s.httpClient
+			servers.AddToUserAgent(userAgent)
+			return mysqlServersClient{servers}
+		}
+		servers := postgresql.NewServersClientWithBaseURI(baseURI, subscriptionID)
+		servers.Authorizer = authorizer
+		servers.Sender = s.httpClient
+		servers.AddToUserAgent(userAgent)
+		return postgresServersClient{servers}
+	}
+
+	newSiteConfigClient = func(s *session, subscriptionID string, authorizer autorest.Authorizer) siteConfigClient {
+		apps := web.NewAppsClientWithBaseURI(strings.TrimSuffix(s.environment.ResourceManagerEndpoint, "/"), subscriptionID)
+		apps.Authorizer = authorizer
+		apps.Sender = s.httpClient
+		apps.AddToUserAgent(userAgent)
+		return apps
+	}
+)
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+	"github.com/Azure/go-autorest/autorest"
+)
+
+type fakeResourceGroupsClient struct {
+	existenceStatus int
+	createStatus    int
+	location        string
+	created         []resources.Group
+}
+
+func (f *fakeResourceGroupsClient) CheckExistence(ctx context.Context, resourceGroupName string) (autorest.Response, error) {
+	return autorest.Response{Response: &http.Response{StatusCode: f.existenceStatus}}, nil
+}
+
+func (f *fakeResourceGroupsClient) Get(ctx context.Context, resourceGroupName string) (resources.Group, error) {
+	if f.existenceStatus != http.StatusNoContent {
+		return resources.Group{}, errors.New("resource group not found")
+	}
+	return resources.Group{Location: &f.location}, nil
+}
+
+func (f *fakeResourceGroupsClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, parameters resources.Group) (resources.Group, error) {
+	f.created = append(f.created, parameters)
+	return resources.Group{Response: autorest.Response{Response: &http.Response{StatusCode: f.createStatus}}}, nil
+}
+
+type fakeDeploymentsClient struct {
+	createErr  error
+	waitErr    error
+	outputs    interface{}
+	deployed   []string
+	waitedUpon int
+}
+
+func (f *fakeDeploymentsClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, deploymentName string, parameters resources.Deployment) (resources.DeploymentsCreateOrUpdateFuture, error) {
+	if f.createErr != nil {
+		return resources.DeploymentsCreateOrUpdateFuture{}, f.createErr
+	}
+	f.deployed = append(f.deployed, resourceGroupName+"/"+deploymentName)
+	return resources.DeploymentsCreateOrUpdateFuture{}, nil
+}
+
+func (f *fakeDeploymentsClient) WaitForCompletion(ctx context.Context, future resources.DeploymentsCreateOrUpdateFuture) error {
+	f.waitedUpon++
+	return f.waitErr
+}
+
+func (f *fakeDeploymentsClient) Get(ctx context.Context, resourceGroupName string, deploymentName string) (resources.DeploymentExtended, error) {
+	return resources.DeploymentExtended{Properties: &resources.DeploymentPropertiesExtended{Outputs: f.outputs}}, nil
+}
+
+func Test_insertResourceGroup(t *testing.T) {
+	testCases := []struct {
+		name            string
+		existenceStatus int
+		createStatus    int
+		wantCreated     bool
+		wantCreateCalls int
+		wantErr         bool
+	}{
+		{"exists", http.StatusNoContent, 0, false, 0, false},
+		{"created", http.StatusNotFound, http.StatusCreated, true, 1, false},
+		{"created by someone else", http.StatusNotFound, http.StatusOK, false, 1, false},
+		{"unexpected creation status", http.StatusNotFound, http.StatusAccepted, false, 1, true},
+		{"unexpected existence status", http.StatusForbidden, 0, false, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			groups := &fakeResourceGroupsClient{existenceStatus: tc.existenceStatus, createStatus: tc.createStatus}
+
+			created, err := insertResourceGroup(context.Background(), groups, "contoso", "westus2")
+			if (err != nil) != tc.wantErr {
+				t.Logf("got error: %v want error: %v", err, tc.wantErr)
+				t.Fail()
+			}
+
+			if created != tc.wantCreated {
+				t.Logf("got created: %v want: %v", created, tc.wantCreated)
+				t.Fail()
+			}
+
+			if len(groups.created) != tc.wantCreateCalls {
+				t.Logf("got %d creation requests, want %d", len(groups.created), tc.wantCreateCalls)
+				t.Fail()
+			} else if len(groups.created) > 0 && *groups.created[0].Location != "westus2" {
+				t.Logf("got location: %q want: %q", *groups.created[0].Location, "westus2")
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_checkResourceGroupExistence(t *testing.T) {
+	testCases := []struct {
+		name            string
+		existenceStatus int
+		requireNew      bool
+		requireExisting bool
+		wantErr         bool
+	}{
+		{"no requirement", http.StatusForbidden, false, false, false},
+		{"require new, missing", http.StatusNotFound, true, false, false},
+		{"require new, exists", http.StatusNoContent, true, false, true},
+		{"require existing, exists", http.StatusNoContent, false, true, false},
+		{"require existing, missing", http.StatusNotFound, false, true, true},
+		{"unexpected existence status", http.StatusForbidden, true, false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			groups := &fakeResourceGroupsClient{existenceStatus: tc.existenceStatus}
+
+			err := checkResourceGroupExistence(context.Background(), groups, "contoso", tc.requireNew, tc.requireExisting)
+			if (err != nil) != tc.wantErr {
+				t.Logf("got error: %v want error: %v", err, tc.wantErr)
+				t.Fail()
+			}
+
+			if len(groups.created) != 0 {
+				t.Log("checking existence should never create a resource group")
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_resourceGroupLocation(t *testing.T) {
+	testCases := []struct {
+		name            string
+		existenceStatus int
+		location        string
+		want            string
+		wantErr         bool
+	}{
+		{"new group", http.StatusNotFound, "", "westus2", false},
+		{"existing group elsewhere", http.StatusNoContent, "eastus", "eastus", false},
+		{"existing group same region", http.StatusNoContent, "West US 2", "West US 2", false},
+		{"existing group without location", http.StatusNoContent, "", "", true},
+		{"unexpected existence status", http.StatusForbidden, "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			groups := &fakeResourceGroupsClient{existenceStatus: tc.existenceStatus, location: tc.location}
+
+			got, err := resourceGroupLocation(context.Background(), groups, "contoso", "westus2")
+			if (err != nil) != tc.wantErr {
+				t.Logf("got error: %v want error: %v", err, tc.wantErr)
+				t.Fail()
+			}
+
+			if got != tc.want {
+				t.Logf("got: %q want: %q", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_doDeployment(t *testing.T) {
+	deployments := &fakeDeploymentsClient{}
+	if err := doDeployment(context.Background(), deployments, "contoso", "buffalo-app-1", &resources.DeploymentProperties{}); err != nil {
+		t.Error(err)
+	}
+
+	if len(deployments.deployed) != 1 || deployments.deployed[0] != "contoso/buffalo-app-1" {
+		t.Logf("got deployments: %v want: [contoso/buffalo-app-1]", deployments.deployed)
+		t.Fail()
+	}
+
+	if deployments.waitedUpon != 1 {
+		t.Logf("waited on %d deployments, want 1", deployments.waitedUpon)
+		t.Fail()
+	}
+
+	failed := &fakeDeploymentsClient{waitErr: errors.New("deployment failed")}
+	if err := doDeployment(context.Background(), failed, "contoso", "buffalo-app-2", &resources.DeploymentProperties{}); err == nil {
+		t.Log("expected an error when the deployment fails")
+		t.Fail()
+	}
+
+	rejected := &fakeDeploymentsClient{createErr: errors.New("template invalid")}
+	if err := doDeployment(context.Background(), rejected, "contoso", "buffalo-app-3", &resources.DeploymentProperties{}); err == nil {
+		t.Log("expected an error when the deployment is rejected")
+		t.Fail()
+	} else if rejected.waitedUpon != 0 {
+		t.Log("should not wait on a deployment which was never started")
+		t.Fail()
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// bicepExecutable is the name of the program used to compile Bicep files into ARM templates.
+var bicepExecutable = "bicep"
+
+// isBicepFile decides if a template path refers to a Bicep file, which must be compiled before it can be deployed.
+func isBicepFile(subject string) bool {
+	return strings.EqualFold(filepath.Ext(subject), ".bicep")
+}
+
+// compileBicep uses the Bicep CLI to transpile the file found at location into an ARM template.
+func compileBicep(ctx context.Context, location string) ([]byte, error) {
+	exe, err := exec.LookPath(bicepExecutable)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile %q, the Bicep CLI could not be found (see https://aka.ms/bicep-install): %v", location, err)
+	}
+
+	log.Debug("compiling bicep file: ", location)
+
+	var stderr bytes.Buffer
+	compile := exec.CommandContext(ctx, exe, "build", location, "--stdout")
+	compile.Stderr = &stderr
+
+	contents, err := compile.Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile %q: %v: %s", location, err, strings.TrimSpace(stderr.String()))
+	}
+	return contents, nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_isBicepFile(t *testing.T) {
+	testCases := []struct {
+		subject string
+		want    bool
+	}{
+		{"./main.bicep", true},
+		{"./infra/MAIN.BICEP", true},
+		{"./azuredeploy.json", false},
+		{"./bicep", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.subject, func(t *testing.T) {
+			if got := isBicepFile(tc.subject); got != tc.want {
+				t.Logf("got: %v want: %v", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_compileBicep_missingCLI(t *testing.T) {
+	original := bicepExecutable
+	bicepExecutable = "buffalo-azure-nonexistent-bicep"
+	defer func() {
+		bicepExecutable = original
+	}()
+
+	if _, err := compileBicep(context.Background(), "./main.bicep"); err == nil {
+		t.Log("expected an error when the Bicep CLI is missing")
+		t.Fail()
+	}
+}
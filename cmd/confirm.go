@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// errNotConfirmed is returned by `Provision` when the deployment summary was not confirmed.
+var errNotConfirmed = errors.New("deployment was not confirmed, nothing was created")
+
+// deploymentSummary describes where and what is about to be deployed, so that it can be checked before anything is
+// created. artifact is the image or source that the site will run.
+func deploymentSummary(subscription, group, location, site, dbType, artifact string) string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Subscription:\t%s\n", subscription)
+	fmt.Fprintf(w, "Resource Group:\t%s\n", group)
+	fmt.Fprintf(w, "Location:\t%s\n", location)
+	fmt.Fprintf(w, "Site:\t%s\n", site)
+	fmt.Fprintf(w, "Database:\t%s\n", dbType)
+	fmt.Fprintf(w, "Deploying:\t%s\n", artifact)
+	w.Flush()
+	return buf.String()
+}
+
+// promptConfirmation writes summary to out, then asks whether to continue. Only an answer of "y" or "yes" is taken as
+// confirmation.
+func promptConfirmation(in io.Reader, out io.Writer, summary string) (bool, error) {
+	fmt.Fprint(out, summary)
+	fmt.Fprint(out, "Continue with this deployment? [y/N]: ")
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// isTerminal decides whether f is connected to a terminal, rather than a pipe or a file, so that a person is able to
+// answer prompts written to it.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
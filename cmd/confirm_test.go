@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_promptConfirmation(t *testing.T) {
+	summary := deploymentSummary("00000000-0000-0000-0000-000000000001", "contoso", "centralus", "contoso", "postgres", ImageDefault)
+
+	testCases := []struct {
+		answer string
+		want   bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		out := &bytes.Buffer{}
+		got, err := promptConfirmation(strings.NewReader(tc.answer), out, summary)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if got != tc.want {
+			t.Logf("answer %q: got: %v want: %v", tc.answer, got, tc.want)
+			t.Fail()
+		}
+
+		if !strings.Contains(out.String(), "centralus") {
+			t.Logf("summary was not shown, got: %q", out.String())
+			t.Fail()
+		}
+	}
+}
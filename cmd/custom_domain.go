@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// These functions query DNS. They are variables so that tests can substitute their own records.
+var (
+	lookupCNAME = net.LookupCNAME
+	lookupTXT   = net.LookupTXT
+)
+
+// verifyCustomDomain checks that the DNS records App Service needs before it will bind hostname to the site, which will
+// be reachable at siteHost, are in place. Either hostname must be a CNAME for siteHost, or there must be an "asuid" TXT
+// record proving ownership of hostname.
+func verifyCustomDomain(hostname, siteHost string) error {
+	if cname, err := lookupCNAME(hostname); err == nil && strings.EqualFold(strings.TrimSuffix(cname, "."), siteHost) {
+		return nil
+	}
+
+	if records, err := lookupTXT("asuid." + hostname); err == nil && len(records) > 0 {
+		return nil
+	}
+
+	return fmt.Errorf("custom domain %q is not ready: create a CNAME record pointing it to %q, or a TXT record named %q holding the site's domain verification ID", hostname, siteHost, "asuid."+hostname)
+}
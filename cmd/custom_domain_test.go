@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_verifyCustomDomain(t *testing.T) {
+	originalCNAME, originalTXT := lookupCNAME, lookupTXT
+	defer func() {
+		lookupCNAME, lookupTXT = originalCNAME, originalTXT
+	}()
+
+	cnames := map[string]string{
+		"www.contoso.com":   "contoso.azurewebsites.net.",
+		"wrong.contoso.com": "fabrikam.azurewebsites.net.",
+	}
+	txts := map[string][]string{
+		"asuid.contoso.com": {"0123456789ABCDEF"},
+	}
+
+	lookupCNAME = func(host string) (string, error) {
+		if cname, ok := cnames[host]; ok {
+			return cname, nil
+		}
+		return "", errors.New("no such host")
+	}
+	lookupTXT = func(name string) ([]string, error) {
+		if records, ok := txts[name]; ok {
+			return records, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	testCases := []struct {
+		hostname string
+		wantErr  bool
+	}{
+		{"www.contoso.com", false},
+		{"contoso.com", false},
+		{"wrong.contoso.com", true},
+		{"missing.contoso.com", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.hostname, func(t *testing.T) {
+			err := verifyCustomDomain(tc.hostname, "contoso.azurewebsites.net")
+			if tc.wantErr && err == nil {
+				t.Log("expected an error")
+				t.Fail()
+			} else if !tc.wantErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
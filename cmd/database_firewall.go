@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// firewallRule is a range of IPv4 addresses which are allowed to connect to the database server, formatted as the
+// template's "databaseAllowedIPs" parameter expects.
+type firewallRule struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// parseFirewallRules interprets each value as either a single IPv4 address, like "203.0.113.7", or an inclusive range
+// of them, like "203.0.113.0-203.0.113.255".
+func parseFirewallRules(values []string) ([]firewallRule, error) {
+	rules := make([]firewallRule, 0, len(values))
+	for _, raw := range values {
+		start, end := raw, raw
+		if separator := strings.Index(raw, "-"); separator >= 0 {
+			start, end = raw[:separator], raw[separator+1:]
+		}
+
+		startIP, endIP := parseIPv4(start), parseIPv4(end)
+		if startIP == nil || endIP == nil {
+			return nil, fmt.Errorf("%q is not an IPv4 address, or a range of them like 203.0.113.0-203.0.113.255", raw)
+		}
+		if bytes.Compare(startIP, endIP) > 0 {
+			return nil, fmt.Errorf("the range %q ends before it starts", raw)
+		}
+
+		rules = append(rules, firewallRule{Start: startIP.String(), End: endIP.String()})
+	}
+	return rules, nil
+}
+
+func parseIPv4(raw string) net.IP {
+	ip := net.ParseIP(strings.TrimSpace(raw))
+	if ip == nil {
+		return nil
+	}
+	return ip.To4()
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func Test_parseFirewallRules(t *testing.T) {
+	got, err := parseFirewallRules([]string{"203.0.113.7", "198.51.100.0-198.51.100.255", " 192.0.2.1 - 192.0.2.9 "})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := []firewallRule{
+		{Start: "203.0.113.7", End: "203.0.113.7"},
+		{Start: "198.51.100.0", End: "198.51.100.255"},
+		{Start: "192.0.2.1", End: "192.0.2.9"},
+	}
+	if len(got) != len(want) {
+		t.Logf("got: %v want: %v", got, want)
+		t.FailNow()
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Logf("got: %v want: %v", got[i], want[i])
+			t.Fail()
+		}
+	}
+
+	for _, invalid := range []string{"", "contoso.com", "203.0.113.0/24", "2001:db8::1", "203.0.113.9-203.0.113.1", "203.0.113.1-"} {
+		if _, err := parseFirewallRules([]string{invalid}); err == nil {
+			t.Logf("expected an error for %q", invalid)
+			t.Fail()
+		}
+	}
+}
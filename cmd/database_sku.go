@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// databaseVersions are the versions of each flavor of database that Azure Database for PostgreSQL and Azure Database
+// for MySQL servers can be created with.
+var databaseVersions = map[string][]string{
+	"postgres": {"9.5", "9.6", "10", "11"},
+	"mysql":    {"5.6", "5.7", "8.0"},
+}
+
+// databaseSKUPattern matches the names of database pricing tiers, which take the form {tier}_{family}_{cores}. For
+// example, "GP_Gen5_4" is General Purpose, with 4 cores.
+var databaseSKUPattern = regexp.MustCompile(`^(B|GP|MO)_Gen5_(\d+)$`)
+
+// databaseSKUCores are the numbers of cores offered by each database pricing tier.
+var databaseSKUCores = map[string][]int{
+	"B":  {1, 2},
+	"GP": {2, 4, 8, 16, 32, 64},
+	"MO": {2, 4, 8, 16, 32},
+}
+
+// These bound the amount of storage, in gigabytes, that a database server may be given. Basic tier servers are limited
+// to less than the others.
+const (
+	databaseStorageMin      = 5
+	databaseStorageBasicMax = 1024
+	databaseStorageMax      = 16384
+)
+
+// checkDatabaseVersion decides whether a version of a flavor of database can be provisioned. An empty version is
+// always accepted, as the template's choice is used in its place.
+func checkDatabaseVersion(dbType, version string) error {
+	if version == "" {
+		return nil
+	}
+
+	flavor := strings.ToLower(dbType)
+	if flavor == "postgresql" {
+		flavor = "postgres"
+	}
+
+	allowed, ok := databaseVersions[flavor]
+	if !ok {
+		return fmt.Errorf("a database version can't be chosen when %s is %q", DatabaseTypeName, dbType)
+	}
+
+	for _, v := range allowed {
+		if v == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %q is not supported, choose one of: %s", flavor, version, strings.Join(allowed, ", "))
+}
+
+// checkDatabaseSKU decides whether sku names a database pricing tier, along with a number of cores it offers. An empty
+// sku is always accepted, as the template's choice is used in its place.
+func checkDatabaseSKU(sku string) error {
+	if sku == "" {
+		return nil
+	}
+
+	match := databaseSKUPattern.FindStringSubmatch(sku)
+	if match == nil {
+		return fmt.Errorf("%q is not a database pricing tier, expected something like B_Gen5_1, GP_Gen5_4, or MO_Gen5_8", sku)
+	}
+
+	cores, _ := strconv.Atoi(match[2])
+	allowed := databaseSKUCores[match[1]]
+	if i := sort.SearchInts(allowed, cores); i < len(allowed) && allowed[i] == cores {
+		return nil
+	}
+
+	options := make([]string, 0, len(allowed))
+	for _, c := range allowed {
+		options = append(options, strconv.Itoa(c))
+	}
+	return fmt.Errorf("%s tier databases are offered with %s cores, not %d", match[1], strings.Join(options, ", "), cores)
+}
+
+// checkDatabaseStorage decides whether a database server of the pricing tier sku may be given storageGB gigabytes of
+// storage. Zero is always accepted, as the template's choice is used in its place.
+func checkDatabaseStorage(sku string, storageGB int) error {
+	if storageGB == 0 {
+		return nil
+	}
+
+	max := databaseStorageMax
+	if sku == "" || strings.HasPrefix(sku, "B_") {
+		max = databaseStorageBasicMax
+	}
+
+	if storageGB < databaseStorageMin || storageGB > max {
+		return fmt.Errorf("%s must be between %d and %d for this pricing tier", DatabaseStorageName, databaseStorageMin, max)
+	}
+	return nil
+}
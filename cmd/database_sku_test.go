@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func Test_checkDatabaseVersion(t *testing.T) {
+	testCases := []struct {
+		dbType  string
+		version string
+		ok      bool
+	}{
+		{"postgres", "", true},
+		{"postgres", "11", true},
+		{"postgresql", "9.6", true},
+		{"postgres", "14", false},
+		{"mysql", "8.0", true},
+		{"mysql", "11", false},
+		{"none", "", true},
+		{"none", "11", false},
+	}
+
+	for _, tc := range testCases {
+		if err := checkDatabaseVersion(tc.dbType, tc.version); (err == nil) != tc.ok {
+			t.Logf("%s %q got: %v want ok: %v", tc.dbType, tc.version, err, tc.ok)
+			t.Fail()
+		}
+	}
+}
+
+func Test_checkDatabaseSKU(t *testing.T) {
+	testCases := map[string]bool{
+		"":           true,
+		"B_Gen5_1":   true,
+		"GP_Gen5_4":  true,
+		"MO_Gen5_32": true,
+		"B_Gen5_4":   false,
+		"MO_Gen5_64": false,
+		"GP_Gen4_2":  false,
+		"gp_gen5_2":  false,
+		"Standard":   false,
+	}
+
+	for sku, ok := range testCases {
+		if err := checkDatabaseSKU(sku); (err == nil) != ok {
+			t.Logf("%q got: %v want ok: %v", sku, err, ok)
+			t.Fail()
+		}
+	}
+}
+
+func Test_checkDatabaseStorage(t *testing.T) {
+	testCases := []struct {
+		sku       string
+		storageGB int
+		ok        bool
+	}{
+		{"", 0, true},
+		{"", 100, true},
+		{"", 2048, false},
+		{"B_Gen5_2", 4, false},
+		{"GP_Gen5_4", 2048, true},
+		{"GP_Gen5_4", 20000, false},
+	}
+
+	for _, tc := range testCases {
+		if err := checkDatabaseStorage(tc.sku, tc.storageGB); (err == nil) != tc.ok {
+			t.Logf("%q %d got: %v want ok: %v", tc.sku, tc.storageGB, err, tc.ok)
+			t.Fail()
+		}
+	}
+}
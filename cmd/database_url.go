@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DatabaseURLEnvVar is the environment variable that Buffalo applications read their database connection from.
+const DatabaseURLEnvVar = "DATABASE_URL"
+
+// databaseURL assembles the connection string for a database created by the template distributed with this program,
+// exactly as that template configures the site with it. It is only meaningful for that template, see
+// isBundledTemplate. Azure Database for PostgreSQL and Azure Database for MySQL both require TLS, and qualify the
+// administrator login with the name of the server. The login and password are escaped as they would be in a URL.
+func databaseURL(databaseType, siteName, admin, password, databaseName string) (string, error) {
+	switch strings.ToLower(databaseType) {
+	case "postgres", "postgresql":
+		server := siteName + "-postgres"
+		connection := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(admin+"@"+server, password),
+			Host:     server + ".postgres.database.azure.com:5432",
+			Path:     "/" + databaseName,
+			RawQuery: "sslmode=require",
+		}
+		return connection.String(), nil
+	case "mysql":
+		// The host is wrapped in parentheses, as in a MySQL DSN, so the connection string is assembled by hand.
+		server := siteName + "-mysql"
+		user := url.UserPassword(admin+"@"+server, password)
+		return fmt.Sprintf("mysql://%s@(%s.mysql.database.azure.com:3306)/%s?tls=true", user, server, databaseName), nil
+	default:
+		return "", fmt.Errorf("no connection string format known for database type %q", databaseType)
+	}
+}
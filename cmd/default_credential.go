@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+// These are the environment variables read by the first link of the default credential chain. They're the same ones
+// read by the Azure SDKs and tools, so that an identity configured for them also works here.
+const (
+	clientCertificatePathEnvVar = "AZURE_CLIENT_CERTIFICATE_PATH"
+	tenantIDEnvVar              = "AZURE_TENANT_ID"
+	clientIDEnvVar              = "AZURE_CLIENT_ID"
+	clientSecretEnvVar          = "AZURE_CLIENT_SECRET"
+)
+
+// azureCLIClientID is the Application ID of the Azure CLI, which tokens it issues are attributed to.
+const azureCLIClientID = "04b07795-8ddb-461a-bbee-02f9e1bf7b46"
+
+// managedIdentityProbeTimeout bounds how long the default credential chain waits to find out whether a managed identity
+// is available, as off of Azure the endpoint which issues its tokens may never respond.
+var managedIdentityProbeTimeout = 3 * time.Second
+
+// credentialSource is one link of the default credential chain. It produces a factory for tokens from an identity it
+// finds, or an error explaining why no identity was found.
+type credentialSource struct {
+	name string
+	load func(s *session, tenantID, clientID, clientSecret string) (func(resource string) (*adal.ServicePrincipalToken, error), error)
+}
+
+// defaultCredentialChain lists the places an identity is looked for, in order, when using the default credential. It
+// matches the order used by DefaultAzureCredential in the Azure SDKs: the environment, then a managed identity, then
+// the Azure CLI.
+var defaultCredentialChain = []credentialSource{
+	{name: "environment", load: (*session).environmentTokenFactory},
+	{name: "managed identity", load: (*session).managedIdentityTokenFactory},
+	{name: "Azure CLI", load: (*session).azureCLITokenFactory},
+}
+
+// getDefaultCredential authenticates with the first identity found by defaultCredentialChain. tenantID, clientID, and
+// clientSecret are used by the environment link in place of the environment variables, which allows flags to override
+// them.
+func (s *session) getDefaultCredential(ctx context.Context, tenantID, clientID, clientSecret string) (autorest.Authorizer, error) {
+	reasons := make([]string, 0, len(defaultCredentialChain))
+	for _, source := range defaultCredentialChain {
+		factory, err := source.load(s, tenantID, clientID, clientSecret)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", source.name, err))
+			continue
+		}
+
+		token, err := factory(s.environment.ResourceManagerEndpoint)
+		if err == nil {
+			err = token.EnsureFreshWithContext(ctx)
+		}
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", source.name, err))
+			continue
+		}
+
+		log.Info("authenticated with default credential from ", source.name)
+		s.tokenFactory = factory
+		return autorest.NewBearerAuthorizer(token), nil
+	}
+	return nil, fmt.Errorf("no identity found for the default credential (%s)", strings.Join(reasons, "; "))
+}
+
+// environmentTokenFactory authenticates as a Service Principal, with either a secret or a certificate.
+func (s *session) environmentTokenFactory(tenantID, clientID, clientSecret string) (func(string) (*adal.ServicePrincipalToken, error), error) {
+	certificatePath := os.Getenv(clientCertificatePathEnvVar)
+	if tenantID == "" || clientID == "" || (clientSecret == "" && certificatePath == "") {
+		return nil, fmt.Errorf("%s, %s, and either %s or %s must be set", tenantIDEnvVar, clientIDEnvVar, clientSecretEnvVar, clientCertificatePathEnvVar)
+	}
+
+	config, err := adal.NewOAuthConfig(s.environment.ActiveDirectoryEndpoint, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientSecret != "" {
+		return func(resource string) (*adal.ServicePrincipalToken, error) {
+			token, err := adal.NewServicePrincipalToken(*config, clientID, clientSecret, resource)
+			if err == nil {
+				token.SetSender(s.httpClient)
+			}
+			return token, err
+		}, nil
+	}
+
+	certificate, key, err := readCertificate(certificatePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", clientCertificatePathEnvVar, err)
+	}
+	return func(resource string) (*adal.ServicePrincipalToken, error) {
+		token, err := adal.NewServicePrincipalTokenFromCertificate(*config, clientID, certificate, key, resource)
+		if err == nil {
+			token.SetSender(s.httpClient)
+		}
+		return token, err
+	}, nil
+}
+
+// readCertificate reads a certificate, along with its RSA private key, from an unencrypted PEM file like those created
+// by `az ad sp create-for-rbac --create-cert`.
+func readCertificate(location string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	contents, err := ioutil.ReadFile(location)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var certificate *x509.Certificate
+	var key *rsa.PrivateKey
+	for block, rest := pem.Decode(contents); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "CERTIFICATE":
+			if certificate == nil {
+				if certificate, err = x509.ParseCertificate(block.Bytes); err != nil {
+					return nil, nil, err
+				}
+			}
+		case "RSA PRIVATE KEY":
+			if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+				return nil, nil, err
+			}
+		case "PRIVATE KEY":
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			var ok bool
+			if key, ok = parsed.(*rsa.PrivateKey); !ok {
+				return nil, nil, errors.New("only RSA private keys are supported")
+			}
+		}
+	}
+
+	if certificate == nil || key == nil {
+		return nil, nil, errors.New("expected a PEM file containing both a certificate and its private key")
+	}
+	return certificate, key, nil
+}
+
+// managedIdentityTokenFactory authenticates as the managed identity of the Azure VM this is running on. When
+// AZURE_CLIENT_ID is set, it picks which of the VM's user-assigned identities is used.
+func (s *session) managedIdentityTokenFactory(_, clientID, _ string) (func(string) (*adal.ServicePrincipalToken, error), error) {
+	endpoint, err := adal.GetMSIVMEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	factory := func(resource string) (*adal.ServicePrincipalToken, error) {
+		if clientID != "" {
+			return adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(endpoint, resource, clientID)
+		}
+		return adal.NewServicePrincipalTokenFromMSI(endpoint, resource)
+	}
+
+	probe, err := factory(s.environment.ResourceManagerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	probe.MaxMSIRefreshAttempts = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), managedIdentityProbeTimeout)
+	defer cancel()
+	if err = probe.RefreshWithContext(ctx); err != nil {
+		return nil, errors.New("no managed identity is available")
+	}
+	return factory, nil
+}
+
+// azureCLICommand runs the Azure CLI, returning what it writes to stdout. Tests replace it with a fake.
+var azureCLICommand = func(args ...string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("az", args...)
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// azureCLITokenFactory authenticates as the identity that is logged in to the Azure CLI, by asking it for tokens. They
+// can't be refreshed, a new token is requested from the Azure CLI for each resource instead.
+func (s *session) azureCLITokenFactory(_, _, _ string) (func(string) (*adal.ServicePrincipalToken, error), error) {
+	if _, err := exec.LookPath("az"); err != nil {
+		return nil, errors.New("the Azure CLI is not installed")
+	}
+	return s.getAzureCLIToken, nil
+}
+
+// getAzureCLIToken asks the Azure CLI for a token for resource.
+func (s *session) getAzureCLIToken(resource string) (*adal.ServicePrincipalToken, error) {
+	output, err := azureCLICommand("account", "get-access-token", "--resource", resource, "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		AccessToken string `json:"accessToken"`
+		ExpiresOn   string `json:"expiresOn"`
+		Tenant      string `json:"tenant"`
+		TokenType   string `json:"tokenType"`
+	}
+	if err = json.Unmarshal(output, &parsed); err != nil {
+		return nil, fmt.Errorf("unable to read token from the Azure CLI: %v", err)
+	}
+
+	// The Azure CLI reports when tokens expire in local time, without a time zone.
+	expires, err := time.ParseInLocation("2006-01-02 15:04:05.999999", parsed.ExpiresOn, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read token from the Azure CLI: %v", err)
+	}
+
+	tenantID := parsed.Tenant
+	if tenantID == "" {
+		tenantID = "common"
+	}
+	config, err := adal.NewOAuthConfig(s.environment.ActiveDirectoryEndpoint, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := adal.NewServicePrincipalTokenFromManualToken(*config, azureCLIClientID, resource, adal.Token{
+		AccessToken: parsed.AccessToken,
+		ExpiresOn:   strconv.FormatInt(expires.Unix(), 10),
+		Resource:    resource,
+		Type:        parsed.TokenType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	token.SetAutoRefresh(false)
+	return token, nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+func Test_getAzureCLIToken(t *testing.T) {
+	originalCommand := azureCLICommand
+	defer func() {
+		azureCLICommand = originalCommand
+	}()
+
+	s := newSession(azure.PublicCloud, nil)
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	var gotArgs []string
+	azureCLICommand = func(args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte(`{
+  "accessToken": "fake-token",
+  "expiresOn": "` + expires.Format("2006-01-02 15:04:05.000000") + `",
+  "subscription": "00000000-0000-0000-0000-000000000000",
+  "tenant": "11111111-1111-1111-1111-111111111111",
+  "tokenType": "Bearer"
+}`), nil
+	}
+
+	token, err := s.getAzureCLIToken(azure.PublicCloud.ResourceManagerEndpoint)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := strings.Join(gotArgs, " "); !strings.Contains(got, "--resource "+azure.PublicCloud.ResourceManagerEndpoint) {
+		t.Logf("unexpected arguments: %s", got)
+		t.Fail()
+	}
+
+	if got := token.OAuthToken(); got != "fake-token" {
+		t.Logf("got: %q want: %q", got, "fake-token")
+		t.Fail()
+	}
+
+	if got := token.Token().Expires(); !got.Equal(expires) {
+		t.Logf("got: %v want: %v", got, expires)
+		t.Fail()
+	}
+
+	azureCLICommand = func(args ...string) ([]byte, error) {
+		return nil, errors.New("Please run 'az login' to setup account.")
+	}
+	if _, err := s.getAzureCLIToken(azure.PublicCloud.ResourceManagerEndpoint); err == nil {
+		t.Log("expected an error when the Azure CLI isn't logged in")
+		t.Fail()
+	}
+}
+
+func Test_environmentTokenFactory_missing(t *testing.T) {
+	originalCertificate := os.Getenv(clientCertificatePathEnvVar)
+	defer func() {
+		os.Setenv(clientCertificatePathEnvVar, originalCertificate)
+	}()
+
+	s := newSession(azure.PublicCloud, nil)
+	os.Unsetenv(clientCertificatePathEnvVar)
+
+	testCases := []struct {
+		tenantID     string
+		clientID     string
+		clientSecret string
+	}{
+		{"", "", ""},
+		{"tenant", "", "secret"},
+		{"", "client", "secret"},
+		{"tenant", "client", ""},
+	}
+
+	for _, tc := range testCases {
+		if _, err := s.environmentTokenFactory(tc.tenantID, tc.clientID, tc.clientSecret); err == nil {
+			t.Logf("expected an error for %+v", tc)
+			t.Fail()
+		}
+	}
+
+	if _, err := s.environmentTokenFactory("tenant", "client", "secret"); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_readCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure-certificate")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "buffalo-azure"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	complete := filepath.Join(dir, "complete.pem")
+	if err = ioutil.WriteFile(complete, append(privateKey, certificate...), 0600); err != nil {
+		t.Error(err)
+		return
+	}
+
+	gotCertificate, gotKey, err := readCertificate(complete)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if gotCertificate.Subject.CommonName != "buffalo-azure" {
+		t.Logf("got: %q want: %q", gotCertificate.Subject.CommonName, "buffalo-azure")
+		t.Fail()
+	}
+	if gotKey.N.Cmp(key.N) != 0 {
+		t.Log("the private key read doesn't match the one written")
+		t.Fail()
+	}
+
+	incomplete := filepath.Join(dir, "incomplete.pem")
+	if err = ioutil.WriteFile(incomplete, certificate, 0600); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, _, err = readCertificate(incomplete); err == nil {
+		t.Log("expected an error for a file without a private key")
+		t.Fail()
+	}
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	// Imported for the go:embed directive which reads the default template.
+	_ "embed"
+	"encoding/json"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+)
+
+// defaultTemplate is the template distributed with this program, read from azuredeploy.json, and TemplateDefaultSHA256
+// is its digest. It is deployed unless a template is asked for. When the template published at TemplateDefaultLink is
+// asked for but doesn't declare a parameter that a flag needs, it is deployed instead, see checkTemplateParameters.
+//
+//go:embed azuredeploy.json
+var defaultTemplate string
+
+// isBundledTemplate decides if the template about to be deployed is the one distributed with this program, whether it
+// was embedded in it or downloaded from elsewhere.
+func isBundledTemplate(template *resources.DeploymentProperties) bool {
+	raw, ok := template.Template.(json.RawMessage)
+	return ok && string(raw) == defaultTemplate
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func Test_defaultTemplate_parameters(t *testing.T) {
+	var parsed struct {
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+
+	if err := json.Unmarshal([]byte(defaultTemplate), &parsed); err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := []string{
+		"name",
+		"database",
+		"databaseName",
+		"imageName",
+		"databaseAdministratorLogin",
+		"databaseAdministratorLoginPassword",
+		"dockerRegistryAccess",
+		"dockerRegistryServerURL",
+		"dockerRegistryServerUsername",
+		"dockerRegistryServerPassword",
+		"planSku",
+		"planCapacity",
+		"customHostname",
+		"managedCertificate",
+		"appSettings",
+		"startupCommand",
+		"existingPlanId",
+		"hostOS",
+		"identityType",
+		"userAssignedIdentityId",
+		"runtimeStack",
+		"packageUri",
+		"databaseVersion",
+		"databaseSku",
+		"databaseStorageGB",
+		"databaseAllowAzureServices",
+		"databaseAllowedIPs",
+		"keyVaultName",
+		"createKeyVault",
+	}
+
+	for _, name := range expected {
+		if _, ok := parsed.Parameters[name]; !ok {
+			t.Logf("didn't find expected parameter %q", name)
+			t.Fail()
+		}
+	}
+}
+
+func Test_defaultTemplate_digest(t *testing.T) {
+	sum := sha256.Sum256([]byte(defaultTemplate))
+	if got := hex.EncodeToString(sum[:]); got != TemplateDefaultSHA256 {
+		t.Logf("got: %s want: %s", got, TemplateDefaultSHA256)
+		t.Log("the bundled template has changed, TemplateDefaultSHA256 must be updated to match it")
+		t.Fail()
+	}
+}
@@ -20,3 +20,18 @@ func NewDeploymentParameters() *DeploymentParameters {
 		Parameters:     make(map[string]DeploymentParameter),
 	}
 }
+
+// mergeParameters copies the parameters in overlay into base, replacing any that base already has.
+func mergeParameters(base, overlay *DeploymentParameters) {
+	if overlay.Schema != "" {
+		base.Schema = overlay.Schema
+	}
+
+	if overlay.ContentVersion != "" {
+		base.ContentVersion = overlay.ContentVersion
+	}
+
+	for key, value := range overlay.Parameters {
+		base.Parameters[key] = value
+	}
+}
@@ -96,3 +96,33 @@ func TestDeploymentParameters_MarshalRoundTrip(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func Test_mergeParameters(t *testing.T) {
+	base := NewDeploymentParameters()
+	base.Parameters["name"] = DeploymentParameter{"contoso-app"}
+	base.Parameters["database"] = DeploymentParameter{"postgresql"}
+
+	overlay := NewDeploymentParameters()
+	overlay.Parameters["database"] = DeploymentParameter{"mysql"}
+	overlay.Parameters["imageName"] = DeploymentParameter{"contoso/app:prod"}
+
+	mergeParameters(base, overlay)
+
+	expected := map[string]string{
+		"name":      "contoso-app",
+		"database":  "mysql",
+		"imageName": "contoso/app:prod",
+	}
+
+	if got, want := len(base.Parameters), len(expected); got != want {
+		t.Logf("Number Parameters:\n\tgot: %d want: %d", got, want)
+		t.Fail()
+	}
+
+	for key, want := range expected {
+		if got := base.Parameters[key].Value; got != want {
+			t.Logf("%s:\n\tgot: %v want: %q", key, got, want)
+			t.Fail()
+		}
+	}
+}
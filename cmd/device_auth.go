@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/sirupsen/logrus"
+)
+
+// These variables control how often Device Auth checks whether the user has entered their code. Checks start
+// deviceAuthPollInterval apart, unless Azure Active Directory suggests another interval, and the delay doubles each time
+// the user still hasn't finished, up to deviceAuthMaxPollInterval.
+var (
+	deviceAuthPollInterval    = 5 * time.Second
+	deviceAuthMaxPollInterval = 30 * time.Second
+)
+
+// deviceAuthReminderInterval is how long Device Auth waits between reminding the user to enter their code.
+var deviceAuthReminderInterval = 2 * time.Minute
+
+// checkDeviceAuth asks Azure Active Directory whether the user has finished Device Auth. It is a variable so that tests
+// can avoid contacting Azure Active Directory.
+var checkDeviceAuth = adal.CheckForUserCompletion
+
+// waitForDeviceAuth polls until the user finishes Device Auth, the device code expires, or ctx is done, whichever comes
+// first. While waiting, a reminder of how to finish is periodically written to out.
+func waitForDeviceAuth(ctx context.Context, sender adal.Sender, code *adal.DeviceCode, out io.Writer) (*adal.Token, error) {
+	interval := deviceAuthPollInterval
+	if code.Interval != nil && *code.Interval > 0 {
+		interval = time.Duration(*code.Interval) * time.Second
+	}
+
+	var expired <-chan time.Time
+	if code.ExpiresIn != nil && *code.ExpiresIn > 0 {
+		timer := time.NewTimer(time.Duration(*code.ExpiresIn) * time.Second)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	reminders := time.NewTicker(deviceAuthReminderInterval)
+	defer reminders.Stop()
+
+	for {
+		token, err := checkDeviceAuth(sender, code)
+		switch err {
+		case nil:
+			return token, nil
+		case adal.ErrDeviceAuthorizationPending, adal.ErrDeviceSlowDown:
+			if interval *= 2; interval > deviceAuthMaxPollInterval {
+				interval = deviceAuthMaxPollInterval
+			}
+			log.WithFields(logrus.Fields{"delay": interval}).Debug("device auth is not complete, waiting before checking again")
+		default:
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("stopped waiting for device auth: %v", ctx.Err())
+			}
+			return nil, err
+		}
+
+		next := time.After(interval)
+		for waiting := true; waiting; {
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("stopped waiting for device auth: %v", ctx.Err())
+			case <-expired:
+				return nil, errors.New("the device code expired before it was entered")
+			case <-reminders.C:
+				fmt.Fprintln(out, "Still waiting for you to enter the code.", deviceAuthInstructions(code))
+			case <-next:
+				waiting = false
+			}
+		}
+	}
+}
+
+// deviceAuthInstructions describes how the user can finish Device Auth.
+func deviceAuthInstructions(code *adal.DeviceCode) string {
+	if code.UserCode != nil && code.VerificationURL != nil {
+		return fmt.Sprintf("Open %s and enter %s to authenticate.", *code.VerificationURL, *code.UserCode)
+	}
+	if code.Message != nil {
+		return *code.Message
+	}
+	return ""
+}
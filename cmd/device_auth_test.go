@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+func stubDeviceAuth(check func(adal.Sender, *adal.DeviceCode) (*adal.Token, error)) func() {
+	originalCheck, originalInterval, originalMax := checkDeviceAuth, deviceAuthPollInterval, deviceAuthMaxPollInterval
+	checkDeviceAuth = check
+	deviceAuthPollInterval, deviceAuthMaxPollInterval = time.Millisecond, 4*time.Millisecond
+	return func() {
+		checkDeviceAuth, deviceAuthPollInterval, deviceAuthMaxPollInterval = originalCheck, originalInterval, originalMax
+	}
+}
+
+func Test_waitForDeviceAuth(t *testing.T) {
+	checks := 0
+	defer stubDeviceAuth(func(adal.Sender, *adal.DeviceCode) (*adal.Token, error) {
+		checks++
+		switch checks {
+		case 1:
+			return nil, adal.ErrDeviceAuthorizationPending
+		case 2:
+			return nil, adal.ErrDeviceSlowDown
+		default:
+			return &adal.Token{AccessToken: "token"}, nil
+		}
+	})()
+
+	token, err := waitForDeviceAuth(context.Background(), nil, &adal.DeviceCode{}, &bytes.Buffer{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if token.AccessToken != "token" {
+		t.Logf("got token: %q want: %q", token.AccessToken, "token")
+		t.Fail()
+	}
+
+	if checks != 3 {
+		t.Logf("got checks: %d want: 3", checks)
+		t.Fail()
+	}
+}
+
+func Test_waitForDeviceAuth_cancelled(t *testing.T) {
+	defer stubDeviceAuth(func(adal.Sender, *adal.DeviceCode) (*adal.Token, error) {
+		return nil, adal.ErrDeviceAuthorizationPending
+	})()
+
+	originalReminder := deviceAuthReminderInterval
+	deviceAuthReminderInterval = time.Millisecond
+	defer func() {
+		deviceAuthReminderInterval = originalReminder
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	userCode, link := "ABC123", "https://microsoft.com/devicelogin"
+	out := &bytes.Buffer{}
+
+	if _, err := waitForDeviceAuth(ctx, nil, &adal.DeviceCode{UserCode: &userCode, VerificationURL: &link}, out); err == nil {
+		t.Log("expected an error when the context is done")
+		t.Fail()
+	}
+
+	if !bytes.Contains(out.Bytes(), []byte(userCode)) {
+		t.Logf("expected a reminder containing the code, got: %q", out.String())
+		t.Fail()
+	}
+}
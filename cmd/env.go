@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// loadEnv sets environment variables from the ".env" file at the location specified, without overriding any that are
+// already set. A missing file is fine, but one which can't be parsed is an error, so that the values in it are never
+// silently ignored.
+func loadEnv(location string) error {
+	err := godotenv.Load(location)
+	if os.IsNotExist(err) {
+		log.Debugf("no environment file found at %q", location)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to read %q: %v", location, err)
+	}
+	return nil
+}
+
+// appendEnv adds each of the values provided to the environment file at the location specified, unless that file
+// already has an entry for the key. Existing content, including comments and ordering, is left untouched. Empty values
+// are never written.
+//
+// The keys that were written are returned in sorted order.
+func appendEnv(location string, values map[string]string) ([]string, error) {
+	_, keys, err := missingEnvKeys(location, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	contents, err := ioutil.ReadFile(location)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	if len(contents) > 0 && contents[len(contents)-1] != '\n' {
+		buf.WriteRune('\n')
+	}
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%s=\"%s\"\n", k, escapeEnvValue(values[k]))
+	}
+
+	handle, err := os.OpenFile(location, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+
+	if _, err = buf.WriteTo(handle); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// missingEnvKeys reads the environment file at the location specified, and finds the keys of the non-empty values
+// provided which it doesn't have an entry for yet, in sorted order. The entries already in the file are also returned.
+func missingEnvKeys(location string, values map[string]string) (map[string]string, []string, error) {
+	existing, err := godotenv.Read(location)
+	if os.IsNotExist(err) {
+		existing = make(map[string]string)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for k, v := range values {
+		if _, ok := existing[k]; ok || v == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return existing, keys, nil
+}
+
+// previewEnv describes to out what appendEnv would do to the environment file at the location specified, without
+// modifying it. Values are never shown, as they're passwords. Keys with an entry that differs from the value provided
+// are listed too, because appendEnv leaves them as they are.
+func previewEnv(out io.Writer, location string, values map[string]string) error {
+	existing, added, err := missingEnvKeys(location, values)
+	if err != nil {
+		return err
+	}
+
+	kept := make([]string, 0, len(values))
+	for k, v := range values {
+		if current, ok := existing[k]; ok && v != "" && current != v {
+			kept = append(kept, k)
+		}
+	}
+	sort.Strings(kept)
+
+	if len(added) == 0 && len(kept) == 0 {
+		_, err = fmt.Fprintf(out, "No changes would be made to %q.\n", location)
+		return err
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	fmt.Fprintf(buf, "Changes that would be made to %q:\n", location)
+	for _, k := range added {
+		fmt.Fprintf(buf, "  + %s=\"%s\"\n", k, redactedMessage)
+	}
+	for _, k := range kept {
+		fmt.Fprintf(buf, "  = %s (already set to a different value, which would be kept)\n", k)
+	}
+	_, err = buf.WriteTo(out)
+	return err
+}
+
+var envValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+func escapeEnvValue(value string) string {
+	return envValueEscaper.Replace(value)
+}
+
+// replaceEnv sets each of the values provided in the environment file at the location specified. Unlike appendEnv,
+// keys which already have an entry are overwritten in place. Other lines, including comments and ordering, are left
+// untouched, and keys without an entry are added to the end of the file.
+func replaceEnv(location string, values map[string]string) error {
+	contents, err := ioutil.ReadFile(location)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	replaced := make(map[string]bool, len(values))
+	lines := strings.SplitAfter(string(contents), "\n")
+	for i, line := range lines {
+		key := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "export "))
+		if eq := strings.Index(key, "="); eq > 0 {
+			key = strings.TrimSpace(key[:eq])
+		} else {
+			continue
+		}
+
+		if value, ok := values[key]; ok {
+			lines[i] = fmt.Sprintf("%s=\"%s\"\n", key, escapeEnvValue(value))
+			replaced[key] = true
+		}
+	}
+
+	missing := make([]string, 0, len(values))
+	for k := range values {
+		if !replaced[k] {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+
+	buf := bytes.NewBufferString(strings.Join(lines, ""))
+	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteRune('\n')
+	}
+	for _, k := range missing {
+		fmt.Fprintf(buf, "%s=\"%s\"\n", k, escapeEnvValue(values[k]))
+	}
+
+	return ioutil.WriteFile(location, buf.Bytes(), 0600)
+}
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_appendEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_env_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name     string
+		original string
+		values   map[string]string
+		want     string
+	}{
+		{
+			name:   "no file",
+			values: map[string]string{"B": "2", "A": "1"},
+			want:   "A=\"1\"\nB=\"2\"\n",
+		},
+		{
+			name:     "preserves existing keys",
+			original: "# my settings\nA=keep\n",
+			values:   map[string]string{"A": "1", "B": "2"},
+			want:     "# my settings\nA=keep\nB=\"2\"\n",
+		},
+		{
+			name:     "missing trailing newline",
+			original: "A=keep",
+			values:   map[string]string{"B": "2"},
+			want:     "A=keep\nB=\"2\"\n",
+		},
+		{
+			name:     "skips empty values",
+			original: "A=keep\n",
+			values:   map[string]string{"B": ""},
+			want:     "A=keep\n",
+		},
+		{
+			name:   "escapes quotes",
+			values: map[string]string{"A": `p"a\ss`},
+			want:   "A=\"p\\\"a\\\\ss\"\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			location := filepath.Join(dir, tc.name+".env")
+			if tc.original != "" {
+				if err := ioutil.WriteFile(location, []byte(tc.original), 0600); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+
+			if _, err := appendEnv(location, tc.values); err != nil {
+				t.Error(err)
+				return
+			}
+
+			got, err := ioutil.ReadFile(location)
+			if os.IsNotExist(err) {
+				got = []byte{}
+			} else if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(got) != tc.want {
+				t.Logf("got: %q want: %q", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_replaceEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_env_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name     string
+		original string
+		values   map[string]string
+		want     string
+	}{
+		{
+			name:   "no file",
+			values: map[string]string{"B": "2", "A": "1"},
+			want:   "A=\"1\"\nB=\"2\"\n",
+		},
+		{
+			name:     "overwrites existing keys",
+			original: "# my settings\nA=old\nC=keep\n",
+			values:   map[string]string{"A": "1", "B": "2"},
+			want:     "# my settings\nA=\"1\"\nC=keep\nB=\"2\"\n",
+		},
+		{
+			name:     "exported and spaced keys",
+			original: "export A = old\nC=keep",
+			values:   map[string]string{"A": "1"},
+			want:     "A=\"1\"\nC=keep\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			location := filepath.Join(dir, tc.name+".env")
+			if tc.original != "" {
+				if err := ioutil.WriteFile(location, []byte(tc.original), 0600); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+
+			if err := replaceEnv(location, tc.values); err != nil {
+				t.Error(err)
+				return
+			}
+
+			got, err := ioutil.ReadFile(location)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(got) != tc.want {
+				t.Logf("got: %q want: %q", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_previewEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_env_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name     string
+		original string
+		values   map[string]string
+		want     string
+	}{
+		{
+			name:   "no file",
+			values: map[string]string{"B": "secret-b", "A": "secret-a", "C": ""},
+			want:   "+ A=\"[redacted]\"\n  + B=\"[redacted]\"\n",
+		},
+		{
+			name:     "existing keys",
+			original: "A=old\nB=secret-b\n",
+			values:   map[string]string{"A": "secret-a", "B": "secret-b", "C": "secret-c"},
+			want:     "+ C=\"[redacted]\"\n  = A (already set to a different value, which would be kept)\n",
+		},
+		{
+			name:     "no changes",
+			original: "A=secret-a\n",
+			values:   map[string]string{"A": "secret-a"},
+			want:     "No changes would be made",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			location := filepath.Join(dir, tc.name+".env")
+			if tc.original != "" {
+				if err := ioutil.WriteFile(location, []byte(tc.original), 0600); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+
+			out := &bytes.Buffer{}
+			if err := previewEnv(out, location, tc.values); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if !strings.Contains(out.String(), tc.want) {
+				t.Logf("got: %q want it to contain: %q", out.String(), tc.want)
+				t.Fail()
+			}
+
+			if strings.Contains(out.String(), "secret-") {
+				t.Logf("a value was shown: %q", out.String())
+				t.Fail()
+			}
+
+			got, err := ioutil.ReadFile(location)
+			if os.IsNotExist(err) && tc.original == "" {
+				return
+			} else if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(got) != tc.original {
+				t.Logf("the file was modified, got: %q want: %q", got, tc.original)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_loadEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_env_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("missing", func(t *testing.T) {
+		if err := loadEnv(filepath.Join(dir, "missing.env")); err != nil {
+			t.Logf("expected a missing file to be ignored, got: %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		location := filepath.Join(dir, "malformed.env")
+		if err := ioutil.WriteFile(location, []byte("BUFFALO_AZURE_TEST_MALFORMED=1\nthis is not a setting\n"), 0600); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if err := loadEnv(location); err == nil {
+			t.Log("expected an error for a malformed file")
+			t.Fail()
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		const loaded, preset = "BUFFALO_AZURE_TEST_LOADED", "BUFFALO_AZURE_TEST_PRESET"
+		os.Setenv(preset, "original")
+		defer os.Unsetenv(preset)
+		defer os.Unsetenv(loaded)
+
+		location := filepath.Join(dir, "valid.env")
+		if err := ioutil.WriteFile(location, []byte(loaded+"=1\n"+preset+"=replaced\n"), 0600); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if err := loadEnv(location); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if got := os.Getenv(loaded); got != "1" {
+			t.Logf("got %s: %q want: %q", loaded, got, "1")
+			t.Fail()
+		}
+		if got := os.Getenv(preset); got != "original" {
+			t.Logf("got %s: %q want: %q", preset, got, "original")
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gobuffalo/buffalo/meta"
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/buffalo-azure/generators/eventgrid"
+)
+
+// eventgridHandlerCmd scaffolds a single Event Grid handler in a Buffalo application.
+var eventgridHandlerCmd = &cobra.Command{
+	Use:   "eventgrid-handler <EventTypeString>",
+	Short: "Generates a handler for one type of Azure Event Grid event.",
+	Long: `Adds a function for handling one type of Event Grid Event to the actions
+package of your Buffalo application, along with a test stub for it.
+
+For example:
+
+buffalo azure generate eventgrid-handler Microsoft.Storage.BlobCreated
+
+creates HandleBlobCreated, and BindBlobCreated to register it with a subscriber
+created by "buffalo generate eventgrid":
+
+dispatcher := eg.NewTypeDispatchSubscriber(parent)
+BindBlobCreated(dispatcher)`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("exactly one Event Type is required")
+		}
+		_, err := eventgrid.HandlerName(args[0])
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		gen := eventgrid.HandlerGenerator{}
+
+		if err := gen.Run(meta.New("."), args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, "unable to create handler: ", err)
+			os.Exit(1)
+		}
+
+		name, _ := eventgrid.HandlerName(args[0])
+		fmt.Printf("Created Handle%s. Call Bind%s with your subscriber to start receiving %q Events.\n", name.Camel(), name.Camel(), args[0])
+	},
+}
+
+func init() {
+	generateCmd.AddCommand(eventgridHandlerCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+// These constants are the exit codes of the provision command, which allow scripts to tell apart the ways it can fail.
+// Invalid arguments, and failures that fit no other category, result in ExitFailure.
+const (
+	ExitSuccess          = 0
+	ExitFailure          = 1
+	ExitAuthFailed       = 2
+	ExitDeploymentFailed = 3
+	ExitCacheFailed      = 4
+)
+
+// ExitError associates an error returned by `Provision` with the exit code the provision command reports it with.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// exitCode finds the code the provision command should exit with, given the outcome of `Provision`. When
+// cacheBestEffort is true, failing to cache the template or parameters is not reported.
+func exitCode(result ProvisionResult, err error, cacheBestEffort bool) int {
+	if err != nil {
+		if exitErr, ok := err.(*ExitError); ok {
+			return exitErr.Code
+		}
+		return ExitFailure
+	}
+
+	if result.CacheErr != nil && !cacheBestEffort {
+		return ExitCacheFailed
+	}
+	return ExitSuccess
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_exitCode(t *testing.T) {
+	testCases := []struct {
+		name            string
+		result          ProvisionResult
+		err             error
+		cacheBestEffort bool
+		want            int
+	}{
+		{"success", ProvisionResult{}, nil, false, ExitSuccess},
+		{"unclassified", ProvisionResult{}, errors.New("unable to fetch template"), false, ExitFailure},
+		{"auth", ProvisionResult{}, &ExitError{Code: ExitAuthFailed, Err: errors.New("unable to authenticate")}, false, ExitAuthFailed},
+		{"deployment", ProvisionResult{}, &ExitError{Code: ExitDeploymentFailed, Err: errors.New("deployment failed")}, false, ExitDeploymentFailed},
+		{"cache", ProvisionResult{CacheErr: errors.New("disk full")}, nil, false, ExitCacheFailed},
+		{"cache best effort", ProvisionResult{CacheErr: errors.New("disk full")}, nil, true, ExitSuccess},
+		{"deployment and cache", ProvisionResult{CacheErr: errors.New("disk full")}, &ExitError{Code: ExitDeploymentFailed, Err: errors.New("deployment failed")}, true, ExitDeploymentFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exitCode(tc.result, tc.err, tc.cacheBestEffort); got != tc.want {
+				t.Logf("got: %d want: %d", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
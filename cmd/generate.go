@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// generateCmd groups the commands which add code to a Buffalo application.
+var generateCmd = &cobra.Command{
+	Use:     "generate",
+	Aliases: []string{"g"},
+	Short:   "Adds code for working with Azure services to your application.",
+}
+
+func init() {
+	azureCmd.AddCommand(generateCmd)
+}
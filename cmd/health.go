@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// healthPollInterval is the amount of time waited between each attempt to reach a newly provisioned site.
+var healthPollInterval = 10 * time.Second
+
+// waitHealthy polls link until it responds with an HTTP 200, or timeout elapses. The status of the last response
+// received is reported in any error returned.
+func (s *session) waitHealthy(ctx context.Context, link string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	lastStatus := "no response"
+	for {
+		req, err := http.NewRequest(http.MethodGet, link, nil)
+		if err != nil {
+			return err
+		}
+
+		if resp, err := s.httpClient.Do(req.WithContext(ctx)); err == nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			lastStatus = resp.Status
+		} else {
+			lastStatus = err.Error()
+		}
+		log.WithField("status", lastStatus).Debug("site is not healthy yet")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("site at %s did not become healthy within %v, last status: %s", link, timeout, lastStatus)
+		case <-time.After(healthPollInterval):
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+func Test_waitHealthy(t *testing.T) {
+	original := healthPollInterval
+	healthPollInterval = time.Millisecond
+	defer func() {
+		healthPollInterval = original
+	}()
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	if err := newSession(azure.PublicCloud, server.Client()).waitHealthy(context.Background(), server.URL, time.Second); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if attempts != 3 {
+		t.Logf("got attempts: %d want: 3", attempts)
+		t.Fail()
+	}
+}
+
+func Test_waitHealthy_timeout(t *testing.T) {
+	original := healthPollInterval
+	healthPollInterval = time.Millisecond
+	defer func() {
+		healthPollInterval = original
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if err := newSession(azure.PublicCloud, server.Client()).waitHealthy(context.Background(), server.URL, 50*time.Millisecond); err == nil {
+		t.Log("expected an error when the site never becomes healthy")
+		t.Fail()
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"strings"
+)
+
+// These are the operating systems that a site's container may run on.
+const (
+	HostOSLinux   = "linux"
+	HostOSWindows = "windows"
+)
+
+// windowsContainerSKUs are the App Service plan pricing tiers which are able to host Windows containers.
+var windowsContainerSKUs = map[string]struct{}{
+	"P0V3": {}, "P1V3": {}, "P2V3": {}, "P3V3": {},
+	"P1MV3": {}, "P2MV3": {}, "P3MV3": {}, "P4MV3": {}, "P5MV3": {},
+	"I1V2": {}, "I2V2": {}, "I3V2": {},
+}
+
+// windowsContainerSKUDefault is the pricing tier used for Windows containers when none is provided, as the template's
+// default can only host Linux containers.
+const windowsContainerSKUDefault = "P1V3"
+
+// windowsImageHints are fragments of image names which are only found in Windows images, for instance those based on
+// mcr.microsoft.com/windows/servercore.
+var windowsImageHints = []string{"windows", "nanoserver", "servercore", "ltsc"}
+
+// imageLooksWindows guesses whether an image was built to run on Windows, based on its name. Images are rarely named
+// after the operating system they target, so only a positive answer is meaningful.
+func imageLooksWindows(image string) bool {
+	ref, err := parseImageReference(image)
+	if err != nil {
+		return false
+	}
+
+	name := strings.ToLower(ref.Repository + ":" + ref.Tag)
+	for _, hint := range windowsImageHints {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
+}
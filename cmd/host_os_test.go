@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func Test_imageLooksWindows(t *testing.T) {
+	testCases := map[string]bool{
+		"appsvc/sample-hello-world:latest":                                         false,
+		"mcr.microsoft.com/windows/servercore:ltsc2022":                            true,
+		"mcr.microsoft.com/dotnet/framework/aspnet:4.8-windowsservercore-ltsc2019": true,
+		"contoso.azurecr.io/buffalo-app:nanoserver-1809":                           true,
+		"contoso.azurecr.io/buffalo-app@sha256:0123456789abcdef":                   false,
+		"": false,
+	}
+
+	for image, want := range testCases {
+		if got := imageLooksWindows(image); got != want {
+			t.Logf("%q got: %v want: %v", image, got, want)
+			t.Fail()
+		}
+	}
+}
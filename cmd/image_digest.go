@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// dockerHubRegistry is the host which serves images whose names don't include a registry.
+const dockerHubRegistry = "registry-1.docker.io"
+
+// manifestMediaTypes are the kinds of manifest that a registry may describe an image with.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// imageReference is a container image name broken into the pieces needed to address it in a registry.
+type imageReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// parseImageReference splits an image name, like "appsvc/sample-hello-world:latest", into its constituent parts.
+// Images without a registry are assumed to be hosted on Docker Hub, and images without a tag or digest are assumed
+// to be tagged "latest".
+func parseImageReference(image string) (ref imageReference, err error) {
+	if image == "" {
+		err = errors.New("no image provided")
+		return
+	}
+
+	remainder := image
+	if i := strings.Index(remainder, "@"); i >= 0 {
+		ref.Digest = remainder[i+1:]
+		remainder = remainder[:i]
+	}
+
+	if i := strings.LastIndex(remainder, ":"); i > strings.LastIndex(remainder, "/") {
+		ref.Tag = remainder[i+1:]
+		remainder = remainder[:i]
+	}
+
+	if i := strings.Index(remainder, "/"); i >= 0 && (strings.ContainsAny(remainder[:i], ".:") || remainder[:i] == "localhost") {
+		ref.Registry = remainder[:i]
+		remainder = remainder[i+1:]
+	} else {
+		ref.Registry = dockerHubRegistry
+		if !strings.Contains(remainder, "/") {
+			remainder = "library/" + remainder
+		}
+	}
+
+	if remainder == "" {
+		err = fmt.Errorf("image %q does not name a repository", image)
+		return
+	}
+	ref.Repository = remainder
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return
+}
+
+// Pinned renders the image name, addressed by digest.
+func (ref imageReference) Pinned(digest string) string {
+	name := ref.Repository
+	if ref.Registry != dockerHubRegistry {
+		name = ref.Registry + "/" + name
+	} else {
+		name = strings.TrimPrefix(name, "library/")
+	}
+	return name + "@" + digest
+}
+
+// resolveImageDigest asks the registry hosting an image for the digest of the manifest its tag currently refers to,
+// and returns the image name addressed by that digest. Images which are already addressed by digest are returned
+// unchanged. When username is not empty, it and password are presented to the registry.
+func (s *session) resolveImageDigest(ctx context.Context, image, username, password string) (string, error) {
+	ref, err := parseImageReference(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return image, nil
+	}
+
+	link := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Registry, ref.Repository, ref.Tag)
+
+	head := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodHead, link, nil)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return s.httpClient.Do(req)
+	}
+
+	resp, err := head("")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := s.registryAuthorization(ctx, resp.Header.Get("Www-Authenticate"), username, password)
+		if err != nil {
+			return "", err
+		}
+
+		resp, err = head(authorization)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to find image %q, registry responded with: %s", image, resp.Status)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not provide a digest for image %q", image)
+	}
+	return ref.Pinned(digest), nil
+}
+
+// registryAuthorization answers the challenge a registry sent in its "WWW-Authenticate" header with a value for the
+// "Authorization" header.
+func (s *session) registryAuthorization(ctx context.Context, challenge, username, password string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" {
+			return "", errors.New("registry requires credentials, but none were provided")
+		}
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(username, password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || realm.Scheme == "" {
+			return "", fmt.Errorf("registry sent an invalid realm: %q", params["realm"])
+		}
+		query := realm.Query()
+		for _, key := range []string{"service", "scope"} {
+			if value, ok := params[key]; ok {
+				query.Set(key, value)
+			}
+		}
+		realm.RawQuery = query.Encode()
+
+		req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		req = req.WithContext(ctx)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unable to authenticate with registry: %s", resp.Status)
+		}
+
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", err
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+	default:
+		return "", fmt.Errorf("registry requested unsupported authentication scheme: %q", scheme)
+	}
+}
+
+// parseChallenge reads the scheme and parameters out of a "WWW-Authenticate" header, for example:
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"
+func parseChallenge(challenge string) (scheme string, params map[string]string) {
+	params = make(map[string]string)
+
+	challenge = strings.TrimSpace(challenge)
+	i := strings.IndexByte(challenge, ' ')
+	if i < 0 {
+		return challenge, params
+	}
+	scheme, challenge = challenge[:i], challenge[i+1:]
+
+	for challenge != "" {
+		eq := strings.IndexByte(challenge, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(challenge[:eq]))
+		challenge = strings.TrimSpace(challenge[eq+1:])
+
+		var value string
+		if strings.HasPrefix(challenge, `"`) {
+			end := strings.IndexByte(challenge[1:], '"')
+			if end < 0 {
+				value, challenge = challenge[1:], ""
+			} else {
+				value, challenge = challenge[1:end+1], challenge[end+2:]
+			}
+		} else if comma := strings.IndexByte(challenge, ','); comma >= 0 {
+			value, challenge = challenge[:comma], challenge[comma:]
+		} else {
+			value, challenge = challenge, ""
+		}
+		params[key] = value
+
+		challenge = strings.TrimLeft(challenge, ", ")
+	}
+	return
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+func Test_parseImageReference(t *testing.T) {
+	testCases := []struct {
+		image string
+		want  imageReference
+	}{
+		{"nginx", imageReference{Registry: dockerHubRegistry, Repository: "library/nginx", Tag: "latest"}},
+		{ImageDefault, imageReference{Registry: dockerHubRegistry, Repository: "appsvc/sample-hello-world", Tag: "latest"}},
+		{"contoso.azurecr.io/web/app:1.2", imageReference{Registry: "contoso.azurecr.io", Repository: "web/app", Tag: "1.2"}},
+		{"localhost:5000/app", imageReference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{"localhost/app:dev", imageReference{Registry: "localhost", Repository: "app", Tag: "dev"}},
+		{"nginx@sha256:abc", imageReference{Registry: dockerHubRegistry, Repository: "library/nginx", Digest: "sha256:abc"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			got, err := parseImageReference(tc.image)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got != tc.want {
+				t.Logf("got: %+v want: %+v", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_imageReference_Pinned(t *testing.T) {
+	testCases := []struct {
+		image string
+		want  string
+	}{
+		{"nginx:latest", "nginx@sha256:abc"},
+		{ImageDefault, "appsvc/sample-hello-world@sha256:abc"},
+		{"contoso.azurecr.io/web/app:1.2", "contoso.azurecr.io/web/app@sha256:abc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			ref, err := parseImageReference(tc.image)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got := ref.Pinned("sha256:abc"); got != tc.want {
+				t.Logf("got: %q want: %q", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_parseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+
+	if scheme != "Bearer" {
+		t.Logf("got scheme: %q want: %q", scheme, "Bearer")
+		t.Fail()
+	}
+
+	want := map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/nginx:pull",
+	}
+	for key, value := range want {
+		if params[key] != value {
+			t.Logf("got %s: %q want: %q", key, params[key], value)
+			t.Fail()
+		}
+	}
+}
+
+func Test_resolveImageDigest(t *testing.T) {
+	const digest = "sha256:6f2b4a9b0ad3b6f2a1c5e9cd1ab1e2c9cdb9e0e4d5f0f81e9a7c8a6b5d4c3b2a"
+
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token": "secret-token"}`))
+		case "/v2/web/app/manifests/1.2":
+			if r.Header.Get("Authorization") != "Bearer secret-token" {
+				w.Header().Set("Www-Authenticate", `Bearer realm="`+server.URL+`/token",service="test",scope="repository:web/app:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	got, err := newSession(azure.PublicCloud, server.Client()).resolveImageDigest(context.Background(), registry+"/web/app:1.2", "user", "pass")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if want := registry + "/web/app@" + digest; got != want {
+		t.Logf("got: %q want: %q", got, want)
+		t.Fail()
+	}
+}
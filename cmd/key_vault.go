@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// keyVaultAPIVersion is the version of the Azure Key Vault REST API used to save secrets.
+const keyVaultAPIVersion = "7.0"
+
+// keyVaultSecretName converts the name of an environment variable, like "BUFFALO_AZURE_DATABASE_PASSWORD", into a name
+// that Key Vault will accept for a secret, like "BUFFALO-AZURE-DATABASE-PASSWORD".
+func keyVaultSecretName(envVar string) string {
+	return strings.Replace(envVar, "_", "-", -1)
+}
+
+// keyVaultLink finds the address of the Key Vault with the given name in the current environment.
+func (s *session) keyVaultLink(vault string) string {
+	return fmt.Sprintf("https://%s.%s", vault, s.environment.KeyVaultDNSSuffix)
+}
+
+// setKeyVaultSecrets saves each secret with a non-empty value into the named Key Vault, using the identity established
+// by `getAuthorizer`. The ID of each secret version created is returned, keyed by the name it was saved under.
+func (s *session) setKeyVaultSecrets(ctx context.Context, vault string, values map[string]string) (map[string]string, error) {
+	if s.tokenFactory == nil {
+		return nil, errors.New("unable to save secrets to key vault, no identity has been authenticated")
+	}
+
+	token, err := s.tokenFactory(strings.TrimSuffix(s.environment.KeyVaultEndpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	token.SetSender(s.httpClient)
+	authorizer := autorest.NewBearerAuthorizer(token)
+
+	keys := make([]string, 0, len(values))
+	for key, value := range values {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	created := make(map[string]string, len(keys))
+	for _, key := range keys {
+		name := keyVaultSecretName(key)
+
+		body, err := json.Marshal(map[string]string{"value": values[key]})
+		if err != nil {
+			return created, err
+		}
+
+		link := fmt.Sprintf("%s/secrets/%s?api-version=%s", s.keyVaultLink(vault), url.PathEscape(name), keyVaultAPIVersion)
+		req, err := http.NewRequest(http.MethodPut, link, bytes.NewReader(body))
+		if err != nil {
+			return created, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		req, err = autorest.Prepare(req.WithContext(ctx), authorizer.WithAuthorization())
+		if err != nil {
+			return created, err
+		}
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return created, err
+		}
+
+		var saved struct {
+			ID string `json:"id"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&saved)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return created, fmt.Errorf("unable to save secret %q to key vault %q: %s", name, vault, resp.Status)
+		} else if err != nil {
+			return created, err
+		}
+		created[name] = saved.ID
+	}
+	return created, nil
+}
+
+// keyVaultReferencePrefix begins the value of an App Setting or Connection String that App Service reads from a Key
+// Vault, like "@Microsoft.KeyVault(SecretUri=https://contoso.vault.azure.net/secrets/DATABASE-URL/)".
+const keyVaultReferencePrefix = "@Microsoft.KeyVault("
+
+// isKeyVaultReference determines whether the value of an App Setting or Connection String is a Key Vault reference.
+func isKeyVaultReference(value *string) bool {
+	return value != nil && strings.HasPrefix(*value, keyVaultReferencePrefix)
+}
+
+// refreshKeyVaultReferences saves the App Settings of a site without changing them. App Service restarts the site, which
+// reads the secrets its Key Vault references refer to again.
+func refreshKeyVaultReferences(ctx context.Context, sites siteConfigClient, group, site string) error {
+	appSettings, err := sites.ListApplicationSettings(ctx, group, site)
+	if err != nil {
+		return fmt.Errorf("unable to read app settings: %v", err)
+	}
+	if _, err = sites.UpdateApplicationSettings(ctx, group, site, appSettings); err != nil {
+		return fmt.Errorf("unable to save app settings: %v", err)
+	}
+	return nil
+}
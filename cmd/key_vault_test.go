@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2018-02-01/web"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func Test_keyVaultSecretName(t *testing.T) {
+	if got, want := keyVaultSecretName(DatabasePasswordEnvVar), "BUFFALO-AZURE-DATABASE-PASSWORD"; got != want {
+		t.Logf("got: %q want: %q", got, want)
+		t.Fail()
+	}
+}
+
+func Test_setKeyVaultSecrets(t *testing.T) {
+	received := make(map[string]string)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || !strings.HasPrefix(r.URL.Path, "/secrets/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer fake-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body struct {
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/secrets/")
+		received[name] = body.Value
+
+		json.NewEncoder(w).Encode(map[string]string{"id": "https://" + r.Host + r.URL.Path + "/1"})
+	}))
+	defer server.Close()
+
+	s := newSession(azure.PublicCloud, server.Client())
+	s.tokenFactory = func(resource string) (*adal.ServicePrincipalToken, error) {
+		config, err := adal.NewOAuthConfig(azure.PublicCloud.ActiveDirectoryEndpoint, "tenantID")
+		if err != nil {
+			return nil, err
+		}
+		return adal.NewServicePrincipalTokenFromManualToken(*config, "clientID", resource, adal.Token{
+			AccessToken: "fake-token",
+			ExpiresOn:   "99999999999",
+		})
+	}
+
+	// The test server's address, for example "127.0.0.1:1234", is split to look like a vault name and DNS suffix.
+	host := strings.TrimPrefix(server.URL, "https://")
+	s.environment.KeyVaultDNSSuffix = host[strings.Index(host, ".")+1:]
+
+	saved, err := s.setKeyVaultSecrets(context.Background(), host[:strings.Index(host, ".")], map[string]string{
+		DatabasePasswordEnvVar:       "db-secret",
+		DockerRegistryPasswordEnvVar: "",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(saved) != 1 || received["BUFFALO-AZURE-DATABASE-PASSWORD"] != "db-secret" {
+		t.Logf("unexpected secrets saved: %v", received)
+		t.Fail()
+	}
+}
+
+func Test_refreshKeyVaultReferences(t *testing.T) {
+	const reference = "@Microsoft.KeyVault(SecretUri=https://contoso-vault.vault.azure.net/secrets/DATABASE-URL/)"
+	sites := &fakeSiteConfigClient{
+		appSettings: web.StringDictionary{Properties: map[string]*string{
+			"DATABASE_URL": to.StringPtr(reference),
+		}},
+	}
+
+	if err := refreshKeyVaultReferences(context.Background(), sites, "contoso", "contoso"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(sites.saved) != 1 || sites.saved[0] != "appsettings" {
+		t.Logf("saved %v, want the app settings saved", sites.saved)
+		t.Fail()
+	}
+	if got := to.String(sites.appSettings.Properties["DATABASE_URL"]); got != reference {
+		t.Logf("got: %q want: %q", got, reference)
+		t.Fail()
+	}
+}
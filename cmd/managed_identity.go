@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"regexp"
+)
+
+var userAssignedIdentityPattern = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.ManagedIdentity/userAssignedIdentities/[^/]+$`)
+
+// isUserAssignedIdentityID decides if a string is the resource ID of a user-assigned managed identity, for example:
+// /subscriptions/{subscription}/resourceGroups/{group}/providers/Microsoft.ManagedIdentity/userAssignedIdentities/{name}
+func isUserAssignedIdentityID(subject string) bool {
+	return userAssignedIdentityPattern.MatchString(subject)
+}
+
+// identityType finds the value of the "identityType" template parameter which gives the site a system-assigned managed
+// identity, attaches a user-assigned one, or both. When neither is requested, the result is empty.
+func identityType(systemAssigned bool, userAssignedID string) string {
+	switch {
+	case systemAssigned && userAssignedID != "":
+		return "SystemAssigned, UserAssigned"
+	case systemAssigned:
+		return "SystemAssigned"
+	case userAssignedID != "":
+		return "UserAssigned"
+	default:
+		return ""
+	}
+}
+
+// stringOutput reads the value of a string output from the outputs of a completed deployment, which take the form:
+//
+//	{"principalId": {"type": "String", "value": "..."}}
+//
+// Should the output be missing, or not a string, the result is empty.
+func stringOutput(outputs interface{}, name string) string {
+	all, ok := outputs.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	output, ok := all[name].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	value, _ := output["value"].(string)
+	return value
+}
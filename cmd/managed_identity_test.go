@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_isUserAssignedIdentityID(t *testing.T) {
+	testCases := map[string]bool{
+		"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/shared/providers/Microsoft.ManagedIdentity/userAssignedIdentities/buffalo": true,
+		"/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/shared/providers/microsoft.managedidentity/userassignedidentities/buffalo": true,
+		"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/shared/providers/Microsoft.Web/sites/contoso":                              false,
+		"buffalo": false,
+	}
+
+	for subject, want := range testCases {
+		if got := isUserAssignedIdentityID(subject); got != want {
+			t.Logf("%q got: %v want: %v", subject, got, want)
+			t.Fail()
+		}
+	}
+}
+
+func Test_identityType(t *testing.T) {
+	const id = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/shared/providers/Microsoft.ManagedIdentity/userAssignedIdentities/buffalo"
+
+	testCases := []struct {
+		system bool
+		user   string
+		want   string
+	}{
+		{false, "", ""},
+		{true, "", "SystemAssigned"},
+		{false, id, "UserAssigned"},
+		{true, id, "SystemAssigned, UserAssigned"},
+	}
+
+	for _, tc := range testCases {
+		if got := identityType(tc.system, tc.user); got != tc.want {
+			t.Logf("identityType(%v, %q) got: %q want: %q", tc.system, tc.user, got, tc.want)
+			t.Fail()
+		}
+	}
+}
+
+func Test_stringOutput(t *testing.T) {
+	var outputs interface{}
+	if err := json.Unmarshal([]byte(`{"principalId": {"type": "String", "value": "11111111-1111-1111-1111-111111111111"}, "count": {"type": "Int", "value": 1}}`), &outputs); err != nil {
+		t.Error(err)
+		return
+	}
+
+	testCases := map[string]string{
+		"principalId": "11111111-1111-1111-1111-111111111111",
+		"count":       "",
+		"missing":     "",
+	}
+
+	for name, want := range testCases {
+		if got := stringOutput(outputs, name); got != want {
+			t.Logf("%q got: %q want: %q", name, got, want)
+			t.Fail()
+		}
+	}
+
+	if got := stringOutput(nil, "principalId"); got != "" {
+		t.Logf("got %q from no outputs, want nothing", got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// These are the bounds on password length enforced by Azure Database for PostgreSQL and Azure Database for MySQL.
+const (
+	passwordMinLength = 8
+	passwordMaxLength = 128
+)
+
+// passwordClasses are the sets of characters that a generated password will contain at least one of. Symbols are
+// restricted to those that do not need to be escaped in a URL, because passwords are embedded in connection strings.
+var passwordClasses = []string{
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"abcdefghijklmnopqrstuvwxyz",
+	"0123456789",
+	"-_.~",
+}
+
+// generatePassword creates a random password of the length specified, which contains at least one upper case letter,
+// one lower case letter, one digit, and one symbol.
+func generatePassword(length int) (string, error) {
+	if length < passwordMinLength || length > passwordMaxLength {
+		return "", fmt.Errorf("password length must be between %d and %d, got %d", passwordMinLength, passwordMaxLength, length)
+	}
+
+	var all string
+	for _, class := range passwordClasses {
+		all += class
+	}
+
+	result := make([]byte, length)
+	for i := range result {
+		// Guarantee that each class is represented by seeding the first few positions with it, the positions are
+		// shuffled below.
+		source := all
+		if i < len(passwordClasses) {
+			source = passwordClasses[i]
+		}
+
+		n, err := randomInt(len(source))
+		if err != nil {
+			return "", err
+		}
+		result[i] = source[n]
+	}
+
+	// Fisher-Yates shuffle, so that the guaranteed characters don't always appear at the beginning.
+	for i := len(result) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return string(result), nil
+}
+
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
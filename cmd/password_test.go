@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_generatePassword(t *testing.T) {
+	const iterations = 1000
+
+	for _, length := range []int{passwordMinLength, 20, passwordMaxLength} {
+		for i := 0; i < iterations; i++ {
+			password, err := generatePassword(length)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if len(password) != length {
+				t.Logf("got length: %d want: %d", len(password), length)
+				t.Fail()
+			}
+
+			for _, class := range passwordClasses {
+				if !strings.ContainsAny(password, class) {
+					t.Logf("password %q is missing a character from %q", password, class)
+					t.Fail()
+				}
+			}
+		}
+	}
+}
+
+func Test_generatePassword_invalidLength(t *testing.T) {
+	for _, length := range []int{0, passwordMinLength - 1, passwordMaxLength + 1} {
+		if _, err := generatePassword(length); err == nil {
+			t.Logf("expected an error for length %d", length)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+)
+
+// providerRegistrationInterval is the amount of time waited between each check on a resource provider that is being
+// registered.
+var providerRegistrationInterval = 5 * time.Second
+
+// providerClient is the subset of `resources.ProvidersClient` used to check on resource provider registration.
+type providerClient interface {
+	Get(ctx context.Context, resourceProviderNamespace string, expand string) (resources.Provider, error)
+	Register(ctx context.Context, resourceProviderNamespace string) (resources.Provider, error)
+}
+
+// requiredProviders lists the resource providers that must be registered in a subscription before the resources
+// described by the default template can be deployed to it.
+func requiredProviders(databaseType string) []string {
+	namespaces := []string{"Microsoft.Web"}
+	switch strings.ToLower(databaseType) {
+	case "postgres", "postgresql":
+		namespaces = append(namespaces, "Microsoft.DBforPostgreSQL")
+	case "mysql":
+		namespaces = append(namespaces, "Microsoft.DBforMySQL")
+	}
+	return namespaces
+}
+
+// checkProviders ensures that each of the resource providers named is registered in a subscription. When register is
+// true, those which are not registered are registered, and checkProviders waits for registration to finish. Otherwise,
+// an error naming those which are not registered is returned.
+func checkProviders(ctx context.Context, client providerClient, namespaces []string, register bool) error {
+	var missing []string
+	for _, namespace := range namespaces {
+		provider, err := client.Get(ctx, namespace, "")
+		if err != nil {
+			return fmt.Errorf("unable to check registration of resource provider %s: %v", namespace, err)
+		}
+
+		if !isRegistered(provider) {
+			missing = append(missing, namespace)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	log.Warn("resource providers not registered in this subscription: ", strings.Join(missing, ", "))
+	if !register {
+		return fmt.Errorf("resource providers must be registered before deploying, use --%s to register them: %s", RegisterProvidersName, strings.Join(missing, ", "))
+	}
+
+	for _, namespace := range missing {
+		log.Info("registering resource provider: ", namespace)
+		provider, err := client.Register(ctx, namespace)
+		for err == nil && !isRegistered(provider) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(providerRegistrationInterval):
+			}
+			provider, err = client.Get(ctx, namespace, "")
+		}
+		if err != nil {
+			return fmt.Errorf("unable to register resource provider %s: %v", namespace, err)
+		}
+		log.Info("registered resource provider: ", namespace)
+	}
+	return nil
+}
+
+func isRegistered(provider resources.Provider) bool {
+	return provider.RegistrationState != nil && strings.EqualFold(*provider.RegistrationState, "Registered")
+}
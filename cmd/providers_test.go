@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+)
+
+type fakeProviderClient struct {
+	states     map[string]string
+	registered []string
+}
+
+func (f *fakeProviderClient) Get(ctx context.Context, namespace string, expand string) (resources.Provider, error) {
+	state := f.states[namespace]
+	return resources.Provider{Namespace: &namespace, RegistrationState: &state}, nil
+}
+
+func (f *fakeProviderClient) Register(ctx context.Context, namespace string) (resources.Provider, error) {
+	f.registered = append(f.registered, namespace)
+	f.states[namespace] = "Registered"
+	state := "Registering"
+	return resources.Provider{Namespace: &namespace, RegistrationState: &state}, nil
+}
+
+func Test_checkProviders(t *testing.T) {
+	original := providerRegistrationInterval
+	providerRegistrationInterval = time.Millisecond
+	defer func() {
+		providerRegistrationInterval = original
+	}()
+
+	newClient := func() *fakeProviderClient {
+		return &fakeProviderClient{
+			states: map[string]string{
+				"Microsoft.Web":             "Registered",
+				"Microsoft.DBforPostgreSQL": "NotRegistered",
+			},
+		}
+	}
+
+	if err := checkProviders(context.Background(), newClient(), requiredProviders("none"), false); err != nil {
+		t.Error(err)
+	}
+
+	if err := checkProviders(context.Background(), newClient(), requiredProviders("postgres"), false); err == nil {
+		t.Log("expected an error for an unregistered provider")
+		t.Fail()
+	}
+
+	client := newClient()
+	if err := checkProviders(context.Background(), client, requiredProviders("postgres"), true); err != nil {
+		t.Error(err)
+	}
+
+	if len(client.registered) != 1 || client.registered[0] != "Microsoft.DBforPostgreSQL" {
+		t.Logf("got registered: %v want: [Microsoft.DBforPostgreSQL]", client.registered)
+		t.Fail()
+	}
+}
@@ -23,6 +23,8 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -31,7 +33,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/subscriptions"
@@ -41,16 +47,16 @@ import (
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/gobuffalo/buffalo/meta"
 	"github.com/gobuffalo/pop"
-	"github.com/joho/godotenv"
 	"github.com/marstr/randname"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
-// clientID is used to identify this application during a Device Auth flow.
-// We have deliberately spoofed as the Azure CLI 2.0 at least temporarily.
-const deviceClientID = "04b07795-8ddb-461a-bbee-02f9e1bf7b46"
+// defaultDeviceClientID is used to identify this application during a Device Auth flow, when no other client ID is
+// provided. We have deliberately spoofed as the Azure CLI 2.0 at least temporarily.
+const defaultDeviceClientID = "04b07795-8ddb-461a-bbee-02f9e1bf7b46"
 
 var provisionConfig = viper.New()
 
@@ -74,8 +80,19 @@ const (
 	resourceGroupUsage     = "The name of the Resource Group that should hold the resources created."
 )
 
+// These constants define parameters which guarantee whether the resource group is created by provisioning, or already
+// exists. Without them, either is acceptable. Resource groups which already exist are never modified, not even their
+// location or tags.
+const (
+	RequireNewGroupName       = "require-new-group"
+	requireNewGroupUsage      = "Fail if the resource group already exists."
+	RequireExistingGroupName  = "require-existing-group"
+	requireExistingGroupUsage = "Fail if the resource group does not already exist."
+)
+
 // These constants define a parameter which allows control over the Azure Region that should be used when creating a
 // resource group. If the specified resource group already exists, its location is used and this parameter is discarded.
+// Either way, the location that was chosen is logged, and reported in the result of `Provision`.
 const (
 	LocationName        = "location"
 	LocationShorthand   = "l"
@@ -123,6 +140,38 @@ const (
 	DatabasePasswordEnvVar    = "BUFFALO_AZURE_DATABASE_PASSWORD"
 )
 
+// These constants define a parameter which controls the length of the database password, when it is randomly generated.
+// Generated passwords always contain upper case letters, lower case letters, digits, and symbols.
+const (
+	DatabasePasswordLengthName    = "db-password-length"
+	DatabasePasswordLengthDefault = 24
+	databasePasswordLengthUsage   = "The number of characters in the database password, when it is randomly generated."
+)
+
+// These constants define parameters which control the version, pricing tier, and storage of the database server. When
+// they are not specified, the values in the template are used, which are PostgreSQL 9.6 or MySQL 5.7 on a Basic server
+// with 1 core and 5 GB of storage.
+const (
+	DatabaseVersionName  = "db-version"
+	databaseVersionUsage = "The version of the database server, for example 11 for PostgreSQL or 8.0 for MySQL. Defaults to the template's choice."
+	DatabaseSKUName      = "db-sku"
+	databaseSKUUsage     = "The pricing tier of the database server, in the form {tier}_Gen5_{cores}, for example GP_Gen5_4. Defaults to the template's choice."
+	DatabaseStorageName  = "db-storage-gb"
+	databaseStorageUsage = "The amount of storage, in gigabytes, given to the database server. Defaults to the template's choice."
+)
+
+// These constants define parameters which control the firewall of the database server. By default, only services
+// running in Azure, including the site and those owned by other Azure customers, are able to connect; connections from
+// anywhere else are refused. Rules are only ever added, so disallowing an address on a later run requires removing its
+// rule from the server.
+const (
+	DatabaseAllowIPName               = "db-allow-ip"
+	databaseAllowIPUsage              = "An IPv4 address, or a range like 203.0.113.0-203.0.113.255, allowed to connect to the database. May be repeated."
+	DatabaseAllowAzureServicesName    = "db-allow-azure-services"
+	databaseAllowAzureServicesUsage   = "Allow services running in Azure, including the site, to connect to the database."
+	databaseAllowAzureServicesDefault = true
+)
+
 // These constants define a parameter which allows control over the particular Azure cloud which should be used for
 // deployment.
 // Some examples of Azure environments by name include:
@@ -137,8 +186,6 @@ const (
 	environmentUsage     = "The Azure environment that will be targeted for deployment."
 )
 
-var environment azure.Environment
-
 // These constants define a parameter which will control which container image is used to
 const (
 	// ImageName is the full parameter name of the argument that controls which container image will be used
@@ -154,6 +201,69 @@ const (
 	imageUsage = "The container image that defines this project."
 )
 
+// These constants define a parameter which deploys the Buffalo application from source, instead of from a container
+// image. It may be the path to the application's directory, which is packaged and uploaded to the site to be built, or
+// a link to a zip archive of an application that has already been built, which the site runs directly.
+const (
+	SourceName  = "source"
+	sourceUsage = "The directory of a Buffalo application, or a link to a zip archive of a built one, to deploy instead of a container image."
+)
+
+// These constants define parameters which control the size and scale of the App Service plan that hosts the site. When
+// they are not specified, the values in the template are used. Templates must declare the "planSku" and "planCapacity"
+// parameters for these to be used, as the one distributed with buffalo-azure does.
+const (
+	PlanSKUName       = "plan-sku"
+	planSKUUsage      = "The pricing tier of the App Service plan, for example B1 or P1v3. Defaults to the template's choice."
+	PlanCapacityName  = "plan-capacity"
+	planCapacityUsage = "The number of instances the App Service plan should run. Defaults to the template's choice."
+	planCapacityLimit = 30
+)
+
+// These constants define a parameter which deploys the site into an existing App Service plan, identified by its
+// resource ID, instead of creating a plan for it. The plan must be a Linux plan in the same region as the site.
+const (
+	PlanIDName  = "plan-id"
+	planIDUsage = "The resource ID of an existing App Service plan the site should share, instead of creating a new one."
+)
+
+// These constants define a parameter which chooses the operating system the site's container runs on. Windows containers
+// can only be hosted by some pricing tiers, like Premium v3, which is used when no other tier is chosen.
+const (
+	HostOSName    = "os"
+	HostOSDefault = HostOSLinux
+	hostOSUsage   = "The operating system the container runs on, either " + HostOSLinux + " or " + HostOSWindows + "."
+)
+
+// These constants define parameters which give the site a managed identity, so that it can authenticate to other Azure
+// services, like Key Vault or Storage, without credentials in its configuration. A system-assigned identity is created
+// along with the site, and its principal ID is reported once the deployment finishes. A user-assigned identity must
+// already exist, and is identified by its resource ID. Both may be used at once. By default, the site has no identity.
+const (
+	SystemIdentityName  = "system-identity"
+	systemIdentityUsage = "Give the site a system-assigned managed identity."
+	UserIdentityName    = "user-identity"
+	userIdentityUsage   = "The resource ID of an existing user-assigned managed identity to attach to the site."
+)
+
+// knownPlanSKUs are the App Service plan pricing tiers which are able to host Linux containers. Others are passed
+// along to the template with a warning, so that new tiers can be used before this list is updated.
+var knownPlanSKUs = map[string]struct{}{
+	"B1": {}, "B2": {}, "B3": {},
+	"S1": {}, "S2": {}, "S3": {},
+	"P1V2": {}, "P2V2": {}, "P3V2": {},
+	"P0V3": {}, "P1V3": {}, "P2V3": {}, "P3V3": {},
+	"P1MV3": {}, "P2MV3": {}, "P3MV3": {}, "P4MV3": {}, "P5MV3": {},
+	"I1V2": {}, "I2V2": {}, "I3V2": {},
+}
+
+// These constants define a parameter which toggles whether or not the image's tag is resolved to the digest it currently
+// refers to, so that the exact same image is always run even if the tag is later moved.
+const (
+	PinImageName  = "pin-image"
+	pinImageUsage = "Resolve the image's tag to a digest, and deploy the image by that digest."
+)
+
 // These constants define a parameter that allows control of the Azure Resource Management (ARM) template that should be
 // used to provision infrastructure. This tool is not designed to deploy arbitrary ARM templates, rather this parameter
 // is intended to give you the flexibility to lock to a known version of the gobuffalo quick start template, or tweak
@@ -175,28 +285,65 @@ const (
 	// TemplateDefault is the name of the Template to use if no value was provided.
 	TemplateDefault = "./azuredeploy.json"
 
-	// TemplateDefaultLink defines the link that will be used if no local rm-template is found, and a link wasn't
-	// provided.
+	// TemplateDefaultLink is where the template distributed with this program is published. It is shown as the
+	// default rm-template when no local one is found, but the bundled copy is deployed unless it is given explicitly.
 	TemplateDefaultLink = "https://aka.ms/buffalo-template"
-	templateUsage       = "The Azure Resource Management template which specifies the resources to provision."
+	templateUsage       = "The Azure Resource Management template which specifies the resources to provision. It may be a path to an ARM or Bicep template, a link, or the resource ID of a Template Spec version."
+)
+
+// These constants define a parameter which allows the contents of the ARM template to be verified before it is used.
+// When provided, the SHA-256 digest of the template must match the hex-encoded value given, or provisioning stops.
+const (
+	TemplateSHA256Name  = "template-sha256"
+	templateSHA256Usage = "The hex-encoded SHA-256 digest that the contents of the template must match."
+)
+
+// These constants define a parameter which forces the identity being used for deployment to be presented while
+// downloading the template. Even without this parameter, the identity is presented to Azure Storage and Azure Resource
+// Manager endpoints that are not already authorized with a Shared Access Signature.
+const (
+	TemplateAuthName  = "rm-template-auth"
+	templateAuthUsage = "Attach a bearer token for the identity being used to the request downloading the template, regardless of where it is hosted."
 )
 
+// These constants define parameters which control how persistently a template is downloaded. Each attempt which fails
+// with a network error, or a status code indicating a temporary failure, is retried after a growing delay until the
+// retries are exhausted. The timeout limits all attempts together. When it is zero, only the overall time allowed to
+// provision applies.
+const (
+	TemplateDownloadRetriesName    = "template-download-retries"
+	TemplateDownloadRetriesDefault = 3
+	templateDownloadRetriesUsage   = "The number of times downloading the template is attempted before giving up."
+	TemplateDownloadTimeoutName    = "template-download-timeout"
+	templateDownloadTimeoutUsage   = "The longest amount of time to spend downloading the template. Zero means no limit."
+)
+
+// TemplateDefaultSHA256 is the hex-encoded SHA-256 digest of the template distributed with this release, which is
+// deployed unless --rm-template is given. It is reported by the version command, so that bug reports identify the
+// template that was deployed.
+var TemplateDefaultSHA256 = "a0e920a92a164c0b49767a35c9183b26e4721740799c91f0c3c49a619f0de10f"
+
 // These constants define a parameter which allows control of the ARM template parameters that should be used during
-// deployment.
+// deployment. When TemplateParametersStdin is provided in place of a file, the parameters are read from stdin.
+//
+// It may be provided more than once, in which case the files are merged in order: a parameter in a later file
+// overrides the same parameter in an earlier one. Values provided with other flags take precedence over all of them.
 const (
 	TemplateParametersName      = "rm-template-params"
 	TemplateParametersShorthand = "p"
 	TemplateParametersDefault   = "./azuredeploy.parameters.json"
-	templateParametersUsage     = "The parameters that should be provided when creating a deployment."
+	TemplateParametersStdin     = "-"
+	templateParametersUsage     = "The parameters that should be provided when creating a deployment. Use \"" + TemplateParametersStdin + "\" to read them from stdin. When repeated, later files override earlier ones."
 )
 
-// These constants define a parameter that Azure subscription to own the resources created.
+// These constants define a parameter that Azure subscription to own the resources created. It may be given as the
+// subscription's ID, or as its display name, which is resolved to an ID after authenticating.
 //
 // This can also be specified with the environment variable AZURE_SUBSCRIPTION_ID or AZ_SUBSCRIPTION_ID.
 const (
 	SubscriptionName      = "subscription-id"
 	SubscriptionShorthand = "s"
-	subscriptionUsage     = "The ID (in UUID format) of the Azure subscription which should host the provisioned resources."
+	subscriptionUsage     = "The ID (in UUID format) or name of the Azure subscription which should host the provisioned resources."
 )
 
 // These constants define a parameter which will control the profile being used for the sake of connections.
@@ -247,6 +394,48 @@ const (
 	deviceAuthUsage = "Ignore --client-id and --client-secret, interactively authenticate instead."
 )
 
+// These constants define a parameter which authenticates with the first identity found in the environment, as a
+// managed identity, or in the Azure CLI, the same way as DefaultAzureCredential in the Azure SDKs. A Service Principal
+// in the environment may use the certificate named by AZURE_CLIENT_CERTIFICATE_PATH instead of a secret. When they are
+// provided, --client-id, --client-secret, and --tenant-id take precedence over the environment.
+const (
+	DefaultCredentialName  = "use-default-credential"
+	defaultCredentialUsage = "Authenticate with the first identity found in the environment, as a managed identity, or in the Azure CLI."
+)
+
+// These constants define a parameter which allows an organization to use its own App Registration during Device Auth,
+// for instance when Conditional Access policies only allow approved applications to sign in. When it isn't provided,
+// the client ID of the Azure CLI 2.0 is used.
+//
+// This can also be specified with the environment variable BUFFALO_AZURE_DEVICE_CLIENT_ID.
+const (
+	DeviceClientIDName  = "device-client-id"
+	deviceClientIDUsage = "The Application ID of the App Registration to use during Device Auth."
+)
+
+// These constants define a parameter which prevents this program from waiting on any user interaction. Should
+// interaction be required, for example to complete Device Auth, the program exits with an error instead. This is
+// intended for use in automated environments like CI pipelines.
+const (
+	NoInputName  = "no-input"
+	noInputUsage = "Fail instead of prompting for user interaction, like Device Auth."
+)
+
+// These constants define a parameter which limits how long this program may run, including any time spent waiting for
+// Device Auth to be completed. Once it elapses, any work in progress is cancelled.
+const (
+	TimeoutName    = "timeout"
+	TimeoutDefault = 45 * time.Minute
+	timeoutUsage   = "The longest amount of time this command may run, including waiting for Device Auth."
+)
+
+// These constants define a parameter which skips confirming the summary of what is about to be deployed. Confirmation is
+// also skipped when --no-input is provided, or stdin is not a terminal.
+const (
+	YesName  = "yes"
+	yesUsage = "Deploy without asking for confirmation of the deployment summary."
+)
+
 // These constants define a parameter which toggles whether or not status information will be printed as this program
 // executes.
 const (
@@ -259,7 +448,7 @@ const (
 const (
 	SkipTemplateCacheName      = "skip-template-cache"
 	SkipTemplateCacheShorthand = "z"
-	skipTemplateCacheUsage     = "After downloading the default template, do NOT save it in the working directory."
+	skipTemplateCacheUsage     = "After downloading the default template, do NOT save it in the cache directory."
 )
 
 // These constants define a parameter which toggles whether or not to save the parameters used for deployment to disk.
@@ -269,6 +458,143 @@ const (
 	skipParameterCacheUsage     = "After creating deploying the site, do NOT save the parameters that were used for deployment."
 )
 
+// These constants define a parameter which prevents failing to save the template or parameters to the cache directory
+// from being reported in the exit code. Either way, provisioning continues when the cache can't be written.
+const (
+	CacheBestEffortName  = "cache-best-effort"
+	cacheBestEffortUsage = "Exit successfully even if the template or parameters could not be saved to the cache directory."
+)
+
+// These constants define a parameter which bypasses the ".env" file entirely. Nothing is read from it, and generated
+// passwords are not saved to it.
+const (
+	SkipEnvReadName  = "skip-env-read"
+	skipEnvReadUsage = "Do not read or write the .env file. Settings must be provided by flags or environment variables."
+)
+
+// These constants define a parameter which controls the directory that templates and parameters are cached in. If the
+// directory does not exist, it will be created.
+const (
+	CacheDirName    = "cache-dir"
+	CacheDirDefault = "."
+	cacheDirUsage   = "The directory that the template and parameters used for deployment should be saved in."
+)
+
+// These constants define a parameter which toggles whether or not generated passwords are saved to the ".env" file. Even
+// when this parameter is not set, keys which are already present in the ".env" file are never overwritten. NoEnvName is
+// accepted as another name for the same parameter.
+const (
+	NoEnvWriteName  = "no-env-write"
+	NoEnvName       = "no-env"
+	noEnvWriteUsage = "Do not save passwords to the .env file, even if they are not yet present there. May also be given as --" + NoEnvName + "."
+)
+
+// normalizeProvisionFlag maps the alternate names that some provision flags are accepted by onto the names they are
+// registered with.
+func normalizeProvisionFlag(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	switch name {
+	case NoEnvName:
+		name = NoEnvWriteName
+	}
+	return pflag.NormalizedName(name)
+}
+
+// These constants define a parameter which prints the changes that would be made to the ".env" file, with passwords
+// redacted, instead of making them. Everything else is provisioned as usual, so passwords which are generated while it
+// is set are only saved in the site's settings.
+const (
+	EnvDryRunName  = "env-dry-run"
+	envDryRunUsage = "Print the keys that would be added to the .env file, with their values redacted, instead of writing them."
+)
+
+// These constants define parameters which bind a custom domain to the site, and optionally secure it with a free
+// certificate managed by App Service. The domain's DNS records must be in place before provisioning.
+const (
+	CustomDomainName        = "custom-domain"
+	customDomainUsage       = "A hostname, like www.contoso.com, which should be bound to the site."
+	ManagedCertificateName  = "managed-certificate"
+	managedCertificateUsage = "Secure the custom domain with a certificate managed by App Service."
+)
+
+// These constants define parameters which cause provisioning to wait until the newly deployed site responds
+// successfully, so that anything which runs afterwards can rely on the site being ready.
+const (
+	WaitHealthyName      = "wait-healthy"
+	waitHealthyUsage     = "After deploying, wait until the site responds with an HTTP 200 before exiting."
+	HealthPathName       = "health-path"
+	HealthPathDefault    = "/"
+	healthPathUsage      = "The path requested while waiting for the site to become healthy."
+	HealthTimeoutName    = "health-timeout"
+	HealthTimeoutDefault = 5 * time.Minute
+	healthTimeoutUsage   = "The longest amount of time to wait for the site to become healthy."
+)
+
+// These constants define parameters which name an Azure Key Vault in the Resource Group that passwords should be saved
+// in, instead of the ".env" file. The site reads DATABASE_URL from the Key Vault, and is allowed to do so through its
+// system-assigned identity. Unless the template is asked to create the Key Vault, it must already exist.
+const (
+	KeyVaultName        = "key-vault"
+	keyVaultUsage       = "The name of an Azure Key Vault in the Resource Group to save passwords in, instead of the .env file. The site's DATABASE_URL refers to the secret in it."
+	CreateKeyVaultName  = "create-key-vault"
+	createKeyVaultUsage = "Create the Azure Key Vault named by --" + KeyVaultName + ", instead of using an existing one."
+)
+
+// These constants define a parameter which prints the template and parameters that would be deployed, then exits
+// without contacting Azure Resource Manager.
+const (
+	ShowTemplateName  = "show-template"
+	showTemplateUsage = "Print the template and parameters that would be deployed, with passwords redacted, then exit without deploying."
+)
+
+// These constants define a parameter which adds App Settings to the site, each given as "key=value". It may be
+// provided more than once, but each key only once.
+const (
+	AppSettingName  = "app-setting"
+	appSettingUsage = "An App Setting to add to the site, in the form key=value. May be repeated."
+)
+
+// These constants define a parameter which replaces the command that the site's container is started with.
+const (
+	StartupCommandName  = "startup-command"
+	startupCommandUsage = "The command the container should be started with, instead of the one in the image."
+)
+
+// appSettings holds the App Settings parsed from the command line.
+var appSettings map[string]string
+
+// databaseAllowedIPs holds the addresses allowed through the database server's firewall, as given on the command line.
+var databaseAllowedIPs []string
+
+// These constants define a parameter which names the deployment created in Azure Resource Manager. When it is not
+// specified, a name unique to each run and site is generated so that the resource group's deployment history is
+// preserved, even when several sites are provisioned into it.
+const (
+	DeploymentNameName  = "deployment-name"
+	deploymentNameUsage = "The name of the deployment created in Azure. Defaults to the site name, followed by the time of this run."
+)
+
+// deploymentNamePattern matches the names that Azure Resource Manager allows deployments to be given.
+var deploymentNamePattern = regexp.MustCompile(`^[-\w.()]{1,64}$`)
+
+// newDeploymentName generates a name for a deployment of a site started at a particular time. Long site names are
+// shortened, so that the name fits within the 64 characters Azure Resource Manager allows.
+func newDeploymentName(site string, started time.Time) string {
+	const maxSiteLength = 48
+	if site == "" {
+		site = siteDefaultPrefix
+	} else if len(site) > maxSiteLength {
+		site = site[:maxSiteLength]
+	}
+	return site + "-" + started.UTC().Format("20060102-150405")
+}
+
+// These constants define a parameter which allows resource providers that the deployment relies on to be registered in
+// the subscription, if they are not already. Without it, provisioning stops and reports which are missing.
+const (
+	RegisterProvidersName  = "register-providers"
+	registerProvidersUsage = "Register any resource providers the deployment needs that are not yet registered in the subscription."
+)
+
 // These constants define a parameter which toggles whether or not a deployment is actually started.
 const (
 	SkipDeploymentName      = "skip-deployment"
@@ -295,6 +621,14 @@ const (
 	DockerRegistryPasswordEnvVar = "BUFFALO_AZURE_DOCKER_PASSWORD"
 )
 
+// These constants define a parameter which routes all outbound requests through an HTTP(S) proxy.
+//
+// When not specified, the environment variables HTTPS_PROXY, HTTP_PROXY, and NO_PROXY are honored.
+const (
+	ProxyName  = "proxy"
+	proxyUsage = "The URL of an HTTP(S) proxy that all outbound requests should be sent through. Defaults to the value of HTTPS_PROXY or HTTP_PROXY."
+)
+
 // DockerAccess is an enum that contains either "private" or "public"
 type DockerAccess string
 
@@ -318,180 +652,197 @@ var debug string
 
 var deployParams *DeploymentParameters
 
+// deployEnvironment and deployClient are selected by provisionCmd's Args, from --az-env and --proxy.
+var (
+	deployEnvironment azure.Environment
+	deployClient      *http.Client
+)
+
 // provisionCmd represents the provision command
 var provisionCmd = &cobra.Command{
 	Aliases: []string{"p"},
 	Use:     "provision",
 	Short:   "Create the infrastructure necessary to run a buffalo app on Azure.",
 	Run: func(cmd *cobra.Command, args []string) {
-		// Authenticate and setup clients
-		subscriptionID := provisionConfig.GetString(SubscriptionName)
-		clientID := provisionConfig.GetString(ClientIDName)
-		clientSecret := provisionConfig.GetString(ClientSecretName)
-		templateLocation := provisionConfig.GetString(TemplateName)
-		image := provisionConfig.GetString(ImageName)
-		databaseType := provisionConfig.GetString(DatabaseTypeName)
-		databaseName := provisionConfig.GetString(DatabaseNameName)
-		databaseAdmin := provisionConfig.GetString(DatabaseAdminName)
-		siteName := provisionConfig.GetString(SiteName)
+		ctx, cancel := context.WithTimeout(context.Background(), provisionConfig.GetDuration(TimeoutName))
+		defer cancel()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 45*time.Minute)
+		ctx, cancel = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 		defer cancel()
 
-		var err error
-		var auth autorest.Authorizer
+		log.Debug(ProxyName+" selected: ", redactProxy(provisionConfig.GetString(ProxyName)))
 
-		if !provisionConfig.GetBool(SkipDeploymentName) {
-			auth, err = getAuthorizer(ctx, subscriptionID, clientID, clientSecret, provisionConfig.GetString(TenantIDName))
-			if err != nil {
-				log.Error("unable to authenticate: ", err)
-				return
-			}
+		// Unless a template was asked for, the copy distributed with buffalo-azure is deployed rather than the one
+		// published at TemplateDefaultLink, which may not declare every parameter this release passes.
+		templateLocation := provisionConfig.GetString(TemplateName)
+		if templateLocation == TemplateDefaultLink && !cmd.Flags().Changed(TemplateName) {
+			templateLocation = ""
 		}
 
-		log.Debug(TenantIDName+" selected: ", provisionConfig.GetString(TenantIDName))
-		log.Debug(SubscriptionName+" selected: ", subscriptionID)
-		log.Debug(TemplateName+" selected: ", templateLocation)
-		log.Debug(DatabaseTypeName+" selected: ", databaseType)
-		log.Debug(DatabaseNameName+" selected: ", databaseName)
-		log.Debug(DatabaseAdminName+" selected: ", databaseAdmin)
+		opts := ProvisionOptions{
+			SubscriptionID:         provisionConfig.GetString(SubscriptionName),
+			TenantID:               provisionConfig.GetString(TenantIDName),
+			ClientID:               provisionConfig.GetString(ClientIDName),
+			ClientSecret:           provisionConfig.GetString(ClientSecretName),
+			UseDeviceAuth:          provisionConfig.GetBool(DeviceAuthName),
+			UseDefaultCredential:   provisionConfig.GetBool(DefaultCredentialName),
+			DeviceClientID:         provisionConfig.GetString(DeviceClientIDName),
+			Environment:            deployEnvironment,
+			HTTPClient:             deployClient,
+			ResourceGroup:          provisionConfig.GetString(ResoureGroupName),
+			RequireNewGroup:        provisionConfig.GetBool(RequireNewGroupName),
+			RequireExistingGroup:   provisionConfig.GetBool(RequireExistingGroupName),
+			Location:               provisionConfig.GetString(LocationName),
+			SiteName:               provisionConfig.GetString(SiteName),
+			Image:                  provisionConfig.GetString(ImageName),
+			PinImage:               provisionConfig.GetBool(PinImageName),
+			Source:                 provisionConfig.GetString(SourceName),
+			PlanSKU:                provisionConfig.GetString(PlanSKUName),
+			PlanCapacity:           provisionConfig.GetInt(PlanCapacityName),
+			PlanID:                 provisionConfig.GetString(PlanIDName),
+			HostOS:                 provisionConfig.GetString(HostOSName),
+			SystemIdentity:         provisionConfig.GetBool(SystemIdentityName),
+			UserIdentityID:         provisionConfig.GetString(UserIdentityName),
+			CustomDomain:           provisionConfig.GetString(CustomDomainName),
+			ManagedCertificate:     provisionConfig.GetBool(ManagedCertificateName),
+			AppSettings:            appSettings,
+			StartupCommand:         provisionConfig.GetString(StartupCommandName),
+			WaitHealthy:            provisionConfig.GetBool(WaitHealthyName),
+			HealthPath:             provisionConfig.GetString(HealthPathName),
+			HealthTimeout:          provisionConfig.GetDuration(HealthTimeoutName),
+			DatabaseType:           provisionConfig.GetString(DatabaseTypeName),
+			DatabaseName:           provisionConfig.GetString(DatabaseNameName),
+			DatabaseAdmin:          provisionConfig.GetString(DatabaseAdminName),
+			DatabasePassword:       provisionConfig.GetString(DatabasePasswordName),
+			DatabasePasswordLength: provisionConfig.GetInt(DatabasePasswordLengthName),
+			DatabaseVersion:        provisionConfig.GetString(DatabaseVersionName),
+			DatabaseSKU:            provisionConfig.GetString(DatabaseSKUName),
+			DatabaseStorageGB:      provisionConfig.GetInt(DatabaseStorageName),
+			DatabaseAllowedIPs:     databaseAllowedIPs,
+			DatabaseBlockAzure:     !provisionConfig.GetBool(DatabaseAllowAzureServicesName),
+			EnvFile:                "./.env",
+			KeyVault:               provisionConfig.GetString(KeyVaultName),
+			CreateKeyVault:         provisionConfig.GetBool(CreateKeyVaultName),
+			DockerRegistryAccess:   provisionConfig.GetString(DockerRegistryAccessName),
+			DockerRegistryURL:      provisionConfig.GetString(DockerRegistryURLName),
+			DockerRegistryUsername: provisionConfig.GetString(DockerRegistryUsernameName),
+			DockerRegistryPassword: provisionConfig.GetString(DockerRegistryPasswordName),
+			TemplateLocation:       templateLocation,
+			TemplateDigest:         provisionConfig.GetString(TemplateSHA256Name),
+			TemplateAuth:           provisionConfig.GetBool(TemplateAuthName),
+			TemplateRetries:        provisionConfig.GetInt(TemplateDownloadRetriesName),
+			TemplateTimeout:        provisionConfig.GetDuration(TemplateDownloadTimeoutName),
+			Parameters:             deployParams,
+			CacheDir:               provisionConfig.GetString(CacheDirName),
+			SkipTemplateCache:      provisionConfig.GetBool(SkipTemplateCacheName),
+			SkipParameterCache:     provisionConfig.GetBool(SkipParameterCacheName),
+			DeploymentName:         provisionConfig.GetString(DeploymentNameName),
+			RegisterProviders:      provisionConfig.GetBool(RegisterProvidersName),
+			SkipDeployment:         provisionConfig.GetBool(SkipDeploymentName),
+			Redact:                 redactor.Add,
+		}
 
-		if usingDB, dbPassword := !strings.EqualFold(provisionConfig.GetString(DatabaseTypeName), "none"), provisionConfig.GetString(DatabasePasswordName); usingDB && dbPassword == DatabasePasswordDefault {
-			newPass := randname.GenerateWithPrefix("MSFT+Buffalo-", 20)
-			provisionConfig.Set(DatabasePasswordName, newPass)
-			log.Debug("generated database password")
-		} else if usingDB {
-			log.Debug("using provided password")
+		if provisionConfig.GetBool(ShowTemplateName) {
+			opts.ShowTemplate = os.Stdout
 		}
 
-		var envMap map[string]string
-		const envFileLoc = "./.env"
-		if envMap, err = godotenv.Read(envFileLoc); err != nil {
-			envMap = make(map[string]string, 1)
+		if !provisionConfig.GetBool(YesName) && !provisionConfig.GetBool(NoInputName) && isTerminal(os.Stdin) {
+			opts.Confirm = func(summary string) (bool, error) {
+				return promptConfirmation(os.Stdin, os.Stdout, summary)
+			}
 		}
 
-		envMap[DatabasePasswordEnvVar] = provisionConfig.GetString(DatabasePasswordName)
-		envMap[DockerRegistryPasswordEnvVar] = provisionConfig.GetString(DockerRegistryPasswordName)
+		if opts.DatabasePassword == DatabasePasswordDefault {
+			opts.DatabasePassword = ""
+		}
 
-		if err := godotenv.Write(envMap, envFileLoc); err == nil {
-			log.Debugf("wrote passwords to %q", envFileLoc)
-		} else {
-			log.Errorf("unable to passwords to %q", envFileLoc)
+		if provisionConfig.GetBool(NoEnvWriteName) || provisionConfig.GetBool(SkipEnvReadName) {
+			opts.EnvFile = ""
 		}
 
-		log.Debug(ImageName+" selected: ", image)
+		if provisionConfig.GetBool(EnvDryRunName) {
+			opts.EnvDryRun = os.Stdout
+		}
 
-		// Provision the necessary assets.
+		result, err := Provision(ctx, opts)
+		if err != nil {
+			log.Error(err)
+		}
 
-		deployParams.Parameters["name"] = DeploymentParameter{siteName}
-		deployParams.Parameters["database"] = DeploymentParameter{strings.ToLower(databaseType)}
-		deployParams.Parameters["databaseName"] = DeploymentParameter{databaseName}
-		deployParams.Parameters["imageName"] = DeploymentParameter{image}
-		deployParams.Parameters["databaseAdministratorLogin"] = DeploymentParameter{databaseAdmin}
-		deployParams.Parameters["databaseAdministratorLoginPassword"] = DeploymentParameter{provisionConfig.GetString(DatabasePasswordName)}
-		deployParams.Parameters["dockerRegistryAccess"] = DeploymentParameter{provisionConfig.GetString(DockerRegistryAccessName)}
-		deployParams.Parameters["dockerRegistryServerURL"] = DeploymentParameter{provisionConfig.GetString(DockerRegistryURLName)}
-		deployParams.Parameters["dockerRegistryServerUsername"] = DeploymentParameter{provisionConfig.GetString(DockerRegistryUsernameName)}
-		deployParams.Parameters["dockerRegistryServerPassword"] = DeploymentParameter{provisionConfig.GetString(DockerRegistryPasswordName)}
+		if code := exitCode(result, err, provisionConfig.GetBool(CacheBestEffortName)); code != ExitSuccess {
+			os.Exit(code)
+		}
+	},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if !provisionConfig.GetBool(SkipEnvReadName) {
+			if err := loadEnv("./.env"); err != nil {
+				return fmt.Errorf("%v, fix it or use --%s to ignore it", err, SkipEnvReadName)
+			}
+		}
 
-		template, err := getDeploymentTemplate(ctx, templateLocation)
-		if err != nil {
-			log.Error("unable to fetch template: ", err)
-			return
+		if provisionConfig.GetString(SubscriptionName) == "" {
+			return fmt.Errorf("no value found for %q", SubscriptionName)
 		}
 
-		template.Parameters = deployParams.Parameters
-		template.Mode = resources.Incremental
+		hasClientID := provisionConfig.GetString(ClientIDName) != ""
+		hasClientSecret := provisionConfig.GetString(ClientSecretName) != ""
 
-		deploymentResults := make(chan error)
-		if provisionConfig.GetBool(SkipDeploymentName) {
-			close(deploymentResults)
-		} else {
-			go func(errOut chan<- error) {
-				defer close(errOut)
-				groups := resources.NewGroupsClient(subscriptionID)
-				groups.Authorizer = auth
-				groups.AddToUserAgent(userAgent)
-
-				// Assert the presence of the specified Resource Group
-				rgName := provisionConfig.GetString(ResoureGroupName)
-				created, err := insertResourceGroup(ctx, groups, rgName, provisionConfig.GetString(LocationName))
-				if err != nil {
-					log.Errorf("unable to fetch or create resource group %s: %v\n", rgName, err)
-					errOut <- err
-					return
-				}
-				if created {
-					log.Info("created resource group: ", rgName)
-				} else {
-					log.Info("found resource group: ", rgName)
-				}
-				log.Debug("site name selected: ", siteName)
+		if provisionConfig.GetBool(DefaultCredentialName) {
+			if provisionConfig.GetBool(DeviceAuthName) {
+				return fmt.Errorf("--%s and --%s can't be used together", DefaultCredentialName, DeviceAuthName)
+			}
+		} else if !hasClientSecret && !hasClientID {
+			provisionConfig.Set(DeviceAuthName, true)
+		} else if (hasClientID || hasClientSecret) && !(hasClientID && hasClientSecret) {
+			return errors.New("--client-id and --client-secret must be specified together or not at all")
+		}
 
-				pLink := portalLink(subscriptionID, rgName)
+		if provisionConfig.GetBool(NoInputName) && provisionConfig.GetBool(DeviceAuthName) && !provisionConfig.GetBool(SkipDeploymentName) && !provisionConfig.GetBool(ShowTemplateName) {
+			return fmt.Errorf("--%s was specified, but authenticating requires interactive Device Auth. Provide --%s and --%s instead", NoInputName, ClientIDName, ClientSecretName)
+		}
 
-				log.Info("beginning deployment")
-				if err := doDeployment(ctx, auth, subscriptionID, rgName, template); err == nil {
-					log.Infof("Check on your new Resource Group in the Azure Portal: %s\nYour site will be available shortly at: https://%s.azurewebsites.net\n", pLink, siteName)
-				} else {
-					log.Warnf("unable to poll for completion progress, your assets may or may not have finished provisioning.\nCheck on their status in the portal: %s\nError: %v\n", pLink, err)
-					errOut <- err
-					return
-				}
-				log.Info("finished deployment")
-			}(deploymentResults)
+		if dn := provisionConfig.GetString(DeploymentNameName); dn != "" && !deploymentNamePattern.MatchString(dn) {
+			return fmt.Errorf("%s must be 1 to 64 letters, digits, or any of \"-_.()\"", DeploymentNameName)
 		}
 
-		doCache := func(ctx context.Context, errOut chan<- error, contents interface{}, location, flavor string) {
-			defer close(errOut)
-			log.Info("caching ", flavor)
-			err := cache(ctx, contents, location)
-			if err != nil {
-				log.Errorf("unable to cache file %s because: %v", location, err)
-				errOut <- err
-				return
-			}
-			log.Debugf("%s cached", flavor)
+		if provisionConfig.GetBool(RequireNewGroupName) && provisionConfig.GetBool(RequireExistingGroupName) {
+			return fmt.Errorf("--%s and --%s can't be used together", RequireNewGroupName, RequireExistingGroupName)
 		}
 
-		templateSaveResults, parameterSaveResults := make(chan error), make(chan error)
-		if provisionConfig.GetBool(SkipTemplateCacheName) {
-			close(templateSaveResults)
-		} else {
-			go doCache(ctx, templateSaveResults, template.Template, TemplateDefault, "template")
+		if provisionConfig.GetBool(ManagedCertificateName) && provisionConfig.GetString(CustomDomainName) == "" {
+			return fmt.Errorf("--%s requires --%s", ManagedCertificateName, CustomDomainName)
 		}
 
-		if provisionConfig.GetBool(SkipParameterCacheName) {
-			close(parameterSaveResults)
-		} else {
-			go doCache(ctx, parameterSaveResults, stripPasswords(deployParams), TemplateParametersDefault, "parameters")
+		rawSettings, err := cmd.Flags().GetStringArray(AppSettingName)
+		if err != nil {
+			return err
+		}
+		if appSettings, err = parseAppSettings(rawSettings); err != nil {
+			return err
 		}
 
-		waitOnResults := func(ctx context.Context, results <-chan error) error {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case err := <-results:
-				return err
-			}
+		if databaseAllowedIPs, err = cmd.Flags().GetStringArray(DatabaseAllowIPName); err != nil {
+			return err
+		}
+		if _, err = parseFirewallRules(databaseAllowedIPs); err != nil {
+			return err
 		}
 
-		waitOnResults(ctx, templateSaveResults)
-		waitOnResults(ctx, parameterSaveResults)
-		waitOnResults(ctx, deploymentResults)
-	},
-	Args: func(cmd *cobra.Command, args []string) error {
-		if provisionConfig.GetString(SubscriptionName) == "" {
-			return fmt.Errorf("no value found for %q", SubscriptionName)
+		if provisionConfig.GetString(KeyVaultName) != "" && provisionConfig.GetBool(SkipDeploymentName) {
+			return fmt.Errorf("--%s can't be used with --%s, passwords are saved to the key vault by the deployment", KeyVaultName, SkipDeploymentName)
 		}
 
-		hasClientID := provisionConfig.GetString(ClientIDName) != ""
-		hasClientSecret := provisionConfig.GetString(ClientSecretName) != ""
+		if provisionConfig.GetBool(CreateKeyVaultName) && provisionConfig.GetString(KeyVaultName) == "" {
+			return fmt.Errorf("--%s requires --%s, to name the key vault", CreateKeyVaultName, KeyVaultName)
+		}
 
-		if !hasClientSecret && !hasClientID {
-			provisionConfig.Set(DeviceAuthName, true)
-		} else if (hasClientID || hasClientSecret) && !(hasClientID && hasClientSecret) {
-			return errors.New("--client-id and --client-secret must be specified together or not at all")
+		if provisionConfig.GetBool(EnvDryRunName) {
+			if provisionConfig.GetString(KeyVaultName) != "" {
+				return fmt.Errorf("--%s can't be used with --%s, passwords are saved to the key vault instead of the .env file", EnvDryRunName, KeyVaultName)
+			}
+			if provisionConfig.GetBool(NoEnvWriteName) || provisionConfig.GetBool(SkipEnvReadName) {
+				return fmt.Errorf("--%s can't be used with --%s or --%s, the .env file isn't written", EnvDryRunName, NoEnvWriteName, SkipEnvReadName)
+			}
 		}
 
 		if rootConfig.GetBool(VerboseName) {
@@ -520,21 +871,125 @@ var provisionCmd = &cobra.Command{
 			log.SetLevel(level)
 		}
 
-		if provisionConfig.GetString(TemplateName) == TemplateDefault {
+		if err := setLogFormat(rootConfig.GetString(logFormatName)); err != nil {
+			return err
+		}
+
+		if tl := provisionConfig.GetString(TemplateName); tl == TemplateDefault || filepath.Clean(tl) == cacheLocation(provisionConfig.GetString(CacheDirName), TemplateDefault) {
 			provisionConfig.Set(SkipTemplateCacheName, true)
 		}
 
+		if pl := provisionConfig.GetInt(DatabasePasswordLengthName); pl < passwordMinLength || pl > passwordMaxLength {
+			return fmt.Errorf("%s must be between %d and %d", DatabasePasswordLengthName, passwordMinLength, passwordMaxLength)
+		}
+
+		if err := checkDatabaseVersion(provisionConfig.GetString(DatabaseTypeName), provisionConfig.GetString(DatabaseVersionName)); err != nil {
+			return err
+		}
+
+		if err := checkDatabaseSKU(provisionConfig.GetString(DatabaseSKUName)); err != nil {
+			return err
+		}
+
+		if err := checkDatabaseStorage(provisionConfig.GetString(DatabaseSKUName), provisionConfig.GetInt(DatabaseStorageName)); err != nil {
+			return err
+		}
+
+		if provisionConfig.GetInt(TemplateDownloadRetriesName) < 1 {
+			return fmt.Errorf("%s must be at least 1", TemplateDownloadRetriesName)
+		}
+
+		if provisionConfig.GetDuration(TimeoutName) <= 0 {
+			return fmt.Errorf("%s must be positive", TimeoutName)
+		}
+
+		if provisionConfig.GetDuration(TemplateDownloadTimeoutName) < 0 {
+			return fmt.Errorf("%s must not be negative", TemplateDownloadTimeoutName)
+		}
+
+		if pc := provisionConfig.GetInt(PlanCapacityName); pc < 0 || pc > planCapacityLimit {
+			return fmt.Errorf("%s must be between 1 and %d, or 0 to use the template's default", PlanCapacityName, planCapacityLimit)
+		}
+
+		if id := provisionConfig.GetString(PlanIDName); id != "" {
+			if !isAppServicePlanID(id) {
+				return fmt.Errorf("%s must be the resource ID of an App Service plan, for example: /subscriptions/{subscription}/resourceGroups/{group}/providers/Microsoft.Web/serverfarms/{name}", PlanIDName)
+			}
+
+			if provisionConfig.GetString(PlanSKUName) != "" || provisionConfig.GetInt(PlanCapacityName) > 0 {
+				log.Warnf("--%s and --%s are ignored when --%s is provided", PlanSKUName, PlanCapacityName, PlanIDName)
+			}
+		}
+
+		if id := provisionConfig.GetString(UserIdentityName); id != "" && !isUserAssignedIdentityID(id) {
+			return fmt.Errorf("%s must be the resource ID of a user-assigned managed identity, for example: /subscriptions/{subscription}/resourceGroups/{group}/providers/Microsoft.ManagedIdentity/userAssignedIdentities/{name}", UserIdentityName)
+		}
+
+		hostOS := strings.ToLower(provisionConfig.GetString(HostOSName))
+		if hostOS != HostOSLinux && hostOS != HostOSWindows {
+			return fmt.Errorf("%s must be either %q or %q", HostOSName, HostOSLinux, HostOSWindows)
+		}
+
+		if source := provisionConfig.GetString(SourceName); source != "" {
+			if hostOS == HostOSWindows {
+				return fmt.Errorf("--%s can't be used with --%s %s, only Linux sites can be deployed from source", SourceName, HostOSName, HostOSWindows)
+			}
+
+			if !isSupportedLink(source) {
+				if info, err := os.Stat(source); err != nil || !info.IsDir() {
+					return fmt.Errorf("%s must be a directory or a link to a zip archive", SourceName)
+				}
+			}
+
+			if cmd.Flags().Changed(ImageName) || provisionConfig.GetBool(PinImageName) {
+				log.Warnf("--%s and --%s are ignored when --%s is provided", ImageName, PinImageName, SourceName)
+			}
+		}
+
+		if sku := provisionConfig.GetString(PlanSKUName); sku != "" && hostOS == HostOSWindows {
+			if _, ok := windowsContainerSKUs[strings.ToUpper(sku)]; !ok {
+				log.Warnf("%s %q may not be able to host Windows containers, consider a Premium v3 tier like %s", PlanSKUName, sku, windowsContainerSKUDefault)
+			}
+		} else if sku != "" {
+			if _, ok := knownPlanSKUs[strings.ToUpper(sku)]; !ok {
+				log.Warnf("%s %q is not a known pricing tier, it will be passed to the template as is", PlanSKUName, sku)
+			}
+		}
+
 		if provisionConfig.GetString(LocationName) == LocationDefaultText {
 			provisionConfig.SetDefault(LocationName, LocationDefault)
 		}
 
-		paramFile := provisionConfig.GetString(TemplateParametersName)
-		p, err := loadFromParameterFile(paramFile)
-		if err == nil {
-			setDefaults(provisionConfig, p)
-			deployParams = p
-		} else if paramFile != TemplateParametersDefault {
-			return fmt.Errorf("unable to load parameters file: %v", err)
+		paramFiles, optionalFile := provisionConfig.GetStringSlice(TemplateParametersName), TemplateParametersDefault
+		if cmd.Flags().Changed(SiteName) && !cmd.Flags().Changed(TemplateParametersName) {
+			cacheDir := provisionConfig.GetString(CacheDirName)
+			if own := parametersCacheLocation(cacheDir, provisionConfig.GetString(SiteName)); own != cacheLocation(cacheDir, TemplateParametersDefault) {
+				log.Debugf("parameters in %q belong to another site, using %q instead", TemplateParametersDefault, own)
+				restoreDefaults(provisionConfig, baseDefaults)
+				deployParams = NewDeploymentParameters()
+				paramFiles, optionalFile = []string{own}, own
+			}
+		}
+
+		merged, loadedAny := NewDeploymentParameters(), false
+		for _, paramFile := range paramFiles {
+			var p *DeploymentParameters
+			var err error
+			if paramFile == TemplateParametersStdin {
+				p, err = loadParameters(os.Stdin)
+			} else {
+				p, err = loadFromParameterFile(paramFile)
+			}
+			if err == nil {
+				mergeParameters(merged, p)
+				loadedAny = true
+			} else if paramFile != optionalFile {
+				return fmt.Errorf("unable to load parameters file %q: %v", paramFile, err)
+			}
+		}
+		if loadedAny {
+			setDefaults(provisionConfig, merged)
+			deployParams = merged
 		}
 
 		nameGenerator := randname.Prefixed{
@@ -551,11 +1006,17 @@ var provisionCmd = &cobra.Command{
 			provisionConfig.Set(ResoureGroupName, provisionConfig.GetString(SiteName))
 		}
 
-		environment, err = azure.EnvironmentFromName(provisionConfig.GetString(EnvironmentName))
+		deployEnvironment, err = azure.EnvironmentFromName(provisionConfig.GetString(EnvironmentName))
 		if err != nil {
 			return err
 		}
 
+		proxy, err := getProxy(provisionConfig.GetString(ProxyName))
+		if err != nil {
+			return err
+		}
+		deployClient = newHTTPClient(proxy)
+
 		return nil
 	},
 }
@@ -574,29 +1035,118 @@ func stripPasswords(original *DeploymentParameters) (copy *DeploymentParameters)
 	return copy
 }
 
+// cacheLocation finds the path that a file should be cached at inside of a particular directory.
+func cacheLocation(dir, filename string) string {
+	return filepath.Join(dir, filepath.Base(filename))
+}
+
+// cache saves contents as JSON to outputName. The file is written in full before it replaces any previous copy, so a
+// failed write never leaves a partial file where a later run would find it. Should ctx be cancelled first, the previous
+// copy is left alone.
 func cache(ctx context.Context, contents interface{}, outputName string) error {
-	if handle, err := os.Create(outputName); err == nil {
-		defer handle.Close()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-		enc := json.NewEncoder(handle)
-		enc.SetIndent("", "  ")
-		err = enc.Encode(contents)
-		if err != nil {
-			return err
-		}
-	} else {
+	if err := os.MkdirAll(filepath.Dir(outputName), os.ModePerm); err != nil {
 		return err
 	}
-	return nil
+
+	handle, err := ioutil.TempFile(filepath.Dir(outputName), filepath.Base(outputName)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(handle.Name())
+
+	enc := json.NewEncoder(handle)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(contents); err != nil {
+		handle.Close()
+		return err
+	}
+
+	if err = handle.Close(); err != nil {
+		return err
+	}
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return os.Rename(handle.Name(), outputName)
 }
 
 func portalLink(subscriptionID, rgName string) string {
 	return fmt.Sprintf("https://portal.azure.com/#resource/subscriptions/%s/resourceGroups/%s/overview", subscriptionID, rgName)
 }
 
+// resourceGroupLocation finds the Azure Region that a site will be deployed to. When the resource group already exists,
+// that is the group's location, regardless of the location requested. Otherwise, the group will be created in the
+// requested location.
+func resourceGroupLocation(ctx context.Context, groups resourceGroupsClient, name string, requested string) (string, error) {
+	existenceResp, err := groups.CheckExistence(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	switch existenceResp.StatusCode {
+	case http.StatusNotFound:
+		log.WithFields(logrus.Fields{"location": requested}).Debug("resource group will be created in the requested location")
+		return requested, nil
+	case http.StatusNoContent:
+		group, err := groups.Get(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if group.Location == nil || *group.Location == "" {
+			return "", fmt.Errorf("resource group %q has no location", name)
+		}
+
+		existing := *group.Location
+		if normalizeRegion(existing) != normalizeRegion(requested) {
+			log.WithFields(logrus.Fields{
+				"location":  existing,
+				"requested": requested,
+			}).Info("resource group already exists, using its location instead of the requested location")
+		} else {
+			log.WithFields(logrus.Fields{"location": existing}).Debug("resource group already exists in the requested location")
+		}
+		return existing, nil
+	default:
+		return "", fmt.Errorf("unexpected status code %d during resource group existence check", existenceResp.StatusCode)
+	}
+}
+
+// checkResourceGroupExistence ensures that a resource group does not yet exist when requireNew is true, or that it
+// already exists when requireExisting is true.
+func checkResourceGroupExistence(ctx context.Context, groups resourceGroupsClient, name string, requireNew, requireExisting bool) error {
+	if !requireNew && !requireExisting {
+		return nil
+	}
+
+	existenceResp, err := groups.CheckExistence(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	switch existenceResp.StatusCode {
+	case http.StatusNoContent:
+		if requireNew {
+			return fmt.Errorf("resource group %q already exists, but --%s was provided", name, RequireNewGroupName)
+		}
+		return nil
+	case http.StatusNotFound:
+		if requireExisting {
+			return fmt.Errorf("resource group %q does not exist, but --%s was provided", name, RequireExistingGroupName)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unexpected status code %d during resource group existence check", existenceResp.StatusCode)
+	}
+}
+
 // insertResourceGroup checks for a Resource Groups's existence, if it is not found it creates that resource group. If
-// that resource group exists, it leaves it alone.
-func insertResourceGroup(ctx context.Context, groups resources.GroupsClient, name string, location string) (bool, error) {
+// that resource group exists, it leaves it alone: neither its location nor its tags are updated.
+func insertResourceGroup(ctx context.Context, groups resourceGroupsClient, name string, location string) (bool, error) {
 	existenceResp, err := groups.CheckExistence(ctx, name)
 	if err != nil {
 		return false, err
@@ -625,32 +1175,32 @@ func insertResourceGroup(ctx context.Context, groups resources.GroupsClient, nam
 	}
 }
 
-func getAuthorizer(ctx context.Context, subscriptionID, clientID, clientSecret, tenantID string) (autorest.Authorizer, error) {
+func (s *session) getAuthorizer(ctx context.Context, subscriptionID, clientID, clientSecret, tenantID string, useDeviceAuth bool) (autorest.Authorizer, error) {
 	const commonTenant = "common"
 
 	if tenantID == "" {
 		tenantID = commonTenant
 	}
 
-	config, err := adal.NewOAuthConfig(environment.ActiveDirectoryEndpoint, tenantID)
+	config, err := adal.NewOAuthConfig(s.environment.ActiveDirectoryEndpoint, tenantID)
 	if err != nil {
 		return nil, err
 	}
 
-	if provisionConfig.GetBool(DeviceAuthName) {
+	if useDeviceAuth {
 		var intermediate *adal.Token
 
-		client := &http.Client{}
+		sender := contextSender{ctx: ctx, Client: s.httpClient}
 		code, err := adal.InitiateDeviceAuth(
-			client,
+			sender,
 			*config,
-			deviceClientID,
-			environment.ResourceManagerEndpoint)
+			s.deviceClientID,
+			s.environment.ResourceManagerEndpoint)
 		if err != nil {
 			return nil, err
 		}
 		fmt.Println(*code.Message)
-		token, err := adal.WaitForUserCompletion(client, code)
+		token, err := waitForDeviceAuth(ctx, sender, code, os.Stdout)
 		if err != nil {
 			return nil, err
 		}
@@ -658,14 +1208,16 @@ func getAuthorizer(ctx context.Context, subscriptionID, clientID, clientSecret,
 
 		if tenantID == commonTenant {
 			var final autorest.Authorizer
-			tenantID, final, err = getTenant(ctx, intermediate, subscriptionID)
+			tenantID, final, err = s.getTenant(ctx, intermediate, subscriptionID)
 			if err != nil {
 				return nil, err
 			}
 
+			s.tokenFactory = s.deviceTokenFactory(tenantID, intermediate.RefreshToken)
 			return final, nil
 		}
-		return autorest.NewBearerAuthorizer(intermediate), nil
+		s.tokenFactory = s.deviceTokenFactory(tenantID, intermediate.RefreshToken)
+		return s.newDeviceAuthorizer(tenantID, *intermediate)
 	}
 
 	if tenantID == commonTenant {
@@ -676,11 +1228,45 @@ func getAuthorizer(ctx context.Context, subscriptionID, clientID, clientSecret,
 		*config,
 		clientID,
 		clientSecret,
-		environment.ResourceManagerEndpoint)
+		s.environment.ResourceManagerEndpoint)
 	if err != nil {
 		return nil, err
 	}
+	auth.SetSender(s.httpClient)
 	log.WithFields(logrus.Fields{"client": clientID}).Debug("service principal token created")
+
+	s.tokenFactory = func(resource string) (*adal.ServicePrincipalToken, error) {
+		return adal.NewServicePrincipalToken(*config, clientID, clientSecret, resource)
+	}
+	return autorest.NewBearerAuthorizer(auth), nil
+}
+
+// deviceTokenFactory creates tokens for arbitrary resources by redeeming the refresh token acquired during Device Auth.
+func (s *session) deviceTokenFactory(tenantID, refreshToken string) func(string) (*adal.ServicePrincipalToken, error) {
+	return func(resource string) (*adal.ServicePrincipalToken, error) {
+		config, err := adal.NewOAuthConfig(s.environment.ActiveDirectoryEndpoint, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		return adal.NewServicePrincipalTokenFromManualToken(*config, s.deviceClientID, resource, adal.Token{
+			RefreshToken: refreshToken,
+		})
+	}
+}
+
+// newDeviceAuthorizer creates an Authorizer for Azure Resource Manager from a token acquired during Device Auth. The
+// token is refreshed as it nears expiration, so that long-running deployments can still be polled to completion.
+func (s *session) newDeviceAuthorizer(tenantID string, token adal.Token) (autorest.Authorizer, error) {
+	config, err := adal.NewOAuthConfig(s.environment.ActiveDirectoryEndpoint, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := adal.NewServicePrincipalTokenFromManualToken(*config, s.deviceClientID, s.environment.ResourceManagerEndpoint, token)
+	if err != nil {
+		return nil, err
+	}
+	auth.SetSender(s.httpClient)
 	return autorest.NewBearerAuthorizer(auth), nil
 }
 
@@ -698,42 +1284,63 @@ func getDatabaseFlavor(buffaloRoot, profile string) (string, string, error) {
 	return conn.Dialect.Name(), conn.Dialect.Details().Database, nil
 }
 
-func doDeployment(ctx context.Context, authorizer autorest.Authorizer, subscriptionID, resourceGroup string, properties *resources.DeploymentProperties) (err error) {
-	deployments := resources.NewDeploymentsClient(subscriptionID)
-	deployments.Authorizer = authorizer
-	deployments.AddToUserAgent(userAgent)
-
-	fut, err := deployments.CreateOrUpdate(ctx, resourceGroup, siteDefaultPrefix, resources.Deployment{Properties: properties})
+func doDeployment(ctx context.Context, deployments deploymentsClient, resourceGroup, deploymentName string, properties *resources.DeploymentProperties) (err error) {
+	fut, err := deployments.CreateOrUpdate(ctx, resourceGroup, deploymentName, resources.Deployment{Properties: properties})
 	if err != nil {
 		return
 	}
 
-	err = fut.WaitForCompletion(ctx, deployments.Client)
+	err = deployments.WaitForCompletion(ctx, fut)
 	return
 }
 
-func getDeploymentTemplate(ctx context.Context, raw string) (*resources.DeploymentProperties, error) {
+// getDeploymentTemplate reads the template found at a link or local path. If digest is not empty, the template's
+// contents must have a SHA-256 digest matching it. If prepare is not nil, it is given the opportunity to authorize
+// each request sent to download the template. The resource ID of a Template Spec version isn't read, the deployment
+// refers to it instead, see templateSpecLink.
+func (s *session) getDeploymentTemplate(ctx context.Context, raw string, digest string, prepare templatePreparer) (*resources.DeploymentProperties, error) {
+	if isTemplateSpecID(raw) {
+		if digest != "" {
+			return nil, fmt.Errorf("template spec %s is deployed by reference, its digest can't be verified", raw)
+		}
+		return &resources.DeploymentProperties{
+			TemplateLink: &resources.TemplateLink{URI: &raw},
+		}, nil
+	}
+
+	var contents []byte
 	if isSupportedLink(raw) {
 		buf := bytes.NewBuffer([]byte{})
 
-		err := downloadTemplate(ctx, buf, raw)
+		err := s.downloadTemplate(ctx, buf, raw, prepare)
 		if err != nil {
 			return nil, err
 		}
+		contents = buf.Bytes()
+	} else if isBicepFile(raw) {
+		var err error
+		contents, err = compileBicep(ctx, raw)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		handle, err := os.Open(raw)
+		if err != nil {
+			return nil, err
+		}
+		defer handle.Close()
 
-		return &resources.DeploymentProperties{
-			Template: json.RawMessage(buf.Bytes()),
-		}, nil
-	}
-
-	handle, err := os.Open(raw)
-	if err != nil {
-		return nil, err
+		contents, err = ioutil.ReadAll(handle)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	contents, err := ioutil.ReadAll(handle)
-	if err != nil {
-		return nil, err
+	if digest != "" {
+		if err := verifyDigest(contents, digest); err != nil {
+			return nil, err
+		}
+		log.Debug("template digest verified")
 	}
 
 	return &resources.DeploymentProperties{
@@ -741,6 +1348,24 @@ func getDeploymentTemplate(ctx context.Context, raw string) (*resources.Deployme
 	}, nil
 }
 
+// digestMismatchError is returned by verifyDigest when contents don't have the digest they were expected to have.
+type digestMismatchError struct {
+	got, want string
+}
+
+func (err digestMismatchError) Error() string {
+	return fmt.Sprintf("template digest mismatch: got %s want %s", err.got, err.want)
+}
+
+// verifyDigest ensures that the SHA-256 digest of contents matches the hex-encoded digest provided.
+func verifyDigest(contents []byte, want string) error {
+	sum := sha256.Sum256(contents)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, strings.TrimSpace(want)) {
+		return digestMismatchError{got: got, want: want}
+	}
+	return nil
+}
+
 var redirectCodes = map[int]struct{}{
 	http.StatusMovedPermanently:  {},
 	http.StatusPermanentRedirect: {},
@@ -759,37 +1384,100 @@ var acceptedCodes = map[int]struct{}{
 	http.StatusOK: {},
 }
 
-func downloadTemplate(ctx context.Context, dest io.Writer, src string) error {
-	const maxRedirects = 5
-	const maxRetries = 3
+// templateDownloadMaxRedirects is the largest number of HTTP redirects followed while downloading a template.
+const templateDownloadMaxRedirects = 5
+
+// templateDownloadBackoff is the delay before the first retry of a template download. It doubles with each retry.
+var templateDownloadBackoff = time.Second
+
+// waitToRetry pauses before the retry following a number of failed attempts, unless ctx is done first.
+func waitToRetry(ctx context.Context, failedAttempts int) error {
+	delay := templateDownloadBackoff << uint(failedAttempts-1)
+	log.WithFields(logrus.Fields{"delay": delay}).Debug("waiting before retrying template download")
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// errTemplateNotModified is returned by downloadTemplateIfModified when the template still has the ETag provided.
+var errTemplateNotModified = errors.New("template not modified")
+
+func (s *session) downloadTemplate(ctx context.Context, dest io.Writer, src string, prepare templatePreparer) error {
+	_, err := s.downloadTemplateIfModified(ctx, dest, src, prepare, "")
+	return err
+}
+
+// downloadTemplateIfModified writes the template found at src to dest, and returns the ETag it was served with. When
+// etag is not empty, it is sent as the value of "If-None-Match", and should the template still have that ETag,
+// errTemplateNotModified is returned without anything being written to dest.
+func (s *session) downloadTemplateIfModified(ctx context.Context, dest io.Writer, src string, prepare templatePreparer, etag string) (string, error) {
 	var download func(context.Context, io.Writer, string, uint) error
+	var served string
 
 	log.Debug("downloading template: ", src)
 
+	if s.templateTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.templateTimeout)
+		defer cancel()
+	}
+
 	download = func(ctx context.Context, dest io.Writer, src string, depth uint) (err error) {
-		if depth > maxRedirects {
+		if depth > templateDownloadMaxRedirects {
 			return errors.New("too many redirects")
 		}
 
-		for attempt := 0; attempt < maxRetries; attempt++ {
+		for attempt := 0; attempt < s.templateRetries; attempt++ {
 			var req *http.Request
 			var resp *http.Response
 
+			if attempt > 0 {
+				if err = waitToRetry(ctx, attempt); err != nil {
+					return
+				}
+			}
+
 			req, err = http.NewRequest(http.MethodGet, src, nil)
 			if err != nil {
 				return
 			}
 			req = req.WithContext(ctx)
 
-			resp, err = http.DefaultClient.Do(req)
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+
+			if prepare != nil {
+				req, err = prepare(req)
+				if err != nil {
+					return
+				}
+			}
+
+			resp, err = s.httpClient.Do(req)
 			if err != nil {
-				return
+				if ctx.Err() != nil {
+					return
+				}
+				log.WithFields(logrus.Fields{"error": err}).Debug("network failure, retrying.")
+				continue
 			}
 
 			if _, ok := acceptedCodes[resp.StatusCode]; ok {
+				served = resp.Header.Get("ETag")
 				_, err = io.Copy(dest, resp.Body)
+				resp.Body.Close()
 				return
 			}
+			resp.Body.Close()
+
+			if resp.StatusCode == http.StatusNotModified && etag != "" {
+				return errTemplateNotModified
+			}
 
 			statusCodeLogger := log.WithFields(logrus.Fields{"status-code": resp.StatusCode})
 
@@ -808,51 +1496,74 @@ func downloadTemplate(ctx context.Context, dest io.Writer, src string) error {
 			return
 		}
 
-		err = errors.New("too many attempts")
+		if err != nil {
+			err = fmt.Errorf("too many attempts: %v", err)
+		} else {
+			err = errors.New("too many attempts")
+		}
 		return
 	}
 
-	return download(ctx, dest, src, 1)
+	err := download(ctx, dest, src, 1)
+	return served, err
 }
 
-func getTenant(ctx context.Context, common *adal.Token, subscription string) (string, autorest.Authorizer, error) {
+func (s *session) getTenant(ctx context.Context, common *adal.Token, subscription string) (string, autorest.Authorizer, error) {
 	tenants := subscriptions.NewTenantsClient()
 	tenants.Authorizer = autorest.NewBearerAuthorizer(common)
+	tenants.Sender = s.httpClient
 
 	var err error
 	var tenantList subscriptions.TenantListResultIterator
 
-	subscriptionClient := subscriptions.NewClient()
+	// Display names are only unique within a tenant, so every tenant is searched for them.
+	var found []string
+	var foundAuth autorest.Authorizer
 
 	log.WithFields(logrus.Fields{"subscription": subscription}).Info("using authorization to infer tenant")
 
 	for tenantList, err = tenants.ListComplete(ctx); err == nil && tenantList.NotDone(); err = tenantList.Next() {
-		var subscriptionList subscriptions.ListResultIterator
 		currentTenant := *tenantList.Value().TenantID
-		currentConfig, err := adal.NewOAuthConfig(environment.ActiveDirectoryEndpoint, currentTenant)
+		currentConfig, err := adal.NewOAuthConfig(s.environment.ActiveDirectoryEndpoint, currentTenant)
 		if err != nil {
 			return "", nil, err
 		}
-		currentAuth, err := adal.NewServicePrincipalTokenFromManualToken(*currentConfig, deviceClientID, environment.ResourceManagerEndpoint, adal.Token{
+		currentAuth, err := adal.NewServicePrincipalTokenFromManualToken(*currentConfig, s.deviceClientID, s.environment.ResourceManagerEndpoint, adal.Token{
 			RefreshToken: common.RefreshToken,
 		})
 		if err != nil {
 			return "", nil, err
 		}
-		subscriptionClient.Authorizer = autorest.NewBearerAuthorizer(currentAuth)
+		currentAuth.SetSender(s.httpClient)
+		authorizer := autorest.NewBearerAuthorizer(currentAuth)
 
-		for subscriptionList, err = subscriptionClient.ListComplete(ctx); err == nil && subscriptionList.NotDone(); err = subscriptionList.Next() {
-			if currentSub := subscriptionList.Value(); currentSub.SubscriptionID != nil && strings.EqualFold(*currentSub.SubscriptionID, subscription) {
-				provisionConfig.Set(TenantIDName, *tenantList.Value().TenantID)
-				return *tenantList.Value().TenantID, subscriptionClient.Authorizer, nil
-			}
+		listed, err := listSubscriptions(ctx, s, authorizer)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if len(findSubscriptions(listed, subscription)) == 0 {
+			continue
+		}
+		log.WithFields(logrus.Fields{"tenant": currentTenant}).Debug("inferred tenant")
+
+		if isSubscriptionID(subscription) {
+			return currentTenant, authorizer, nil
 		}
+		found, foundAuth = append(found, currentTenant), authorizer
 	}
 	if err != nil {
 		return "", nil, err
 	}
 
-	return "", nil, fmt.Errorf("unable to find subscription: %s", subscription)
+	switch len(found) {
+	case 0:
+		return "", nil, fmt.Errorf("unable to find subscription: %s", subscription)
+	case 1:
+		return found[0], foundAuth, nil
+	default:
+		return "", nil, fmt.Errorf("subscriptions named %q were found in more than one tenant (%s), use the subscription's ID or --%s instead", subscription, strings.Join(found, ", "), TenantIDName)
+	}
 }
 
 var normalizeScheme = strings.ToLower
@@ -875,6 +1586,21 @@ func isSupportedLink(subject string) bool {
 	return ok
 }
 
+// parameterDefaultKeys are the settings whose defaults may be taken from a parameters file by setDefaults.
+var parameterDefaultKeys = []string{
+	SiteName,
+	DatabaseTypeName,
+	DatabaseNameName,
+	ImageName,
+	DatabaseAdminName,
+	DockerRegistryAccessName,
+	DockerRegistryURLName,
+	DockerRegistryUsernameName,
+}
+
+// baseDefaults are the defaults of parameterDefaultKeys before any parameters file was read.
+var baseDefaults map[string]interface{}
+
 func setDefaults(conf *viper.Viper, params *DeploymentParameters) {
 	if name, ok := params.Parameters["name"]; ok {
 		conf.SetDefault(SiteName, name.Value)
@@ -910,28 +1636,30 @@ func setDefaults(conf *viper.Viper, params *DeploymentParameters) {
 }
 
 func loadFromParameterFile(paramFile string) (*DeploymentParameters, error) {
+	handle, err := os.Open(paramFile)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+
+	return loadParameters(handle)
+}
+
+// loadParameters reads deployment parameters from r, for instance when they are piped to this program.
+func loadParameters(r io.Reader) (*DeploymentParameters, error) {
 	loaded := NewDeploymentParameters()
-	if _, err := os.Stat(TemplateParametersDefault); err == nil {
-		provisionConfig.SetDefault(TemplateParametersName, TemplateParametersDefault)
-		if handle, err := os.Open(provisionConfig.GetString(TemplateParametersName)); err == nil {
-			dec := json.NewDecoder(handle)
-			err = dec.Decode(loaded)
-			if err != nil {
-				return nil, err
-			}
-		}
-	} else {
+	if err := json.NewDecoder(r).Decode(loaded); err != nil {
 		return nil, err
 	}
 	return loaded, nil
 }
 
+// redactedMessage replaces secrets which would otherwise be printed.
+const redactedMessage = "[redacted]"
+
 func init() {
-	const redactedMessage = "[redacted]"
 	azureCmd.AddCommand(provisionCmd)
 
-	godotenv.Load()
-
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -946,6 +1674,7 @@ func init() {
 	provisionConfig.BindEnv(ClientIDName, "AZURE_CLIENT_ID", "AZ_CLIENT_ID")
 	provisionConfig.BindEnv(ClientSecretName, "AZURE_CLIENT_SECRET", "AZ_CLIENT_SECRET")
 	provisionConfig.BindEnv(TenantIDName, "AZURE_TENANT_ID", "AZ_TENANT_ID")
+	provisionConfig.BindEnv(DeviceClientIDName, "BUFFALO_AZURE_DEVICE_CLIENT_ID")
 	provisionConfig.BindEnv(EnvironmentName, "AZURE_ENVIRONMENT", "AZ_ENVIRONMENT")
 	provisionConfig.BindEnv(ProfileName, "GO_ENV")
 	provisionConfig.BindEnv(DatabasePasswordName, DatabasePasswordEnvVar, "BUFFALO_AZURE_DB_PASSWORD", "BUFFALO_AZ_DATABASE_PASSWORD", "BUFFALO_AZ_DB_PASSWORD")
@@ -986,7 +1715,8 @@ func init() {
 		provisionConfig.SetDefault(TemplateName, TemplateDefaultLink)
 	}
 
-	if p, err := loadFromParameterFile(provisionConfig.GetString(TemplateParametersName)); err == nil {
+	baseDefaults = snapshotDefaults(provisionConfig, parameterDefaultKeys)
+	if p, err := loadFromParameterFile(TemplateParametersDefault); err == nil {
 		setDefaults(provisionConfig, p)
 		deployParams = p
 	} else {
@@ -1012,23 +1742,67 @@ func init() {
 	provisionCmd.Flags().String(ClientIDName, provisionConfig.GetString(ClientIDName), clientIDUsage)
 	provisionCmd.Flags().String(ClientSecretName, sanitizedClientSecret, clientSecretUsage)
 	provisionCmd.Flags().Bool(DeviceAuthName, false, deviceAuthUsage)
+	provisionCmd.Flags().Bool(DefaultCredentialName, false, defaultCredentialUsage)
+	provisionCmd.Flags().String(DeviceClientIDName, provisionConfig.GetString(DeviceClientIDName), deviceClientIDUsage)
+	provisionCmd.Flags().Bool(NoInputName, false, noInputUsage)
+	provisionCmd.Flags().Bool(YesName, false, yesUsage)
+	provisionCmd.Flags().Duration(TimeoutName, TimeoutDefault, timeoutUsage)
 	provisionCmd.Flags().String(TenantIDName, provisionConfig.GetString(TenantIDName), tenantUsage)
 	provisionCmd.Flags().StringP(EnvironmentName, EnvironmentShorthand, provisionConfig.GetString(EnvironmentName), environmentUsage)
 	provisionCmd.Flags().String(DatabaseNameName, provisionConfig.GetString(DatabaseNameName), databaseNameUsage)
 	provisionCmd.Flags().StringP(DatabaseTypeName, DatabaseShorthand, provisionConfig.GetString(DatabaseTypeName), databaseUsage)
 	provisionCmd.Flags().StringP(ResoureGroupName, ResourceGroupShorthand, provisionConfig.GetString(ResoureGroupName), resourceGroupUsage)
+	provisionCmd.Flags().Bool(RequireNewGroupName, false, requireNewGroupUsage)
+	provisionCmd.Flags().Bool(RequireExistingGroupName, false, requireExistingGroupUsage)
 	provisionCmd.Flags().StringP(SiteName, SiteShorthand, provisionConfig.GetString(SiteName), siteUsage)
 	provisionCmd.Flags().StringP(LocationName, LocationShorthand, provisionConfig.GetString(LocationName), locationUsage)
 	provisionCmd.Flags().BoolP(SkipTemplateCacheName, SkipTemplateCacheShorthand, false, skipTemplateCacheUsage)
 	provisionCmd.Flags().BoolP(SkipParameterCacheName, SkipParameterCacheShorthand, false, skipParameterCacheUsage)
 	provisionCmd.Flags().BoolP(SkipDeploymentName, SkipDeploymentShorthand, false, skipDeploymentUsage)
 	provisionCmd.Flags().StringP(DatabasePasswordName, DatabasePasswordShorthand, dbPassText, databasePasswordUsage)
+	provisionCmd.Flags().Int(DatabasePasswordLengthName, DatabasePasswordLengthDefault, databasePasswordLengthUsage)
+	provisionCmd.Flags().String(DatabaseVersionName, "", databaseVersionUsage)
+	provisionCmd.Flags().String(DatabaseSKUName, "", databaseSKUUsage)
+	provisionCmd.Flags().Int(DatabaseStorageName, 0, databaseStorageUsage)
+	provisionCmd.Flags().StringArray(DatabaseAllowIPName, nil, databaseAllowIPUsage)
+	provisionCmd.Flags().Bool(DatabaseAllowAzureServicesName, databaseAllowAzureServicesDefault, databaseAllowAzureServicesUsage)
 	provisionCmd.Flags().String(DatabaseAdminName, provisionConfig.GetString(DatabaseAdminName), databaseAdminUsage)
-	provisionCmd.Flags().StringP(TemplateParametersName, TemplateParametersShorthand, provisionConfig.GetString(TemplateParametersName), templateParametersUsage)
+	provisionCmd.Flags().String(TemplateSHA256Name, "", templateSHA256Usage)
+	provisionCmd.Flags().Bool(TemplateAuthName, false, templateAuthUsage)
+	provisionCmd.Flags().Int(TemplateDownloadRetriesName, TemplateDownloadRetriesDefault, templateDownloadRetriesUsage)
+	provisionCmd.Flags().Duration(TemplateDownloadTimeoutName, 0, templateDownloadTimeoutUsage)
+	provisionCmd.Flags().StringSliceP(TemplateParametersName, TemplateParametersShorthand, provisionConfig.GetStringSlice(TemplateParametersName), templateParametersUsage)
 	provisionCmd.Flags().String(DockerRegistryAccessName, provisionConfig.GetString(DockerRegistryAccessName), dockerRegistryAccessUsage)
 	provisionCmd.Flags().String(DockerRegistryURLName, provisionConfig.GetString(DockerRegistryURLName), dockerRegistryURLUsage)
 	provisionCmd.Flags().String(DockerRegistryUsernameName, provisionConfig.GetString(DockerRegistryUsernameName), dockerRegistryUsernameUsage)
 	provisionCmd.Flags().String(DockerRegistryPasswordName, dockerPassText, dockerRegistryPasswordUsage)
+	provisionCmd.Flags().String(ProxyName, "", proxyUsage)
+	provisionCmd.Flags().String(CacheDirName, CacheDirDefault, cacheDirUsage)
+	provisionCmd.Flags().Bool(NoEnvWriteName, false, noEnvWriteUsage)
+	provisionCmd.Flags().SetNormalizeFunc(normalizeProvisionFlag)
+	provisionCmd.Flags().Bool(EnvDryRunName, false, envDryRunUsage)
+	provisionCmd.Flags().Bool(SkipEnvReadName, false, skipEnvReadUsage)
+	provisionCmd.Flags().Bool(CacheBestEffortName, false, cacheBestEffortUsage)
+	provisionCmd.Flags().Bool(ShowTemplateName, false, showTemplateUsage)
+	provisionCmd.Flags().String(DeploymentNameName, "", deploymentNameUsage)
+	provisionCmd.Flags().Bool(RegisterProvidersName, false, registerProvidersUsage)
+	provisionCmd.Flags().String(KeyVaultName, "", keyVaultUsage)
+	provisionCmd.Flags().Bool(CreateKeyVaultName, false, createKeyVaultUsage)
+	provisionCmd.Flags().Bool(PinImageName, false, pinImageUsage)
+	provisionCmd.Flags().String(SourceName, "", sourceUsage)
+	provisionCmd.Flags().String(PlanSKUName, "", planSKUUsage)
+	provisionCmd.Flags().Int(PlanCapacityName, 0, planCapacityUsage)
+	provisionCmd.Flags().String(PlanIDName, "", planIDUsage)
+	provisionCmd.Flags().String(HostOSName, HostOSDefault, hostOSUsage)
+	provisionCmd.Flags().Bool(SystemIdentityName, false, systemIdentityUsage)
+	provisionCmd.Flags().String(UserIdentityName, "", userIdentityUsage)
+	provisionCmd.Flags().String(CustomDomainName, "", customDomainUsage)
+	provisionCmd.Flags().StringArray(AppSettingName, nil, appSettingUsage)
+	provisionCmd.Flags().String(StartupCommandName, "", startupCommandUsage)
+	provisionCmd.Flags().Bool(ManagedCertificateName, false, managedCertificateUsage)
+	provisionCmd.Flags().Bool(WaitHealthyName, false, waitHealthyUsage)
+	provisionCmd.Flags().String(HealthPathName, HealthPathDefault, healthPathUsage)
+	provisionCmd.Flags().Duration(HealthTimeoutName, HealthTimeoutDefault, healthTimeoutUsage)
 
 	provisionConfig.BindPFlags(provisionCmd.Flags())
 
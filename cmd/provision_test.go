@@ -6,22 +6,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/spf13/pflag"
 )
 
 func init() {
-	var err error
-	environment, err = azure.EnvironmentFromName(provisionConfig.GetString(EnvironmentName))
-	if err != nil {
-		environment = azure.PublicCloud
-	}
-
 	log.Out = ioutil.Discard
 }
 
@@ -41,6 +42,11 @@ func Test_getAuthorizer(t *testing.T) {
 		return
 	}
 
+	environment, err := azure.EnvironmentFromName(provisionConfig.GetString(EnvironmentName))
+	if err != nil {
+		environment = azure.PublicCloud
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 
@@ -51,7 +57,7 @@ func Test_getAuthorizer(t *testing.T) {
 			return
 		}
 
-		if auth, err := getAuthorizer(ctx, subscriptionID, clientID, clientSecret, tenantID); err != nil {
+		if auth, err := newSession(environment, nil).getAuthorizer(ctx, subscriptionID, clientID, clientSecret, tenantID, false); err != nil {
 			t.Error(err)
 		} else if auth == nil {
 			t.Log("auth unexpected nil in non error case")
@@ -67,7 +73,7 @@ func Test_getAuthorizer(t *testing.T) {
 			return
 		}
 
-		if _, err := getAuthorizer(ctx, subscriptionID, clientID, clientSecret, ""); err == nil {
+		if _, err := newSession(environment, nil).getAuthorizer(ctx, subscriptionID, clientID, clientSecret, "", false); err == nil {
 			// Is this failing because you've found a work around and implemented Service Principal tenant inference?
 			// Awesome, change this test.
 			// Otherwise, something is wrong that could cause us to mislead customers into thinking they can do tenant
@@ -80,6 +86,138 @@ func Test_getAuthorizer(t *testing.T) {
 	wg.Wait()
 }
 
+func Test_newDeviceAuthorizer_refresh(t *testing.T) {
+	const tenantID = "contoso.onmicrosoft.com"
+
+	refreshed := 0
+	environment := azure.PublicCloud
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+tenantID+"/oauth2/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != "refresh-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		refreshed++
+
+		expiresOn := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token":  "fresh-token",
+			"refresh_token": "refresh-token",
+			"expires_in":    "3600",
+			"expires_on":    expiresOn,
+			"not_before":    expiresOn,
+			"resource":      environment.ResourceManagerEndpoint,
+			"token_type":    "Bearer",
+		})
+	}))
+	defer server.Close()
+
+	environment.ActiveDirectoryEndpoint = server.URL + "/"
+
+	// A token that expired a minute ago, as would be the case late in a long deployment.
+	auth, err := newSession(environment, server.Client()).newDeviceAuthorizer(tenantID, adal.Token{
+		AccessToken:  "stale-token",
+		RefreshToken: "refresh-token",
+		ExpiresOn:    strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10),
+		Type:         "Bearer",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := autorest.Prepare(&http.Request{}, autorest.WithBaseURL(environment.ResourceManagerEndpoint), auth.WithAuthorization())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got, want := req.Header.Get("Authorization"), "Bearer fresh-token"; got != want {
+		t.Logf("got: %q want: %q", got, want)
+		t.Fail()
+	}
+
+	if refreshed != 1 {
+		t.Logf("got %d refreshes, want 1", refreshed)
+		t.Fail()
+	}
+}
+
+func Test_loadParameters(t *testing.T) {
+	loaded, err := loadParameters(strings.NewReader(`{
+	"$schema": "https://schema.management.azure.com/schemas/2015-01-01/deploymentParameters.json#",
+	"contentVersion": "1.0.0.0",
+	"parameters": {
+		"name": {"value": "contoso-app"}
+	}
+}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := loaded.Parameters["name"].Value; got != "contoso-app" {
+		t.Logf("got: %v want: %q", got, "contoso-app")
+		t.Fail()
+	}
+
+	if _, err = loadParameters(strings.NewReader("not json")); err == nil {
+		t.Log("expected an error for malformed parameters")
+		t.Fail()
+	}
+}
+
+func Test_cache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure-cache")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	location := filepath.Join(dir, "nested", TemplateDefault)
+	for _, contents := range []string{"first", "second"} {
+		if err = cache(context.Background(), map[string]string{"contents": contents}, location); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = cache(cancelled, map[string]string{"contents": "third"}, location); err == nil {
+		t.Log("expected an error caching after being cancelled")
+		t.Fail()
+	}
+
+	var saved map[string]string
+	raw, err := ioutil.ReadFile(location)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = json.Unmarshal(raw, &saved); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := saved["contents"]; got != "second" {
+		t.Logf("got: %q want: %q", got, "second")
+		t.Fail()
+	}
+
+	if entries, err := ioutil.ReadDir(filepath.Dir(location)); err != nil {
+		t.Error(err)
+	} else if len(entries) != 1 {
+		t.Logf("got %d files in the cache directory, want 1", len(entries))
+		t.Fail()
+	}
+}
+
 func Test_getDeploymentTemplate_links(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -91,7 +229,7 @@ func Test_getDeploymentTemplate_links(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run("", func(t *testing.T) {
-			result, err := getDeploymentTemplate(ctx, tc)
+			result, err := newSession(azure.PublicCloud, nil).getDeploymentTemplate(ctx, tc, "", nil)
 			if err != nil {
 				t.Error(err)
 			}
@@ -126,7 +264,7 @@ func Test_getDeploymentTemplate_localFiles(t *testing.T) {
 				return
 			}
 
-			result, err := getDeploymentTemplate(ctx, tc)
+			result, err := newSession(azure.PublicCloud, nil).getDeploymentTemplate(ctx, tc, "", nil)
 			if err != nil {
 				t.Error(err)
 				return
@@ -196,3 +334,162 @@ func Test_getDeploymentTemplate_localFiles(t *testing.T) {
 		})
 	}
 }
+
+func Test_getDeploymentTemplate_digest(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const location = "./testdata/template1.json"
+
+	testCases := []struct {
+		digest    string
+		expectErr bool
+	}{
+		{"", false},
+		{"0fd4a7380b0ec8994f3a115e430af41c807d5060d86bdcf04cb17fc051be98b3", false},
+		{"0FD4A7380B0EC8994F3A115E430AF41C807D5060D86BDCF04CB17FC051BE98B3", false},
+		{"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.digest, func(t *testing.T) {
+			_, err := newSession(azure.PublicCloud, nil).getDeploymentTemplate(ctx, location, tc.digest, nil)
+			if tc.expectErr && err == nil {
+				t.Log("expected a digest mismatch")
+				t.Fail()
+			} else if !tc.expectErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func Test_downloadTemplate_retries(t *testing.T) {
+	const contents = `{"resources": []}`
+
+	originalBackoff := templateDownloadBackoff
+	defer func() {
+		templateDownloadBackoff = originalBackoff
+	}()
+	templateDownloadBackoff = time.Millisecond
+
+	testCases := []struct {
+		retries  int
+		failures int
+		wantErr  bool
+	}{
+		{retries: 3, failures: 0},
+		{retries: 3, failures: 2},
+		{retries: 2, failures: 2, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%d retries %d failures", tc.retries, tc.failures), func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tc.failures {
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				fmt.Fprint(w, contents)
+			}))
+			defer server.Close()
+
+			s := newSession(azure.PublicCloud, server.Client())
+			s.templateRetries = tc.retries
+
+			buf := &bytes.Buffer{}
+			err := s.downloadTemplate(context.Background(), buf, server.URL, nil)
+			if tc.wantErr {
+				if err == nil {
+					t.Log("expected an error")
+					t.Fail()
+				}
+				if attempts != tc.retries {
+					t.Logf("got %d attempts, want %d", attempts, tc.retries)
+					t.Fail()
+				}
+				return
+			} else if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if got := buf.String(); got != contents {
+				t.Logf("got: %q want: %q", got, contents)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_downloadTemplate_timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	s := newSession(azure.PublicCloud, server.Client())
+	s.templateTimeout = 10 * time.Millisecond
+
+	if err := s.downloadTemplate(context.Background(), ioutil.Discard, server.URL, nil); err == nil {
+		t.Log("expected the download to time out")
+		t.Fail()
+	}
+}
+
+func Test_newDeploymentName(t *testing.T) {
+	started := time.Date(2018, time.June, 4, 13, 5, 9, 0, time.UTC)
+
+	got := newDeploymentName("", started)
+	if want := "buffalo-app-20180604-130509"; got != want {
+		t.Logf("got: %q want: %q", got, want)
+		t.Fail()
+	}
+
+	if !deploymentNamePattern.MatchString(got) {
+		t.Logf("generated name %q is not a valid deployment name", got)
+		t.Fail()
+	}
+
+	if got := newDeploymentName("contoso-api", started); got != "contoso-api-20180604-130509" {
+		t.Logf("got: %q want: %q", got, "contoso-api-20180604-130509")
+		t.Fail()
+	}
+
+	if got := newDeploymentName(strings.Repeat("a", 60), started); !deploymentNamePattern.MatchString(got) {
+		t.Logf("generated name %q for a long site name is not a valid deployment name", got)
+		t.Fail()
+	}
+
+	if deploymentNamePattern.MatchString("has spaces") || deploymentNamePattern.MatchString(strings.Repeat("a", 65)) {
+		t.Log("invalid deployment names were accepted")
+		t.Fail()
+	}
+}
+
+func Test_normalizeProvisionFlag(t *testing.T) {
+	if flag := provisionCmd.Flags().Lookup(NoEnvName); flag == nil || flag.Name != NoEnvWriteName {
+		t.Logf("got: %v want --%s to find --%s", flag, NoEnvName, NoEnvWriteName)
+		t.Fail()
+	}
+
+	for _, arg := range []string{"--" + NoEnvName, "--" + NoEnvWriteName} {
+		flags := pflag.NewFlagSet("provision", pflag.ContinueOnError)
+		flags.Bool(NoEnvWriteName, false, noEnvWriteUsage)
+		flags.SetNormalizeFunc(normalizeProvisionFlag)
+
+		if err := flags.Parse([]string{arg}); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if got, err := flags.GetBool(NoEnvWriteName); err != nil || !got {
+			t.Logf("%s got: %v (%v) want: true", arg, got, err)
+			t.Fail()
+		}
+	}
+}
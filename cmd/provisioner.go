@@ -0,0 +1,741 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2018-02-01/web"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// ProvisionOptions describes the infrastructure that should be created to host a Buffalo application, and how
+// `Provision` should go about creating it.
+type ProvisionOptions struct {
+	// SubscriptionID is the ID of the Azure subscription which should host the provisioned resources. It may instead be
+	// the subscription's display name, as long as no other subscription the identity can access shares it.
+	SubscriptionID string
+
+	// TenantID is the ID of the organization that the identity being used belongs to. When using Device Auth, it may
+	// be left empty and will be inferred.
+	TenantID string
+
+	// ClientID and ClientSecret identify the Service Principal used to authenticate. They are ignored when
+	// UseDeviceAuth is true.
+	ClientID     string
+	ClientSecret string
+
+	// UseDeviceAuth indicates that the user should be prompted to authenticate interactively.
+	UseDeviceAuth bool
+
+	// UseDefaultCredential authenticates with the first identity found in the environment, as a managed identity, or
+	// in the Azure CLI. TenantID, ClientID, and ClientSecret take precedence over the environment when they're set.
+	UseDefaultCredential bool
+
+	// DeviceClientID is the Application ID of the App Registration used during Device Auth. When left empty, the
+	// client ID of the Azure CLI 2.0 is used.
+	DeviceClientID string
+
+	// Environment is the Azure cloud that will be targeted. When left empty, the Azure Public Cloud is used.
+	Environment azure.Environment
+
+	// HTTPClient sends every request made while provisioning. When it is nil, a client which honors the environment
+	// variables HTTPS_PROXY, HTTP_PROXY, and NO_PROXY is used.
+	HTTPClient *http.Client
+
+	// ResourceGroup is the name of the Resource Group that should hold the resources created. It is created in
+	// Location if it does not already exist. Should it exist, its own location is used instead of Location, and the
+	// group itself is left untouched.
+	ResourceGroup string
+	Location      string
+
+	// RequireNewGroup causes Provision to fail if ResourceGroup already exists, and RequireExistingGroup causes it to
+	// fail if ResourceGroup does not.
+	RequireNewGroup      bool
+	RequireExistingGroup bool
+
+	// SiteName is the name of the Web App that will be created.
+	SiteName string
+
+	// Image is the container image that will be run by the Web App.
+	Image string
+
+	// PinImage causes Image to be deployed by the digest its tag currently refers to, rather than by its tag.
+	PinImage bool
+
+	// Source, when not empty, is deployed instead of Image. It is either the directory of a Buffalo application, which
+	// is packaged and uploaded to the site once it has been created, or a link to a zip archive of an application that
+	// has already been built. Only Linux sites may be deployed from source.
+	Source string
+
+	// PlanSKU and PlanCapacity choose the pricing tier and number of instances of the App Service plan. When they
+	// are empty or zero, the template's defaults are used.
+	PlanSKU      string
+	PlanCapacity int
+
+	// PlanID is the resource ID of an existing App Service plan that the site should be deployed into. When it is
+	// empty, a plan is created for the site. PlanSKU and PlanCapacity are ignored when it is provided.
+	PlanID string
+
+	// HostOS is the operating system the site's container runs on, either HostOSLinux or HostOSWindows. When it is
+	// empty, HostOSLinux is used.
+	HostOS string
+
+	// SystemIdentity gives the site a system-assigned managed identity. UserIdentityID, when not empty, is the resource
+	// ID of an existing user-assigned managed identity which should be attached to the site. When neither is provided,
+	// the site has no identity.
+	SystemIdentity bool
+	UserIdentityID string
+
+	// DatabaseType is the flavor of database that will be provisioned, or "none".
+	DatabaseType  string
+	DatabaseName  string
+	DatabaseAdmin string
+
+	// DatabasePassword is the administrator password of the database. When it is empty, and a database is being
+	// provisioned, a password of DatabasePasswordLength characters is generated.
+	DatabasePassword       string
+	DatabasePasswordLength int
+
+	// DatabaseVersion, DatabaseSKU, and DatabaseStorageGB choose the version, pricing tier, and storage of the database
+	// server. When they are empty or zero, the template's defaults are used.
+	DatabaseVersion   string
+	DatabaseSKU       string
+	DatabaseStorageGB int
+
+	// DatabaseAllowedIPs are IPv4 addresses, or ranges of them like "203.0.113.0-203.0.113.255", which are allowed
+	// through the database server's firewall. Services running in Azure, including the site, are allowed through
+	// unless DatabaseBlockAzure is set.
+	DatabaseAllowedIPs []string
+	DatabaseBlockAzure bool
+
+	// EnvFile is the location of a ".env" file that passwords should be saved in, if they are not already present.
+	// When it is empty, passwords are not saved.
+	EnvFile string
+
+	// EnvDryRun, when not nil, receives a description of the changes that would be made to EnvFile, with passwords
+	// redacted. EnvFile itself is left untouched.
+	EnvDryRun io.Writer
+
+	// KeyVault is the name of an Azure Key Vault in ResourceGroup that the deployment should save passwords in. When it
+	// is not empty, passwords aren't saved in EnvFile, and the site refers to the saved DATABASE_URL through a Key Vault
+	// reference. The site is given a system-assigned identity, which is allowed to read secrets from the Key Vault.
+	// CreateKeyVault has the template create the Key Vault, otherwise it must already exist.
+	KeyVault       string
+	CreateKeyVault bool
+
+	DockerRegistryAccess   string
+	DockerRegistryURL      string
+	DockerRegistryUsername string
+	DockerRegistryPassword string
+
+	// TemplateLocation is a link or path to the ARM template that will be deployed. When it is empty, the template
+	// distributed with this program is deployed, without downloading anything.
+	TemplateLocation string
+
+	// TemplateDigest is the hex-encoded SHA-256 digest that the template must match. When it is empty, the template
+	// isn't verified.
+	TemplateDigest string
+
+	// TemplateAuth forces the identity being used to be presented while downloading the template.
+	TemplateAuth bool
+
+	// TemplateRetries is the number of times downloading the template is attempted. When it is zero,
+	// TemplateDownloadRetriesDefault is used. TemplateTimeout, when not zero, limits how long all of those attempts may
+	// take together.
+	TemplateRetries int
+	TemplateTimeout time.Duration
+
+	// Parameters are used as the basis of the deployment parameters. Values derived from the other options take
+	// precedence over any that are present here.
+	Parameters *DeploymentParameters
+
+	// CacheDir is the directory that the template and parameters used for deployment are saved in.
+	CacheDir           string
+	SkipTemplateCache  bool
+	SkipParameterCache bool
+
+	// CustomDomain is a hostname that should be bound to the site. Its DNS records are verified before anything is
+	// deployed. When ManagedCertificate is true, it is secured with a certificate managed by App Service.
+	CustomDomain       string
+	ManagedCertificate bool
+
+	// WaitHealthy causes Provision to wait, for at most HealthTimeout, until a request for HealthPath on the newly
+	// deployed site receives an HTTP 200. When HealthTimeout is zero, HealthTimeoutDefault is used.
+	WaitHealthy   bool
+	HealthPath    string
+	HealthTimeout time.Duration
+
+	// AppSettings are added to the configuration of the site, along with those the template defines. StartupCommand,
+	// when not empty, replaces the command the container is started with.
+	AppSettings    map[string]string
+	StartupCommand string
+
+	// ShowTemplate, when not nil, receives the template and parameters that would be deployed, with passwords
+	// redacted. Nothing is saved, cached, or deployed.
+	ShowTemplate io.Writer
+
+	// DeploymentName is the name of the deployment created in Azure Resource Manager. When it is empty, a name
+	// unique to this call is generated.
+	DeploymentName string
+
+	// RegisterProviders causes any resource providers the deployment relies on, which are not yet registered in the
+	// subscription, to be registered. Otherwise, Provision stops before deploying if any are missing.
+	RegisterProviders bool
+
+	// Confirm, when not nil, is given a summary of what is about to be deployed before anything is created in Azure.
+	// Should it return false, Provision stops without creating anything. When it is nil, the summary is logged instead.
+	Confirm func(summary string) (bool, error)
+
+	// SkipDeployment prevents anything from being created in Azure.
+	SkipDeployment bool
+
+	// Redact, when not nil, is given each secret that is provided or generated, like passwords and connection strings,
+	// so that they can be kept out of logs.
+	Redact func(secrets ...string)
+}
+
+// ProvisionResult describes the infrastructure created by `Provision`.
+type ProvisionResult struct {
+	ResourceGroup        string
+	ResourceGroupCreated bool
+	DeploymentName       string
+	SiteName             string
+	SiteURL              string
+	PortalLink           string
+
+	// Location is the Azure Region the resources were deployed to. When the resource group already existed, it is the
+	// group's location, rather than the location requested.
+	Location string
+
+	// DatabasePassword is the administrator password of the database, which may have been generated.
+	DatabasePassword string
+
+	// DatabaseURL is the connection string the application should use to connect to the database. It is only known
+	// when the template distributed with this program is deployed, which also configures it as the DATABASE_URL App
+	// Setting of the site. Otherwise, it is empty.
+	DatabaseURL string
+
+	// PrincipalID is the ID of the site's system-assigned managed identity, if it was given one.
+	PrincipalID string
+
+	// CacheErr is the first error encountered while caching the template or parameters. Failing to cache them doesn't
+	// cause Provision to fail.
+	CacheErr error
+}
+
+// Provision creates the infrastructure necessary to run a Buffalo application on Azure, as described by opts. Each call
+// keeps its own settings and credentials, so calls with different options may run concurrently.
+func Provision(ctx context.Context, opts ProvisionOptions) (result ProvisionResult, err error) {
+	s := newSession(opts.Environment, opts.HTTPClient)
+	if opts.DeviceClientID != "" {
+		s.deviceClientID = opts.DeviceClientID
+	}
+	if opts.TemplateRetries > 0 {
+		s.templateRetries = opts.TemplateRetries
+	}
+	s.templateTimeout = opts.TemplateTimeout
+
+	redact := opts.Redact
+	if redact == nil {
+		redact = func(...string) {}
+	}
+	redact(opts.ClientSecret, opts.DatabasePassword, opts.DockerRegistryPassword)
+
+	result.SiteName = opts.SiteName
+	result.DeploymentName = opts.DeploymentName
+	if result.DeploymentName == "" {
+		result.DeploymentName = newDeploymentName(opts.SiteName, time.Now())
+	}
+	result.ResourceGroup = opts.ResourceGroup
+	result.Location = opts.Location
+
+	showOnly := opts.ShowTemplate != nil
+
+	hostOS := strings.ToLower(opts.HostOS)
+	if hostOS == "" {
+		hostOS = HostOSLinux
+	}
+	if opts.Source != "" && hostOS == HostOSWindows {
+		err = errors.New("only Linux sites can be deployed from source")
+		return
+	}
+
+	var auth autorest.Authorizer
+	if !opts.SkipDeployment && !showOnly {
+		if opts.UseDefaultCredential {
+			auth, err = s.getDefaultCredential(ctx, opts.TenantID, opts.ClientID, opts.ClientSecret)
+		} else {
+			auth, err = s.getAuthorizer(ctx, opts.SubscriptionID, opts.ClientID, opts.ClientSecret, opts.TenantID, opts.UseDeviceAuth)
+		}
+		if err != nil {
+			err = &ExitError{Code: ExitAuthFailed, Err: fmt.Errorf("unable to authenticate: %v", err)}
+			return
+		}
+
+		opts.SubscriptionID, err = s.resolveSubscriptionID(ctx, auth, opts.SubscriptionID)
+		if err != nil {
+			return
+		}
+
+		if err = checkResourceGroupExistence(ctx, newResourceGroupsClient(s, opts.SubscriptionID, auth), opts.ResourceGroup, opts.RequireNewGroup, opts.RequireExistingGroup); err != nil {
+			return
+		}
+
+		result.Location, err = resourceGroupLocation(ctx, newResourceGroupsClient(s, opts.SubscriptionID, auth), opts.ResourceGroup, opts.Location)
+		if err != nil {
+			err = fmt.Errorf("unable to find location of resource group %s: %v", opts.ResourceGroup, err)
+			return
+		}
+		log.Info("location selected: ", result.Location)
+
+		if opts.PlanID != "" {
+			var plan web.AppServicePlan
+			plan, err = s.getAppServicePlan(ctx, auth, opts.PlanID)
+			if err != nil {
+				return
+			}
+			if err = checkAppServicePlan(plan, result.Location, hostOS); err != nil {
+				err = fmt.Errorf("unable to use app service plan %q: %v", opts.PlanID, err)
+				return
+			}
+			log.Info("found app service plan: ", opts.PlanID)
+		}
+
+		artifact := opts.Image
+		if opts.Source != "" {
+			artifact = opts.Source
+		}
+		summary := deploymentSummary(opts.SubscriptionID, opts.ResourceGroup, result.Location, opts.SiteName, opts.DatabaseType, artifact)
+		if opts.Confirm == nil {
+			log.Info("deployment summary:\n", summary)
+		} else {
+			var confirmed bool
+			if confirmed, err = opts.Confirm(summary); err != nil {
+				return
+			} else if !confirmed {
+				err = errNotConfirmed
+				return
+			}
+		}
+
+		if err = checkProviders(ctx, newProvidersClient(s, opts.SubscriptionID, auth), requiredProviders(opts.DatabaseType), opts.RegisterProviders); err != nil {
+			return
+		}
+	}
+
+	log.Debug(TenantIDName+" selected: ", opts.TenantID)
+	log.Debug(SubscriptionName+" selected: ", opts.SubscriptionID)
+	if opts.TemplateLocation == "" {
+		log.Debug(TemplateName + " selected: the template distributed with buffalo-azure")
+	} else {
+		log.Debug(TemplateName+" selected: ", opts.TemplateLocation)
+	}
+	log.Debug(DatabaseTypeName+" selected: ", opts.DatabaseType)
+	log.Debug(DatabaseNameName+" selected: ", opts.DatabaseName)
+	log.Debug(DatabaseAdminName+" selected: ", opts.DatabaseAdmin)
+
+	result.DatabasePassword = opts.DatabasePassword
+	usingDB := !strings.EqualFold(opts.DatabaseType, "none")
+	if usingDB && result.DatabasePassword == "" {
+		result.DatabasePassword, err = generatePassword(opts.DatabasePasswordLength)
+		if err != nil {
+			err = fmt.Errorf("unable to generate database password: %v", err)
+			return
+		}
+		redact(result.DatabasePassword)
+		log.Debug("generated database password")
+	} else if usingDB {
+		log.Debug("using provided password")
+	}
+
+	log.Debug(HostOSName+" selected: ", hostOS)
+
+	image := opts.Image
+	var sourcePackage []byte
+	if opts.Source != "" {
+		log.Debug(SourceName+" selected: ", opts.Source)
+
+		if !isSupportedLink(opts.Source) {
+			buf := &bytes.Buffer{}
+			if err = packageSource(opts.Source, buf); err != nil {
+				err = fmt.Errorf("unable to package %q: %v", opts.Source, err)
+				return
+			}
+			sourcePackage = buf.Bytes()
+			log.Infof("packaged %s (%d bytes)", opts.Source, len(sourcePackage))
+		}
+	} else {
+		log.Debug(ImageName+" selected: ", opts.Image)
+
+		if windowsImage := imageLooksWindows(opts.Image); windowsImage && hostOS == HostOSLinux {
+			log.Warnf("image %q appears to be a Windows image, but it will be run on Linux. Use --%s %s to run it on Windows", opts.Image, HostOSName, HostOSWindows)
+		} else if !windowsImage && hostOS == HostOSWindows {
+			log.Warnf("image %q does not appear to be a Windows image, but it will be run on Windows", opts.Image)
+		}
+
+		if opts.PinImage {
+			var username, password string
+			if strings.EqualFold(opts.DockerRegistryAccess, DockerAccessPrivate) {
+				username, password = opts.DockerRegistryUsername, opts.DockerRegistryPassword
+			}
+
+			image, err = s.resolveImageDigest(ctx, opts.Image, username, password)
+			if err != nil {
+				err = fmt.Errorf("unable to resolve digest of image %q: %v", opts.Image, err)
+				return
+			}
+			log.Info("pinned image: ", image)
+		}
+	}
+
+	// Provision the necessary assets.
+
+	params := NewDeploymentParameters()
+	if opts.Parameters != nil {
+		params.Schema = opts.Parameters.Schema
+		params.ContentVersion = opts.Parameters.ContentVersion
+		for k, v := range opts.Parameters.Parameters {
+			params.Parameters[k] = v
+		}
+	}
+
+	params.Parameters["name"] = DeploymentParameter{opts.SiteName}
+	params.Parameters["database"] = DeploymentParameter{strings.ToLower(opts.DatabaseType)}
+	params.Parameters["databaseName"] = DeploymentParameter{opts.DatabaseName}
+	params.Parameters["imageName"] = DeploymentParameter{image}
+	params.Parameters["databaseAdministratorLogin"] = DeploymentParameter{opts.DatabaseAdmin}
+	params.Parameters["databaseAdministratorLoginPassword"] = DeploymentParameter{result.DatabasePassword}
+	params.Parameters["dockerRegistryAccess"] = DeploymentParameter{opts.DockerRegistryAccess}
+	params.Parameters["dockerRegistryServerURL"] = DeploymentParameter{opts.DockerRegistryURL}
+	params.Parameters["dockerRegistryServerUsername"] = DeploymentParameter{opts.DockerRegistryUsername}
+	params.Parameters["dockerRegistryServerPassword"] = DeploymentParameter{opts.DockerRegistryPassword}
+
+	if usingDB {
+		if opts.DatabaseVersion != "" {
+			params.Parameters["databaseVersion"] = DeploymentParameter{opts.DatabaseVersion}
+		}
+		if opts.DatabaseSKU != "" {
+			params.Parameters["databaseSku"] = DeploymentParameter{opts.DatabaseSKU}
+		}
+		if opts.DatabaseStorageGB > 0 {
+			params.Parameters["databaseStorageGB"] = DeploymentParameter{opts.DatabaseStorageGB}
+		}
+		if len(opts.DatabaseAllowedIPs) > 0 {
+			var rules []firewallRule
+			if rules, err = parseFirewallRules(opts.DatabaseAllowedIPs); err != nil {
+				return
+			}
+			params.Parameters["databaseAllowedIPs"] = DeploymentParameter{rules}
+		}
+		if opts.DatabaseBlockAzure {
+			log.Warn("services running in Azure, including the site, won't be able to connect to the database unless their addresses are allowed")
+			params.Parameters["databaseAllowAzureServices"] = DeploymentParameter{false}
+		}
+	}
+	if opts.PlanID != "" {
+		params.Parameters["existingPlanId"] = DeploymentParameter{opts.PlanID}
+	}
+	if it := identityType(opts.SystemIdentity, opts.UserIdentityID); it != "" {
+		params.Parameters["identityType"] = DeploymentParameter{it}
+		params.Parameters["userAssignedIdentityId"] = DeploymentParameter{opts.UserIdentityID}
+	}
+	if opts.KeyVault != "" {
+		params.Parameters["keyVaultName"] = DeploymentParameter{opts.KeyVault}
+		if opts.CreateKeyVault {
+			params.Parameters["createKeyVault"] = DeploymentParameter{true}
+		}
+	}
+	if opts.Source != "" {
+		params.Parameters["runtimeStack"] = DeploymentParameter{sourceRuntimeStack}
+		if sourcePackage == nil {
+			params.Parameters["packageUri"] = DeploymentParameter{opts.Source}
+		}
+	}
+	if hostOS == HostOSWindows {
+		params.Parameters["hostOS"] = DeploymentParameter{hostOS}
+	}
+	if opts.PlanSKU != "" {
+		params.Parameters["planSku"] = DeploymentParameter{strings.ToUpper(opts.PlanSKU)}
+	} else if hostOS == HostOSWindows && opts.PlanID == "" {
+		params.Parameters["planSku"] = DeploymentParameter{windowsContainerSKUDefault}
+	}
+	if opts.PlanCapacity > 0 {
+		params.Parameters["planCapacity"] = DeploymentParameter{opts.PlanCapacity}
+	}
+
+	if len(opts.AppSettings) > 0 {
+		params.Parameters["appSettings"] = DeploymentParameter{appSettingsParameter(opts.AppSettings)}
+	}
+	if opts.StartupCommand != "" {
+		params.Parameters["startupCommand"] = DeploymentParameter{opts.StartupCommand}
+	}
+
+	if opts.CustomDomain != "" {
+		params.Parameters["customHostname"] = DeploymentParameter{opts.CustomDomain}
+		params.Parameters["managedCertificate"] = DeploymentParameter{opts.ManagedCertificate}
+	}
+
+	var prepare templatePreparer
+	if auth != nil {
+		prepare = s.getTemplatePreparer(opts.TemplateAuth)
+	}
+
+	var template *resources.DeploymentProperties
+	var templateETag string
+	if opts.TemplateLocation == "" {
+		if opts.TemplateDigest != "" {
+			err = verifyDigest([]byte(defaultTemplate), opts.TemplateDigest)
+		}
+		template = &resources.DeploymentProperties{
+			Template: json.RawMessage(defaultTemplate),
+		}
+	} else if opts.TemplateLocation == TemplateDefaultLink && !opts.SkipTemplateCache {
+		template, templateETag, err = s.getDefaultTemplate(ctx, opts.TemplateLocation, cacheLocation(opts.CacheDir, TemplateDefault), opts.TemplateDigest, prepare)
+	} else {
+		template, err = s.getDeploymentTemplate(ctx, opts.TemplateLocation, opts.TemplateDigest, prepare)
+	}
+	if _, mismatched := err.(digestMismatchError); mismatched {
+		err = fmt.Errorf("template doesn't match --%s: %v", TemplateSHA256Name, err)
+		return
+	} else if err != nil {
+		err = fmt.Errorf("unable to fetch template: %v", err)
+		return
+	}
+
+	if template, err = checkTemplateParameters(template, opts.TemplateLocation, params.Parameters); err != nil {
+		return
+	}
+
+	if opts.CustomDomain != "" {
+		if err = verifyCustomDomain(opts.CustomDomain, opts.SiteName+".azurewebsites.net"); err != nil {
+			return
+		}
+	}
+
+	secrets := map[string]string{
+		DatabasePasswordEnvVar:       result.DatabasePassword,
+		DockerRegistryPasswordEnvVar: opts.DockerRegistryPassword,
+	}
+
+	if usingDB && isBundledTemplate(template) {
+		result.DatabaseURL, err = databaseURL(opts.DatabaseType, opts.SiteName, opts.DatabaseAdmin, result.DatabasePassword, opts.DatabaseName)
+		if err != nil {
+			return
+		}
+		redact(result.DatabaseURL)
+		secrets[DatabaseURLEnvVar] = result.DatabaseURL
+	} else if usingDB {
+		log.Infof("the connection string of the database created by %s isn't known, so %s won't be saved", opts.TemplateLocation, DatabaseURLEnvVar)
+	}
+
+	if showOnly {
+		log.Debug("skipped saving passwords, only showing the template")
+	} else if opts.KeyVault != "" {
+		log.Debugf("skipped writing passwords to .env file, the deployment saves them to key vault %q", opts.KeyVault)
+	} else if opts.EnvFile == "" {
+		log.Debug("skipped writing passwords to .env file")
+	} else if opts.EnvDryRun != nil {
+		if err = previewEnv(opts.EnvDryRun, opts.EnvFile, secrets); err != nil {
+			err = fmt.Errorf("unable to preview changes to %q: %v", opts.EnvFile, err)
+			return
+		}
+	} else if written, err := appendEnv(opts.EnvFile, secrets); err == nil {
+		log.Debugf("wrote %v to %q", written, opts.EnvFile)
+	} else {
+		log.Errorf("unable to write passwords to %q: %v", opts.EnvFile, err)
+	}
+
+	template.Parameters = params.Parameters
+	template.Mode = resources.Incremental
+
+	if showOnly {
+		if id, ok := templateSpecLink(template); ok {
+			log.Info("template spec is deployed by reference, so its contents aren't shown: ", id)
+		}
+		err = showTemplate(opts.ShowTemplate, template.Template, params)
+		return
+	}
+
+	deploymentResults := make(chan error)
+	if opts.SkipDeployment {
+		close(deploymentResults)
+	} else {
+		go func(errOut chan<- error) {
+			defer close(errOut)
+			groups := newResourceGroupsClient(s, opts.SubscriptionID, auth)
+
+			// Assert the presence of the specified Resource Group
+			rgName := opts.ResourceGroup
+			var created bool
+			var err error
+			if !opts.RequireExistingGroup {
+				created, err = insertResourceGroup(ctx, groups, rgName, result.Location)
+			}
+			if err == nil && opts.RequireNewGroup && !created {
+				err = fmt.Errorf("resource group %s was created by someone else while provisioning", rgName)
+			}
+			if err != nil {
+				log.Errorf("unable to fetch or create resource group %s: %v\n", rgName, err)
+				errOut <- err
+				return
+			}
+			if created {
+				log.Info("created resource group: ", rgName)
+			} else {
+				log.Info("found resource group: ", rgName)
+			}
+			log.Debug("site name selected: ", opts.SiteName)
+
+			pLink := portalLink(opts.SubscriptionID, rgName)
+			result.ResourceGroupCreated = created
+			result.PortalLink = pLink
+
+			log.Info("beginning deployment: ", result.DeploymentName)
+			deployments := newDeploymentsClient(s, opts.SubscriptionID, auth)
+			if err := doDeployment(ctx, deployments, rgName, result.DeploymentName, template); err == nil {
+				if opts.SystemIdentity {
+					if deployment, err := deployments.Get(ctx, rgName, result.DeploymentName); err == nil && deployment.Properties != nil {
+						result.PrincipalID = stringOutput(deployment.Properties.Outputs, "principalId")
+					}
+					if result.PrincipalID != "" {
+						log.Info("site identity principal ID: ", result.PrincipalID)
+					} else {
+						log.Warn("unable to find the principal ID of the site's identity, check the template has a \"principalId\" output")
+					}
+				}
+
+				if opts.KeyVault != "" {
+					// The site is only allowed to read the Key Vault once its identity exists, which is after it first
+					// tried to resolve its Key Vault references.
+					if err := refreshKeyVaultReferences(ctx, newSiteConfigClient(s, opts.SubscriptionID, auth), rgName, opts.SiteName); err != nil {
+						log.Warnf("unable to restart site %s, it may be unable to read key vault %q until it is restarted: %v", opts.SiteName, opts.KeyVault, err)
+					}
+				}
+
+				if sourcePackage != nil {
+					log.Info("uploading source to be built by the site")
+					if err := s.zipDeploy(ctx, auth, opts.SiteName, sourcePackage); err != nil {
+						log.Errorf("unable to deploy source to site %s: %v", opts.SiteName, err)
+						errOut <- err
+						return
+					}
+					log.Info("source deployed")
+				}
+
+				result.SiteURL = fmt.Sprintf("https://%s.azurewebsites.net", opts.SiteName)
+				if opts.CustomDomain != "" && opts.ManagedCertificate {
+					result.SiteURL = "https://" + opts.CustomDomain
+				}
+				log.Infof("Check on your new Resource Group in the Azure Portal: %s\nYour site will be available shortly at: %s\n", pLink, result.SiteURL)
+
+				if opts.WaitHealthy {
+					healthLink := result.SiteURL + "/" + strings.TrimPrefix(opts.HealthPath, "/")
+					log.Info("waiting for site to become healthy: ", healthLink)
+					timeout := opts.HealthTimeout
+					if timeout <= 0 {
+						timeout = HealthTimeoutDefault
+					}
+					if err := s.waitHealthy(ctx, healthLink, timeout); err != nil {
+						log.Error(err)
+						errOut <- err
+						return
+					}
+					log.Info("site is healthy")
+				}
+			} else if ctx.Err() == context.Canceled {
+				log.Warnf("provisioning was interrupted, but the deployment may continue running in Azure.\nCheck on or delete the partially provisioned assets in the portal: %s\n", pLink)
+				errOut <- ctx.Err()
+				return
+			} else {
+				log.Warnf("unable to poll for completion progress, your assets may or may not have finished provisioning.\nCheck on their status in the portal: %s\nError: %v\n", pLink, err)
+				errOut <- err
+				return
+			}
+			log.Info("finished deployment")
+		}(deploymentResults)
+	}
+
+	doCache := func(ctx context.Context, errOut chan<- error, contents interface{}, location, flavor string) {
+		defer close(errOut)
+		log.Info("caching ", flavor)
+		err := cache(ctx, contents, location)
+		if err == nil && flavor == "template" && templateETag != "" {
+			err = cacheTemplateETag(ctx, location, templateETag)
+		}
+		if err != nil {
+			log.Warnf("unable to cache file %s because: %v", location, err)
+			errOut <- err
+			return
+		}
+		log.Debugf("%s cached", flavor)
+	}
+
+	// Each result is buffered, so that caching finishes even if it is no longer being waited upon.
+	templateSaveResults, parameterSaveResults := make(chan error, 1), make(chan error, 1)
+	if opts.SkipTemplateCache || template.Template == nil {
+		// A Template Spec is deployed by reference, so there's no template to cache.
+		close(templateSaveResults)
+	} else {
+		go doCache(ctx, templateSaveResults, template.Template, cacheLocation(opts.CacheDir, TemplateDefault), "template")
+	}
+
+	if opts.SkipParameterCache {
+		close(parameterSaveResults)
+	} else {
+		go doCache(ctx, parameterSaveResults, stripPasswords(params), parametersCacheLocation(opts.CacheDir, opts.SiteName), "parameters")
+	}
+
+	waitOnResults := func(ctx context.Context, results <-chan error) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-results:
+			return err
+		}
+	}
+
+	// Failing to cache the template or parameters is not fatal, those errors have already been reported.
+	for _, results := range []<-chan error{templateSaveResults, parameterSaveResults} {
+		if cacheErr := waitOnResults(ctx, results); cacheErr != nil && result.CacheErr == nil {
+			result.CacheErr = cacheErr
+		}
+	}
+
+	// The deployment observes cancellation itself, wait for it to report where the user can find any partially
+	// provisioned assets.
+	if err = waitOnResults(context.Background(), deploymentResults); err != nil {
+		err = &ExitError{Code: ExitDeploymentFailed, Err: err}
+	}
+	return
+}
+
+// showTemplate writes a template and the parameters it would be deployed with to out, as a single JSON document.
+// Passwords are redacted.
+func showTemplate(out io.Writer, template interface{}, params *DeploymentParameters) error {
+	shown := stripPasswords(params)
+	for _, key := range []string{"databaseAdministratorLoginPassword", "dockerRegistryServerPassword"} {
+		if original, ok := params.Parameters[key]; ok && original.Value != nil && original.Value != "" {
+			shown.Parameters[key] = DeploymentParameter{redactedMessage}
+		}
+	}
+
+	contents, err := json.MarshalIndent(struct {
+		Template   interface{}           `json:"template"`
+		Parameters *DeploymentParameters `json:"parameters"`
+	}{template, shown}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(contents))
+	return err
+}
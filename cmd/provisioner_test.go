@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_showTemplate(t *testing.T) {
+	params := NewDeploymentParameters()
+	params.Parameters["name"] = DeploymentParameter{"contoso"}
+	params.Parameters["databaseAdministratorLoginPassword"] = DeploymentParameter{"hunter2"}
+	params.Parameters["dockerRegistryServerPassword"] = DeploymentParameter{""}
+
+	output := &bytes.Buffer{}
+	if err := showTemplate(output, json.RawMessage(`{"resources": []}`), params); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if strings.Contains(output.String(), "hunter2") {
+		t.Log("password was not redacted")
+		t.Fail()
+	}
+
+	var shown struct {
+		Template   map[string]interface{} `json:"template"`
+		Parameters DeploymentParameters   `json:"parameters"`
+	}
+	if err := json.Unmarshal(output.Bytes(), &shown); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, ok := shown.Template["resources"]; !ok {
+		t.Log("template was not included")
+		t.Fail()
+	}
+
+	if got := shown.Parameters.Parameters["name"].Value; got != "contoso" {
+		t.Logf("got name: %v want: %q", got, "contoso")
+		t.Fail()
+	}
+
+	if got := shown.Parameters.Parameters["databaseAdministratorLoginPassword"].Value; got != redactedMessage {
+		t.Logf("got password: %v want: %q", got, redactedMessage)
+		t.Fail()
+	}
+
+	if _, ok := shown.Parameters.Parameters["dockerRegistryServerPassword"]; ok {
+		t.Log("empty password should not be shown")
+		t.Fail()
+	}
+}
+
+func TestProvision_sitesShareResourceGroup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_provisioner_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	templateLocation := filepath.Join(dir, "template.json")
+	if err := ioutil.WriteFile(templateLocation, []byte(defaultTemplate), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	sites := []string{"contoso-api", "contoso-web"}
+	deploymentNames := make(map[string]bool, len(sites))
+	for _, site := range sites {
+		result, err := Provision(context.Background(), ProvisionOptions{
+			SiteName:          site,
+			ResourceGroup:     "contoso",
+			Image:             ImageDefault,
+			DatabaseType:      "none",
+			TemplateLocation:  templateLocation,
+			CacheDir:          dir,
+			SkipTemplateCache: true,
+			SkipDeployment:    true,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if !strings.HasPrefix(result.DeploymentName, site+"-") {
+			t.Logf("deployment name %q does not start with the site name %q", result.DeploymentName, site)
+			t.Fail()
+		}
+		deploymentNames[result.DeploymentName] = true
+	}
+
+	if len(deploymentNames) != len(sites) {
+		t.Logf("got deployment names: %v, want one for each site", deploymentNames)
+		t.Fail()
+	}
+
+	want := map[string]string{
+		TemplateParametersDefault:         "contoso-api",
+		siteParametersFile("contoso-web"): "contoso-web",
+	}
+	for file, site := range want {
+		cached, err := loadFromParameterFile(cacheLocation(dir, file))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got := cached.Parameters["name"].Value; got != site {
+			t.Logf("%s got name: %v want: %q", file, got, site)
+			t.Fail()
+		}
+		if got := parametersCacheLocation(dir, site); got != cacheLocation(dir, file) {
+			t.Logf("parameters of %s got: %q want: %q", site, got, cacheLocation(dir, file))
+			t.Fail()
+		}
+	}
+}
+
+func TestProvision_envDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_provisioner_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	templateLocation := filepath.Join(dir, "template.json")
+	if err := ioutil.WriteFile(templateLocation, []byte(defaultTemplate), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	envFile := filepath.Join(dir, ".env")
+	out := &bytes.Buffer{}
+	result, err := Provision(context.Background(), ProvisionOptions{
+		SiteName:               "contoso",
+		ResourceGroup:          "contoso",
+		Image:                  ImageDefault,
+		DatabaseType:           "postgres",
+		DatabaseAdmin:          DatabaseAdminDefault,
+		DatabaseName:           "contoso_production",
+		DatabasePasswordLength: DatabasePasswordLengthDefault,
+		TemplateLocation:       templateLocation,
+		CacheDir:               dir,
+		SkipTemplateCache:      true,
+		SkipDeployment:         true,
+		EnvFile:                envFile,
+		EnvDryRun:              out,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := os.Stat(envFile); !os.IsNotExist(err) {
+		t.Logf("expected %q not to be written", envFile)
+		t.Fail()
+	}
+
+	for _, key := range []string{DatabasePasswordEnvVar, DatabaseURLEnvVar} {
+		if !strings.Contains(out.String(), key) {
+			t.Logf("expected %s to be listed, got: %q", key, out.String())
+			t.Fail()
+		}
+	}
+
+	if strings.Contains(out.String(), result.DatabasePassword) {
+		t.Logf("the database password was shown: %q", out.String())
+		t.Fail()
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestProvision_defaultTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_provisioner_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	published := templateWithout(t, "keyVaultName", "createKeyVault")
+	testCases := []struct {
+		name     string
+		location string
+		serve    roundTripperFunc
+		wantErr  bool
+	}{
+		{
+			name: "bundled",
+			serve: func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("the bundled template shouldn't be downloaded")
+			},
+		},
+		{
+			name:     "published",
+			location: TemplateDefaultLink,
+			serve: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(bytes.NewReader(published)),
+					Request:    req,
+				}, nil
+			},
+		},
+		{
+			name:     "unavailable",
+			location: TemplateDefaultLink,
+			serve: func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("no route to host")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Provision(context.Background(), ProvisionOptions{
+				HTTPClient:        &http.Client{Transport: tc.serve},
+				SiteName:          "contoso",
+				ResourceGroup:     "contoso",
+				Image:             ImageDefault,
+				DatabaseType:      "none",
+				TemplateLocation:  tc.location,
+				TemplateRetries:   1,
+				CacheDir:          dir,
+				SkipTemplateCache: true,
+				SkipDeployment:    true,
+			})
+			if tc.wantErr && err == nil {
+				t.Log("expected an error when the template can't be downloaded")
+				t.Fail()
+			} else if !tc.wantErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestProvision_databaseURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_provisioner_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name     string
+		template []byte
+		wantURL  bool
+	}{
+		{"bundled", []byte(defaultTemplate), true},
+		{"custom", templateWithout(t), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			templateLocation := filepath.Join(dir, tc.name+".json")
+			if err := ioutil.WriteFile(templateLocation, tc.template, 0644); err != nil {
+				t.Error(err)
+				return
+			}
+
+			result, err := Provision(context.Background(), ProvisionOptions{
+				SiteName:               "contoso",
+				ResourceGroup:          "contoso",
+				Image:                  ImageDefault,
+				DatabaseType:           "postgres",
+				DatabaseAdmin:          DatabaseAdminDefault,
+				DatabaseName:           "contoso_production",
+				DatabasePasswordLength: DatabasePasswordLengthDefault,
+				TemplateLocation:       templateLocation,
+				CacheDir:               dir,
+				SkipTemplateCache:      true,
+				SkipDeployment:         true,
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if got := result.DatabaseURL != ""; got != tc.wantURL {
+				t.Logf("got database URL: %q, want one: %v", result.DatabaseURL, tc.wantURL)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestProvision_keyVault(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_provisioner_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	templateLocation := filepath.Join(dir, "template.json")
+	if err := ioutil.WriteFile(templateLocation, []byte(defaultTemplate), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	envFile := filepath.Join(dir, ".env")
+	_, err = Provision(context.Background(), ProvisionOptions{
+		SiteName:               "contoso",
+		ResourceGroup:          "contoso",
+		Image:                  ImageDefault,
+		DatabaseType:           "postgres",
+		DatabaseAdmin:          DatabaseAdminDefault,
+		DatabaseName:           "contoso_production",
+		DatabasePasswordLength: DatabasePasswordLengthDefault,
+		TemplateLocation:       templateLocation,
+		CacheDir:               dir,
+		SkipTemplateCache:      true,
+		SkipDeployment:         true,
+		EnvFile:                envFile,
+		KeyVault:               "contoso-vault",
+		CreateKeyVault:         true,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := os.Stat(envFile); !os.IsNotExist(err) {
+		t.Logf("expected %q not to be written when saving passwords to a key vault", envFile)
+		t.Fail()
+	}
+
+	cached, err := loadFromParameterFile(cacheLocation(dir, TemplateParametersDefault))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got := cached.Parameters["keyVaultName"].Value; got != "contoso-vault" {
+		t.Logf("got keyVaultName: %v want: %q", got, "contoso-vault")
+		t.Fail()
+	}
+	if got := cached.Parameters["createKeyVault"].Value; got != true {
+		t.Logf("got createKeyVault: %v want: %v", got, true)
+		t.Fail()
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// newHTTPClient creates an `http.Client` which routes all requests through the
+// proxy selected by the function provided.
+func newHTTPClient(proxy func(*http.Request) (*url.URL, error)) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
+
+// getProxy selects the proxy that should be used for outbound requests. When a
+// proxy is specified explicitly it is used for all requests, otherwise the
+// environment variables HTTPS_PROXY, HTTP_PROXY, and NO_PROXY are consulted.
+func getProxy(raw string) (func(*http.Request) (*url.URL, error), error) {
+	if raw == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	// The URL may hold credentials, so it isn't repeated in the error.
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, errors.New("invalid proxy URL, it must include a scheme and a host")
+	}
+
+	return http.ProxyURL(parsed), nil
+}
+
+// redactProxy removes any credentials from a proxy URL so that it is safe to print. A URL that can't be parsed may
+// hide credentials anywhere, so it is replaced entirely.
+func redactProxy(raw string) string {
+	if raw == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return redactedMessage
+	}
+	if parsed.User != nil {
+		parsed.User = url.User(redactedMessage)
+	}
+	return parsed.String()
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redactor scrubs secrets from everything written by log, so that they don't end up in places like CI logs.
+var redactor = &secretRedactor{}
+
+func init() {
+	redactor.Formatter = log.Formatter
+	log.Formatter = redactor
+}
+
+// secretRedactor is a `logrus.Formatter` which replaces each registered secret in the output of another
+// `logrus.Formatter` with redactedMessage.
+type secretRedactor struct {
+	logrus.Formatter
+	mu      sync.RWMutex
+	secrets [][]byte
+}
+
+// Add registers secrets which should never be written. Empty strings are ignored.
+func (r *secretRedactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		r.secrets = append(r.secrets, []byte(secret))
+
+		// Formatters quote and escape values, which would allow a secret containing special characters to slip by.
+		if quoted := strconv.Quote(secret); quoted[1:len(quoted)-1] != secret {
+			r.secrets = append(r.secrets, []byte(quoted[1:len(quoted)-1]))
+		}
+		if encoded, err := json.Marshal(secret); err == nil && string(encoded[1:len(encoded)-1]) != secret {
+			r.secrets = append(r.secrets, encoded[1:len(encoded)-1])
+		}
+	}
+}
+
+// Format renders an entry using the wrapped `logrus.Formatter`, then removes any secrets that appear.
+func (r *secretRedactor) Format(entry *logrus.Entry) ([]byte, error) {
+	formatted, err := r.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, secret := range r.secrets {
+		formatted = bytes.Replace(formatted, secret, []byte(redactedMessage), -1)
+	}
+	return formatted, nil
+}
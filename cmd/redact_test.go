@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func Test_secretRedactor(t *testing.T) {
+	const secret = `hunter2-"quoted"`
+
+	subject := &secretRedactor{Formatter: &logrus.TextFormatter{DisableColors: true}}
+	subject.Add("", secret)
+
+	output := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = output
+	logger.Formatter = subject
+
+	logger.Info("connecting with password: ", secret)
+	logger.WithField("password", secret).Info("connecting")
+
+	if got := output.String(); strings.Contains(got, "hunter2") {
+		t.Logf("secret was written: %q", got)
+		t.Fail()
+	} else if count := strings.Count(got, redactedMessage); count != 2 {
+		t.Logf("got %d redactions want: 2\n%s", count, got)
+		t.Fail()
+	}
+}
+
+func Test_secretRedactor_json(t *testing.T) {
+	const secret = `hunter2<"&>`
+
+	subject := &secretRedactor{Formatter: &logrus.JSONFormatter{}}
+	subject.Add(secret)
+
+	output := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = output
+	logger.Formatter = subject
+
+	logger.WithFields(logrus.Fields{"password": secret, "status-code": 200}).Info("connecting")
+
+	if got := output.String(); strings.Contains(got, "hunter2") {
+		t.Logf("secret was written: %q", got)
+		t.Fail()
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Error(err)
+		return
+	}
+	if got := entry["status-code"]; got != float64(200) {
+		t.Logf("got status-code: %v want: 200", got)
+		t.Fail()
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"os"
 
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -45,6 +46,28 @@ const (
 	logOutputLevelUsage     = "The amount of output you'd like to see. Options include: " + logOutputLevelDebug + ", " + logOutputLevelInfo + ", " + logOutputLevelWarn + ", " + logOutputLevelError + ", " + logOutputLevelFatal + ", and " + logOutputLevelPanic
 )
 
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+
+	logFormatName    = "log-format"
+	logFormatDefault = logFormatText
+	logFormatUsage   = "The format of log output, either " + logFormatText + " or " + logFormatJSON + ". Use " + logFormatJSON + " when logs are collected by tools like ELK or Azure Monitor."
+)
+
+// setLogFormat chooses how entries written by log are rendered. Secrets are redacted in either format.
+func setLogFormat(format string) error {
+	switch format {
+	case logFormatText:
+		redactor.Formatter = &logrus.TextFormatter{}
+	case logFormatJSON:
+		redactor.Formatter = &logrus.JSONFormatter{}
+	default:
+		return fmt.Errorf("unrecognized "+logFormatName+": %s", format)
+	}
+	return nil
+}
+
 var cfgFile string
 
 var rootConfig = viper.New()
@@ -82,6 +105,7 @@ func init() {
 
 	rootCmd.PersistentFlags().StringP(logOutputLevelName, logOutputLevelShorthand, logOutputLevelDefault, logOutputLevelUsage)
 	rootCmd.PersistentFlags().BoolP(VerboseName, VerboseShortname, false, verboseUsage)
+	rootCmd.PersistentFlags().String(logFormatName, logFormatDefault, logFormatUsage)
 
 	rootConfig.BindPFlags(rootCmd.PersistentFlags())
 }
@@ -0,0 +1,277 @@
+// Copyright © 2018 Microsoft Corporation and contributors
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// rotateTimeoutDefault is the longest amount of time rotating the database password may take, unless --timeout is given.
+const rotateTimeoutDefault = 15 * time.Minute
+
+var rotateConfig = viper.New()
+
+// rotateDBPasswordCmd represents the rotate-db-password command
+var rotateDBPasswordCmd = &cobra.Command{
+	Use:   "rotate-db-password",
+	Short: "Replace the administrator password of the database created by `buffalo azure provision`.",
+	Long: `Generates a new administrator password for the database created by "buffalo azure provision", and sets it
+on the database server. The new password is saved to the ".env" file, or to a Key Vault. Then, the DATABASE_URL App
+Setting and Connection String of the site are updated to match, which causes App Service to restart the site.
+
+Between the password being changed and the site restarting, the site will be unable to connect to the database. Any
+other copies of the old password, for instance in a CI pipeline, must be updated separately.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(context.Background(), rotateConfig.GetDuration(TimeoutName))
+		defer cancel()
+
+		ctx, cancel = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		if err := rotateDatabasePassword(ctx); err != nil {
+			log.Error(err)
+			code := ExitFailure
+			if exitErr, ok := err.(*ExitError); ok {
+				code = exitErr.Code
+			}
+			os.Exit(code)
+		}
+	},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := setLogFormat(rootConfig.GetString(logFormatName)); err != nil {
+			return err
+		}
+
+		if err := loadEnv("./.env"); err != nil {
+			return err
+		}
+
+		if rotateConfig.GetString(SubscriptionName) == "" {
+			return fmt.Errorf("no value found for %q", SubscriptionName)
+		}
+
+		hasClientID := rotateConfig.GetString(ClientIDName) != ""
+		hasClientSecret := rotateConfig.GetString(ClientSecretName) != ""
+
+		if rotateConfig.GetBool(DefaultCredentialName) {
+			if rotateConfig.GetBool(DeviceAuthName) {
+				return fmt.Errorf("--%s and --%s can't be used together", DefaultCredentialName, DeviceAuthName)
+			}
+		} else if !hasClientSecret && !hasClientID {
+			rotateConfig.Set(DeviceAuthName, true)
+		} else if (hasClientID || hasClientSecret) && !(hasClientID && hasClientSecret) {
+			return errors.New("--client-id and --client-secret must be specified together or not at all")
+		}
+
+		if rotateConfig.GetString(SiteName) == "" {
+			return fmt.Errorf("no value found for %q", SiteName)
+		}
+
+		if rotateConfig.GetString(ResoureGroupName) == "" {
+			rotateConfig.Set(ResoureGroupName, rotateConfig.GetString(SiteName))
+		}
+
+		if dbType := strings.ToLower(rotateConfig.GetString(DatabaseTypeName)); dbType != "postgres" && dbType != "mysql" {
+			return fmt.Errorf("%s must be either \"postgres\" or \"mysql\"", DatabaseTypeName)
+		}
+
+		if pl := rotateConfig.GetInt(DatabasePasswordLengthName); pl < passwordMinLength || pl > passwordMaxLength {
+			return fmt.Errorf("%s must be between %d and %d", DatabasePasswordLengthName, passwordMinLength, passwordMaxLength)
+		}
+
+		if rotateConfig.GetDuration(TimeoutName) <= 0 {
+			return fmt.Errorf("%s must be positive", TimeoutName)
+		}
+
+		if _, err := azure.EnvironmentFromName(rotateConfig.GetString(EnvironmentName)); err != nil {
+			return err
+		}
+		return cobra.NoArgs(cmd, args)
+	},
+}
+
+// rotateDatabasePassword generates and sets a new database password, using the values provided to rotateDBPasswordCmd.
+func rotateDatabasePassword(ctx context.Context) error {
+	subscription := rotateConfig.GetString(SubscriptionName)
+	group := rotateConfig.GetString(ResoureGroupName)
+	site := rotateConfig.GetString(SiteName)
+	dbType := strings.ToLower(rotateConfig.GetString(DatabaseTypeName))
+
+	redactor.Add(rotateConfig.GetString(ClientSecretName))
+
+	environment, err := azure.EnvironmentFromName(rotateConfig.GetString(EnvironmentName))
+	if err != nil {
+		return err
+	}
+	s := newSession(environment, nil)
+	if clientID := rotateConfig.GetString(DeviceClientIDName); clientID != "" {
+		s.deviceClientID = clientID
+	}
+
+	var auth autorest.Authorizer
+	if rotateConfig.GetBool(DefaultCredentialName) {
+		auth, err = s.getDefaultCredential(ctx, rotateConfig.GetString(TenantIDName), rotateConfig.GetString(ClientIDName), rotateConfig.GetString(ClientSecretName))
+	} else {
+		auth, err = s.getAuthorizer(ctx, subscription, rotateConfig.GetString(ClientIDName), rotateConfig.GetString(ClientSecretName), rotateConfig.GetString(TenantIDName), rotateConfig.GetBool(DeviceAuthName))
+	}
+	if err != nil {
+		return &ExitError{Code: ExitAuthFailed, Err: fmt.Errorf("unable to authenticate: %v", err)}
+	}
+
+	if subscription, err = s.resolveSubscriptionID(ctx, auth, subscription); err != nil {
+		return err
+	}
+
+	password, err := generatePassword(rotateConfig.GetInt(DatabasePasswordLengthName))
+	if err != nil {
+		return fmt.Errorf("unable to generate database password: %v", err)
+	}
+	redactor.Add(password)
+
+	dbURL, err := databaseURL(dbType, site, rotateConfig.GetString(DatabaseAdminName), password, rotateConfig.GetString(DatabaseNameName))
+	if err != nil {
+		return err
+	}
+	redactor.Add(dbURL)
+
+	server := databaseServerName(dbType, site)
+	log.Info("setting administrator password of database server: ", server)
+	if err = newDatabaseServersClient(s, dbType, subscription, auth).SetAdministratorPassword(ctx, group, server, password); err != nil {
+		return fmt.Errorf("unable to set database password: %v", err)
+	}
+	log.Info("database password changed")
+
+	// The old password no longer works, so the new one is saved before anything else can fail.
+	secrets := map[string]string{
+		DatabasePasswordEnvVar: password,
+		DatabaseURLEnvVar:      dbURL,
+	}
+	if vault := rotateConfig.GetString(KeyVaultName); vault != "" {
+		if _, err = s.setKeyVaultSecrets(ctx, vault, secrets); err != nil {
+			return fmt.Errorf("unable to save passwords to key vault %q: %v", vault, err)
+		}
+		log.Info("saved new password to key vault: ", vault)
+	} else if err = replaceEnv("./.env", secrets); err != nil {
+		return fmt.Errorf("unable to save new password to .env: %v", err)
+	} else {
+		log.Info("saved new password to .env")
+	}
+
+	if err = setSiteDatabaseURL(ctx, newSiteConfigClient(s, subscription, auth), group, site, dbURL); err != nil {
+		return fmt.Errorf("unable to update %s of site %q, it will be unable to connect to the database until it is updated: %v", DatabaseURLEnvVar, site, err)
+	}
+	log.Info("updated site settings, App Service will restart the site to apply them")
+	return nil
+}
+
+// databaseServerName finds the name of the database server created for a site by the default template.
+func databaseServerName(dbType, site string) string {
+	if dbType == "mysql" {
+		return site + "-mysql"
+	}
+	return site + "-postgres"
+}
+
+// setSiteDatabaseURL replaces the value of the DATABASE_URL App Setting and Connection String of a site, where they are
+// present. Saving either restarts the site. A Key Vault reference is saved unchanged, so that the site reads the new
+// secret from the Key Vault as it restarts.
+func setSiteDatabaseURL(ctx context.Context, sites siteConfigClient, group, site, dbURL string) error {
+	appSettings, err := sites.ListApplicationSettings(ctx, group, site)
+	if err != nil {
+		return fmt.Errorf("unable to read app settings: %v", err)
+	}
+	if existing, ok := appSettings.Properties[DatabaseURLEnvVar]; ok {
+		if !isKeyVaultReference(existing) {
+			appSettings.Properties[DatabaseURLEnvVar] = to.StringPtr(dbURL)
+		}
+		if _, err = sites.UpdateApplicationSettings(ctx, group, site, appSettings); err != nil {
+			return fmt.Errorf("unable to save app settings: %v", err)
+		}
+		log.Debugf("updated %s in site app settings", DatabaseURLEnvVar)
+	} else {
+		log.Debugf("site has no %s in its app settings, leaving them alone", DatabaseURLEnvVar)
+	}
+
+	connectionStrings, err := sites.ListConnectionStrings(ctx, group, site)
+	if err != nil {
+		return fmt.Errorf("unable to read connection strings: %v", err)
+	}
+	if connection, ok := connectionStrings.Properties[DatabaseURLEnvVar]; ok && connection != nil {
+		if !isKeyVaultReference(connection.Value) {
+			connection.Value = to.StringPtr(dbURL)
+		}
+		if _, err = sites.UpdateConnectionStrings(ctx, group, site, connectionStrings); err != nil {
+			return fmt.Errorf("unable to save connection strings: %v", err)
+		}
+		log.Debugf("updated %s in site connection strings", DatabaseURLEnvVar)
+	} else {
+		log.Debugf("site has no %s in its connection strings, leaving them alone", DatabaseURLEnvVar)
+	}
+	return nil
+}
+
+func init() {
+	azureCmd.AddCommand(rotateDBPasswordCmd)
+
+	rotateConfig.BindEnv(SubscriptionName, "AZURE_SUBSCRIPTION_ID", "AZ_SUBSCRIPTION_ID")
+	rotateConfig.BindEnv(ClientIDName, "AZURE_CLIENT_ID", "AZ_CLIENT_ID")
+	rotateConfig.BindEnv(ClientSecretName, "AZURE_CLIENT_SECRET", "AZ_CLIENT_SECRET")
+	rotateConfig.BindEnv(TenantIDName, "AZURE_TENANT_ID", "AZ_TENANT_ID")
+	rotateConfig.BindEnv(DeviceClientIDName, "BUFFALO_AZURE_DEVICE_CLIENT_ID")
+	rotateConfig.BindEnv(EnvironmentName, "AZURE_ENVIRONMENT", "AZ_ENVIRONMENT")
+
+	rotateConfig.SetDefault(EnvironmentName, EnvironmentDefault)
+	rotateConfig.SetDefault(DatabaseAdminName, DatabaseAdminDefault)
+	rotateConfig.SetDefault(DatabaseTypeName, provisionConfig.GetString(DatabaseTypeName))
+	rotateConfig.SetDefault(DatabaseNameName, provisionConfig.GetString(DatabaseNameName))
+
+	rotateDBPasswordCmd.Flags().StringP(SubscriptionName, SubscriptionShorthand, rotateConfig.GetString(SubscriptionName), subscriptionUsage)
+	rotateDBPasswordCmd.Flags().String(ClientIDName, rotateConfig.GetString(ClientIDName), clientIDUsage)
+	rotateDBPasswordCmd.Flags().String(ClientSecretName, "", clientSecretUsage)
+	rotateDBPasswordCmd.Flags().Bool(DeviceAuthName, false, deviceAuthUsage)
+	rotateDBPasswordCmd.Flags().Bool(DefaultCredentialName, false, defaultCredentialUsage)
+	rotateDBPasswordCmd.Flags().String(DeviceClientIDName, rotateConfig.GetString(DeviceClientIDName), deviceClientIDUsage)
+	rotateDBPasswordCmd.Flags().String(TenantIDName, rotateConfig.GetString(TenantIDName), tenantUsage)
+	rotateDBPasswordCmd.Flags().StringP(EnvironmentName, EnvironmentShorthand, rotateConfig.GetString(EnvironmentName), environmentUsage)
+	rotateDBPasswordCmd.Flags().StringP(SiteName, SiteShorthand, "", siteUsage)
+	rotateDBPasswordCmd.Flags().StringP(ResoureGroupName, ResourceGroupShorthand, "", resourceGroupUsage)
+	rotateDBPasswordCmd.Flags().StringP(DatabaseTypeName, DatabaseShorthand, rotateConfig.GetString(DatabaseTypeName), databaseUsage)
+	rotateDBPasswordCmd.Flags().String(DatabaseNameName, rotateConfig.GetString(DatabaseNameName), databaseNameUsage)
+	rotateDBPasswordCmd.Flags().String(DatabaseAdminName, rotateConfig.GetString(DatabaseAdminName), databaseAdminUsage)
+	rotateDBPasswordCmd.Flags().Int(DatabasePasswordLengthName, DatabasePasswordLengthDefault, databasePasswordLengthUsage)
+	rotateDBPasswordCmd.Flags().String(KeyVaultName, "", keyVaultUsage)
+	rotateDBPasswordCmd.Flags().Duration(TimeoutName, rotateTimeoutDefault, timeoutUsage)
+
+	rotateConfig.BindPFlags(rotateDBPasswordCmd.Flags())
+}
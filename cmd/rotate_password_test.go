@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2018-02-01/web"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func Test_databaseServerName(t *testing.T) {
+	testCases := map[string]string{
+		"postgres": "contoso-postgres",
+		"mysql":    "contoso-mysql",
+	}
+
+	for dbType, want := range testCases {
+		if got := databaseServerName(dbType, "contoso"); got != want {
+			t.Logf("%s got: %q want: %q", dbType, got, want)
+			t.Fail()
+		}
+	}
+}
+
+func Test_newDatabaseServersClient(t *testing.T) {
+	testCases := map[string]string{
+		"postgres": "/subscriptions/sub/resourceGroups/contoso/providers/Microsoft.DBforPostgreSQL/servers/contoso-postgres",
+		"mysql":    "/subscriptions/sub/resourceGroups/contoso/providers/Microsoft.DBforMySQL/servers/contoso-mysql",
+	}
+
+	for dbType, serverID := range testCases {
+		t.Run(dbType, func(t *testing.T) {
+			var sent struct {
+				Properties struct {
+					AdministratorLoginPassword string `json:"administratorLoginPassword"`
+				} `json:"properties"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPatch || r.URL.Path != serverID {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				json.NewDecoder(r.Body).Decode(&sent)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id": "` + serverID + `", "properties": {"userVisibleState": "Ready"}}`))
+			}))
+			defer server.Close()
+
+			environment := azure.PublicCloud
+			environment.ResourceManagerEndpoint = server.URL + "/"
+
+			servers := newDatabaseServersClient(newSession(environment, nil), dbType, "sub", autorest.NullAuthorizer{})
+			if err := servers.SetAdministratorPassword(context.Background(), "contoso", databaseServerName(dbType, "contoso"), "n3w-Password"); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if got := sent.Properties.AdministratorLoginPassword; got != "n3w-Password" {
+				t.Logf("got password: %q want: %q", got, "n3w-Password")
+				t.Fail()
+			}
+
+			if err := servers.SetAdministratorPassword(context.Background(), "contoso", "missing", "n3w-Password"); err == nil {
+				t.Log("expected an error for a server that doesn't exist")
+				t.Fail()
+			}
+		})
+	}
+}
+
+type fakeSiteConfigClient struct {
+	appSettings       web.StringDictionary
+	connectionStrings web.ConnectionStringDictionary
+	saved             []string
+}
+
+func (f *fakeSiteConfigClient) ListApplicationSettings(ctx context.Context, resourceGroupName string, name string) (web.StringDictionary, error) {
+	return f.appSettings, nil
+}
+
+func (f *fakeSiteConfigClient) UpdateApplicationSettings(ctx context.Context, resourceGroupName string, name string, appSettings web.StringDictionary) (web.StringDictionary, error) {
+	f.saved = append(f.saved, "appsettings")
+	f.appSettings = appSettings
+	return appSettings, nil
+}
+
+func (f *fakeSiteConfigClient) ListConnectionStrings(ctx context.Context, resourceGroupName string, name string) (web.ConnectionStringDictionary, error) {
+	return f.connectionStrings, nil
+}
+
+func (f *fakeSiteConfigClient) UpdateConnectionStrings(ctx context.Context, resourceGroupName string, name string, connectionStrings web.ConnectionStringDictionary) (web.ConnectionStringDictionary, error) {
+	f.saved = append(f.saved, "connectionstrings")
+	f.connectionStrings = connectionStrings
+	return connectionStrings, nil
+}
+
+func Test_setSiteDatabaseURL(t *testing.T) {
+	const dbURL = "postgres://new"
+
+	sites := &fakeSiteConfigClient{
+		appSettings: web.StringDictionary{Properties: map[string]*string{
+			"GO_ENV":       to.StringPtr("production"),
+			"DATABASE_URL": to.StringPtr("postgres://old"),
+		}},
+		connectionStrings: web.ConnectionStringDictionary{Properties: map[string]*web.ConnStringValueTypePair{
+			"DATABASE_URL": {Value: to.StringPtr("postgres://old"), Type: web.Custom},
+		}},
+	}
+
+	if err := setSiteDatabaseURL(context.Background(), sites, "contoso", "contoso", dbURL); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := sites.appSettings.Properties; to.String(got["DATABASE_URL"]) != dbURL || to.String(got["GO_ENV"]) != "production" {
+		t.Logf("unexpected app settings: %v", got)
+		t.Fail()
+	}
+
+	if got := sites.connectionStrings.Properties["DATABASE_URL"]; to.String(got.Value) != dbURL || got.Type != web.Custom {
+		t.Logf("unexpected connection string: %+v", got)
+		t.Fail()
+	}
+
+	sites = &fakeSiteConfigClient{
+		appSettings: web.StringDictionary{Properties: map[string]*string{
+			"GO_ENV": to.StringPtr("production"),
+		}},
+		connectionStrings: web.ConnectionStringDictionary{Properties: map[string]*web.ConnStringValueTypePair{}},
+	}
+
+	if err := setSiteDatabaseURL(context.Background(), sites, "contoso", "contoso", dbURL); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(sites.saved) != 0 {
+		t.Logf("saved %v for a site without %s", sites.saved, DatabaseURLEnvVar)
+		t.Fail()
+	}
+
+	const reference = "@Microsoft.KeyVault(SecretUri=https://contoso-vault.vault.azure.net/secrets/DATABASE-URL/)"
+	sites = &fakeSiteConfigClient{
+		appSettings: web.StringDictionary{Properties: map[string]*string{
+			"DATABASE_URL": to.StringPtr(reference),
+		}},
+		connectionStrings: web.ConnectionStringDictionary{Properties: map[string]*web.ConnStringValueTypePair{
+			"DATABASE_URL": {Value: to.StringPtr(reference), Type: web.Custom},
+		}},
+	}
+
+	if err := setSiteDatabaseURL(context.Background(), sites, "contoso", "contoso", dbURL); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := to.String(sites.appSettings.Properties["DATABASE_URL"]); got != reference {
+		t.Logf("got app setting: %q want the Key Vault reference kept: %q", got, reference)
+		t.Fail()
+	}
+	if got := to.String(sites.connectionStrings.Properties["DATABASE_URL"].Value); got != reference {
+		t.Logf("got connection string: %q want the Key Vault reference kept: %q", got, reference)
+		t.Fail()
+	}
+	if len(sites.saved) != 2 {
+		t.Logf("saved %v, want both saved so that the site restarts", sites.saved)
+		t.Fail()
+	}
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// session holds the settings that every request sent to Azure depends on. `Provision` and the rotate command each
+// create their own, so that calls made with different settings don't interfere with each other.
+type session struct {
+	// environment is the Azure cloud being targeted.
+	environment azure.Environment
+
+	// deviceClientID is the client ID in use during a Device Auth flow.
+	deviceClientID string
+
+	// httpClient sends every outbound request, so that proxy settings are honored consistently by template downloads,
+	// authentication, and the Azure Resource Manager clients alike.
+	httpClient *http.Client
+
+	// templateRetries and templateTimeout control how persistently downloadTemplate tries to fetch a template.
+	templateRetries int
+	templateTimeout time.Duration
+
+	// tokenFactory creates tokens for an arbitrary Azure resource, using the identity that was established by
+	// `getAuthorizer`. It is nil until authentication has occurred.
+	tokenFactory func(resource string) (*adal.ServicePrincipalToken, error)
+}
+
+// newSession creates a session which targets environment, and sends requests with client. When environment is empty,
+// the Azure Public Cloud is targeted. When client is nil, one which honors the environment variables HTTPS_PROXY,
+// HTTP_PROXY, and NO_PROXY is used.
+func newSession(environment azure.Environment, client *http.Client) *session {
+	if environment.Name == "" {
+		environment = azure.PublicCloud
+	}
+	if client == nil {
+		client = newHTTPClient(http.ProxyFromEnvironment)
+	}
+	return &session{
+		environment:     environment,
+		deviceClientID:  defaultDeviceClientID,
+		httpClient:      client,
+		templateRetries: TemplateDownloadRetriesDefault,
+	}
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextSender binds each request it sends to a context. This allows calls into APIs which do not accept a
+// `context.Context`, like polling for Device Auth completion, to be cancelled.
+type contextSender struct {
+	ctx context.Context
+	*http.Client
+}
+
+// Do sends an HTTP request, which will be cancelled along with the sender's context.
+func (s contextSender) Do(req *http.Request) (*http.Response, error) {
+	return s.Client.Do(req.WithContext(s.ctx))
+}
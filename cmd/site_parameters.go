@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// siteParametersFile is the name of the file that the parameters used to provision a site are cached in, when the
+// shared TemplateParametersDefault file already belongs to another site.
+func siteParametersFile(site string) string {
+	return "azuredeploy." + site + ".parameters.json"
+}
+
+// parametersCacheLocation finds the file in dir which holds the parameters used to provision site. They are kept in the
+// shared TemplateParametersDefault file, unless it belongs to a different site, so that provisioning several sites from
+// one directory, for example into the same resource group, never mixes up their parameters.
+func parametersCacheLocation(dir, site string) string {
+	shared := cacheLocation(dir, TemplateParametersDefault)
+	if site == "" {
+		return shared
+	}
+
+	own := cacheLocation(dir, siteParametersFile(site))
+	if _, err := os.Stat(own); err == nil {
+		return own
+	}
+
+	if cached, err := loadFromParameterFile(shared); err == nil {
+		if name, ok := cached.Parameters["name"]; ok && name.Value != nil && name.Value != site {
+			return own
+		}
+	}
+	return shared
+}
+
+// snapshotDefaults records the values of keys, so that they can later be restored as defaults with restoreDefaults.
+func snapshotDefaults(conf *viper.Viper, keys []string) map[string]interface{} {
+	snapshot := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		snapshot[key] = conf.Get(key)
+	}
+	return snapshot
+}
+
+// restoreDefaults sets the default of each key recorded by snapshotDefaults back to the value that was recorded.
+func restoreDefaults(conf *viper.Viper, snapshot map[string]interface{}) {
+	for key, value := range snapshot {
+		conf.SetDefault(key, value)
+	}
+}
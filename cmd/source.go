@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// sourceRuntimeStack is the App Service runtime that builds and runs a site deployed from source, instead of from a
+// container image.
+const sourceRuntimeStack = "GO|1.19"
+
+// sourceExcludedDirs are directories which are never included when a Buffalo application is packaged, either because
+// they are rebuilt during deployment or because they only matter on a developer's machine.
+var sourceExcludedDirs = map[string]struct{}{
+	".git":         {},
+	"bin":          {},
+	"node_modules": {},
+	"tmp":          {},
+}
+
+// sourceExcludedFiles are files which are never included when a Buffalo application is packaged, because they may hold
+// secrets. Settings the site needs are provided as App Settings instead.
+var sourceExcludedFiles = map[string]struct{}{
+	".env": {},
+}
+
+// packageSource writes a zip archive of the Buffalo application rooted at dir to dest, suitable for deploying to App
+// Service.
+func packageSource(dir string, dest io.Writer) error {
+	archive := zip.NewWriter(dest)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		if info.IsDir() {
+			if _, ok := sourceExcludedDirs[info.Name()]; ok {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if _, ok := sourceExcludedFiles[info.Name()]; ok || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+
+		entry, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		handle, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer handle.Close()
+
+		_, err = io.Copy(entry, handle)
+		return err
+	})
+	if err != nil {
+		archive.Close()
+		return err
+	}
+
+	return archive.Close()
+}
+
+// zipDeployLink finds the address of the endpoint which accepts packages to be deployed to a site.
+var zipDeployLink = func(site string) string {
+	return fmt.Sprintf("https://%s.scm.azurewebsites.net/api/zipdeploy?isAsync=false", site)
+}
+
+// zipDeploy uploads a package created by packageSource to a site, which builds and starts it. The identity established
+// by `getAuthorizer` is used to authenticate.
+func (s *session) zipDeploy(ctx context.Context, authorizer autorest.Authorizer, site string, pkg []byte) error {
+	req, err := http.NewRequest(http.MethodPost, zipDeployLink(site), bytes.NewReader(pkg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/zip")
+
+	req, err = autorest.Prepare(req.WithContext(ctx), authorizer.WithAuthorization())
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
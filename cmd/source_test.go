@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+func Test_packageSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure-source")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"main.go", "actions/app.go", ".env", "node_modules/left-pad/index.js", "bin/app"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := packageSource(dir, buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var got []string
+	for _, f := range archive.File {
+		got = append(got, f.Name)
+	}
+	sort.Strings(got)
+
+	want := []string{"actions/app.go", "main.go"}
+	if len(got) != len(want) {
+		t.Logf("got files: %v want: %v", got, want)
+		t.FailNow()
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Logf("got files: %v want: %v", got, want)
+			t.Fail()
+			break
+		}
+	}
+}
+
+func Test_zipDeploy(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		received, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	originalLink := zipDeployLink
+	zipDeployLink = func(string) string { return server.URL }
+	defer func() {
+		zipDeployLink = originalLink
+	}()
+
+	if err := newSession(azure.PublicCloud, server.Client()).zipDeploy(context.Background(), autorest.NullAuthorizer{}, "contoso", []byte("package")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if string(received) != "package" {
+		t.Logf("got body: %q want: %q", received, "package")
+		t.Fail()
+	}
+}
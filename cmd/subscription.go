@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/subscriptions"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/sirupsen/logrus"
+)
+
+// subscriptionIDPattern matches the IDs of Azure subscriptions, which are UUIDs.
+var subscriptionIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isSubscriptionID decides whether a subscription was identified by its ID, rather than by its display name.
+func isSubscriptionID(subject string) bool {
+	return subscriptionIDPattern.MatchString(subject)
+}
+
+// listSubscriptions finds all of the subscriptions that an identity has access to. Tests replace it with a fake.
+var listSubscriptions = func(ctx context.Context, s *session, authorizer autorest.Authorizer) ([]subscriptions.Subscription, error) {
+	client := subscriptions.NewClient()
+	client.Authorizer = authorizer
+	client.Sender = s.httpClient
+	client.AddToUserAgent(userAgent)
+
+	var listed []subscriptions.Subscription
+	list, err := client.ListComplete(ctx)
+	for ; err == nil && list.NotDone(); err = list.Next() {
+		listed = append(listed, list.Value())
+	}
+	return listed, err
+}
+
+// findSubscriptions finds the IDs of each subscription listed which is identified by want, either by its ID, or
+// case-insensitively by its display name.
+func findSubscriptions(listed []subscriptions.Subscription, want string) (ids []string) {
+	byID := isSubscriptionID(want)
+	for _, sub := range listed {
+		if sub.SubscriptionID == nil {
+			continue
+		}
+
+		if byID && strings.EqualFold(*sub.SubscriptionID, want) {
+			ids = append(ids, *sub.SubscriptionID)
+		} else if !byID && sub.DisplayName != nil && strings.EqualFold(*sub.DisplayName, want) {
+			ids = append(ids, *sub.SubscriptionID)
+		}
+	}
+	return
+}
+
+// resolveSubscriptionID finds the ID of a subscription that may have been identified by its display name. IDs are
+// returned as is, without contacting Azure. A display name shared by more than one subscription is an error, as there
+// is no telling which was meant.
+func (s *session) resolveSubscriptionID(ctx context.Context, authorizer autorest.Authorizer, subscription string) (string, error) {
+	if isSubscriptionID(subscription) {
+		return subscription, nil
+	}
+
+	listed, err := listSubscriptions(ctx, s, authorizer)
+	if err != nil {
+		return "", err
+	}
+
+	switch ids := findSubscriptions(listed, subscription); len(ids) {
+	case 0:
+		return "", fmt.Errorf("unable to find subscription: %s", subscription)
+	case 1:
+		log.WithFields(logrus.Fields{"name": subscription, "subscription": ids[0]}).Debug("resolved subscription name")
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("more than one subscription is named %q, use one of their IDs instead: %s", subscription, strings.Join(ids, ", "))
+	}
+}
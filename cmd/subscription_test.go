@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/subscriptions"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+func Test_resolveSubscriptionID(t *testing.T) {
+	newSubscription := func(id, name string) subscriptions.Subscription {
+		return subscriptions.Subscription{SubscriptionID: &id, DisplayName: &name}
+	}
+
+	original := listSubscriptions
+	listSubscriptions = func(context.Context, *session, autorest.Authorizer) ([]subscriptions.Subscription, error) {
+		return []subscriptions.Subscription{
+			newSubscription("00000000-0000-0000-0000-000000000001", "Production"),
+			newSubscription("00000000-0000-0000-0000-000000000002", "Shared"),
+			newSubscription("00000000-0000-0000-0000-000000000003", "Shared"),
+		}, nil
+	}
+	defer func() {
+		listSubscriptions = original
+	}()
+
+	testCases := []struct {
+		subscription string
+		want         string
+		wantErr      bool
+	}{
+		{"6a9bf7d4-3c36-4e0a-9d6c-1d2f6b1e9a7c", "6a9bf7d4-3c36-4e0a-9d6c-1d2f6b1e9a7c", false},
+		{"Production", "00000000-0000-0000-0000-000000000001", false},
+		{"production", "00000000-0000-0000-0000-000000000001", false},
+		{"Shared", "", true},
+		{"Staging", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.subscription, func(t *testing.T) {
+			got, err := newSession(azure.PublicCloud, nil).resolveSubscriptionID(context.Background(), autorest.NullAuthorizer{}, tc.subscription)
+			if (err != nil) != tc.wantErr {
+				t.Logf("got error: %v want error: %v", err, tc.wantErr)
+				t.Fail()
+			}
+
+			if got != tc.want {
+				t.Logf("got: %q want: %q", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// storageResource is the resource that tokens must be issued for to access Azure Storage using Azure Active Directory.
+const storageResource = "https://storage.azure.com/"
+
+// storageAPIVersion is the earliest version of the Azure Storage REST API which accepts bearer tokens.
+const storageAPIVersion = "2017-11-09"
+
+// templatePreparer attaches credentials to a request for a template, when it is appropriate.
+type templatePreparer func(*http.Request) (*http.Request, error)
+
+// getTemplatePreparer creates a templatePreparer which attaches a bearer token to requests sent to Azure Storage or
+// Azure Resource Manager endpoints. When force is true, a bearer token for Azure Resource Manager is attached to
+// requests sent to any other host as well.
+//
+// Requests to Azure Storage which are already authorized by a Shared Access Signature are left alone.
+func (s *session) getTemplatePreparer(force bool) templatePreparer {
+	return func(req *http.Request) (*http.Request, error) {
+		var resource string
+		var decorators []autorest.PrepareDecorator
+
+		if s.isStorageHost(req.URL) {
+			if req.URL.Query().Get("sig") != "" {
+				return req, nil
+			}
+			resource = storageResource
+			decorators = append(decorators, autorest.WithHeader("x-ms-version", storageAPIVersion))
+		} else if s.isResourceManagerHost(req.URL) || force {
+			resource = s.environment.ResourceManagerEndpoint
+		} else {
+			return req, nil
+		}
+
+		if s.tokenFactory == nil {
+			return nil, errors.New("unable to authorize template download, no identity has been authenticated")
+		}
+
+		token, err := s.tokenFactory(resource)
+		if err != nil {
+			return nil, err
+		}
+		token.SetSender(s.httpClient)
+
+		decorators = append(decorators, autorest.NewBearerAuthorizer(token).WithAuthorization())
+		return autorest.Prepare(req, decorators...)
+	}
+}
+
+func (s *session) isStorageHost(u *url.URL) bool {
+	suffix := s.environment.StorageEndpointSuffix
+	return suffix != "" && strings.HasSuffix(strings.ToLower(u.Hostname()), "."+strings.ToLower(suffix))
+}
+
+func (s *session) isResourceManagerHost(u *url.URL) bool {
+	rm, err := url.Parse(s.environment.ResourceManagerEndpoint)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Hostname(), rm.Hostname())
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+func Test_getTemplatePreparer(t *testing.T) {
+	testCases := []struct {
+		link      string
+		force     bool
+		expectErr bool
+	}{
+		{"https://aka.ms/buffalo-template", false, false},
+		{"https://contoso.blob.core.windows.net/templates/azuredeploy.json?sv=2017-11-09&sig=abc", false, false},
+		{"https://contoso.blob.core.windows.net/templates/azuredeploy.json", false, true},
+		{"https://management.azure.com/providers/Microsoft.Resources/templateSpecs", false, true},
+		{"https://aka.ms/buffalo-template", true, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.link, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.link, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			// With no identity authenticated, any attempt to authorize the request will fail.
+			result, err := newSession(azure.PublicCloud, nil).getTemplatePreparer(tc.force)(req)
+			if tc.expectErr {
+				if err == nil {
+					t.Log("expected the request to require authorization")
+					t.Fail()
+				}
+				return
+			} else if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if got := result.Header.Get("Authorization"); got != "" {
+				t.Logf("unexpected Authorization header: %q", got)
+				t.Fail()
+			}
+		})
+	}
+}
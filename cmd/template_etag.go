@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+	"github.com/sirupsen/logrus"
+)
+
+// templateETagSuffix is appended to the location of the cached default template to find where its ETag is saved.
+const templateETagSuffix = ".etag"
+
+// templateETag records the ETag the default template was served with, alongside the SHA-256 digest of the copy of it
+// that was cached. The digest allows a cached template that has since been edited to be detected, so that it isn't
+// mistaken for the template the ETag describes.
+type templateETag struct {
+	ETag   string `json:"etag"`
+	SHA256 string `json:"sha256"`
+}
+
+// readCachedTemplate finds the ETag and contents of the default template cached at location. If either are missing,
+// or the template no longer matches the digest recorded alongside the ETag, ok is false.
+func readCachedTemplate(location string) (etag string, contents []byte, ok bool) {
+	rawETag, err := ioutil.ReadFile(location + templateETagSuffix)
+	if err != nil {
+		return
+	}
+
+	var saved templateETag
+	if err = json.Unmarshal(rawETag, &saved); err != nil || saved.ETag == "" {
+		return
+	}
+
+	contents, err = ioutil.ReadFile(location)
+	if err != nil {
+		return
+	}
+
+	if verifyDigest(contents, saved.SHA256) != nil {
+		log.WithFields(logrus.Fields{"location": location}).Debug("cached template has changed since it was downloaded")
+		return
+	}
+
+	return saved.ETag, contents, true
+}
+
+// cacheTemplateETag saves the ETag of the default template next to the copy of it cached at location.
+func cacheTemplateETag(ctx context.Context, location, etag string) error {
+	contents, err := ioutil.ReadFile(location)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(contents)
+	return cache(ctx, templateETag{ETag: etag, SHA256: hex.EncodeToString(sum[:])}, location+templateETagSuffix)
+}
+
+// getDefaultTemplate reads the default template found at link. When a copy of it is cached at location along with the
+// ETag it was served with, the template is only downloaded if it has changed since. Otherwise, the cached
+// copy is used. The ETag the template currently has is returned, so that it can be cached along with the template.
+//
+// If digest is not empty, the template's contents must have a SHA-256 digest matching it. A cached copy that doesn't,
+// perhaps because it was cached by an earlier release, is ignored and the template is downloaded again.
+func (s *session) getDefaultTemplate(ctx context.Context, link, location, digest string, prepare templatePreparer) (*resources.DeploymentProperties, string, error) {
+	etag, cached, _ := readCachedTemplate(location)
+	if digest != "" && verifyDigest(cached, digest) != nil {
+		etag = ""
+	}
+
+	buf := &bytes.Buffer{}
+	served, err := s.downloadTemplateIfModified(ctx, buf, link, prepare, etag)
+	if err == errTemplateNotModified {
+		log.WithFields(logrus.Fields{"location": location}).Info("default template has not changed, using cached copy")
+		return &resources.DeploymentProperties{
+			Template: json.RawMessage(cached),
+		}, etag, nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	if digest != "" {
+		if err = verifyDigest(buf.Bytes(), digest); err != nil {
+			return nil, "", err
+		}
+		log.Debug("template digest verified")
+	}
+
+	return &resources.DeploymentProperties{
+		Template: json.RawMessage(buf.Bytes()),
+	}, served, nil
+}
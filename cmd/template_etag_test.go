@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+func Test_getDefaultTemplate_conditional(t *testing.T) {
+	const etag = `"v1"`
+	const contents = `{"resources": []}`
+
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, contents)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "buffalo-azure-etag")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	location := filepath.Join(dir, "azuredeploy.json")
+
+	ctx := context.Background()
+	s := newSession(azure.PublicCloud, server.Client())
+
+	template, served, err := s.getDefaultTemplate(ctx, server.URL, location, "", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if served != etag {
+		t.Logf("got ETag: %q want: %q", served, etag)
+		t.Fail()
+	}
+
+	if err = cache(ctx, template.Template, location); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = cacheTemplateETag(ctx, location, served); err != nil {
+		t.Error(err)
+		return
+	}
+
+	template, served, err = s.getDefaultTemplate(ctx, server.URL, location, "", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if downloads != 1 {
+		t.Logf("got %d downloads, want 1", downloads)
+		t.Fail()
+	}
+	if served != etag || len(template.Template.(json.RawMessage)) == 0 {
+		t.Logf("cached template was not used, got ETag: %q", served)
+		t.Fail()
+	}
+
+	// Editing the cached template should cause it to be downloaded again.
+	if err = ioutil.WriteFile(location, []byte(`{}`), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, _, err = s.getDefaultTemplate(ctx, server.URL, location, "", nil); err != nil {
+		t.Error(err)
+		return
+	}
+	if downloads != 2 {
+		t.Logf("got %d downloads, want 2", downloads)
+		t.Fail()
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+)
+
+// parameterFlags relates the template parameters that are only passed to a deployment when a particular flag is set,
+// to the name of that flag. It is used to explain which flags a template is unable to honor.
+var parameterFlags = map[string]string{
+	"planSku":                    PlanSKUName + "/--" + HostOSName,
+	"planCapacity":               PlanCapacityName,
+	"existingPlanId":             PlanIDName,
+	"hostOS":                     HostOSName,
+	"customHostname":             CustomDomainName,
+	"managedCertificate":         CustomDomainName,
+	"appSettings":                AppSettingName,
+	"startupCommand":             StartupCommandName,
+	"identityType":               SystemIdentityName + "/--" + UserIdentityName,
+	"userAssignedIdentityId":     UserIdentityName,
+	"runtimeStack":               SourceName,
+	"packageUri":                 SourceName,
+	"databaseVersion":            DatabaseVersionName,
+	"databaseSku":                DatabaseSKUName,
+	"databaseStorageGB":          DatabaseStorageName,
+	"databaseAllowedIPs":         DatabaseAllowIPName,
+	"databaseAllowAzureServices": DatabaseAllowAzureServicesName,
+	"keyVaultName":               KeyVaultName,
+	"createKeyVault":             CreateKeyVaultName,
+}
+
+// undeclaredParameters lists, in sorted order, the parameters about to be passed to a deployment of template that it
+// doesn't declare. Azure Resource Manager rejects a deployment with any such parameter.
+func undeclaredParameters(template interface{}, params map[string]DeploymentParameter) ([]string, error) {
+	if template == nil {
+		return nil, nil
+	}
+
+	contents, ok := template.(json.RawMessage)
+	if !ok {
+		var err error
+		if contents, err = json.Marshal(template); err != nil {
+			return nil, err
+		}
+	}
+
+	var declared struct {
+		Parameters map[string]json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal(contents, &declared); err != nil {
+		return nil, fmt.Errorf("unable to parse template: %v", err)
+	}
+
+	var missing []string
+	for name := range params {
+		if _, ok := declared.Parameters[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// checkTemplateParameters ensures that the template about to be deployed declares each of the parameters that will
+// be passed to it. The template published at TemplateDefaultLink may lag behind the copy distributed with this
+// program, so when it doesn't declare a parameter, the bundled copy is deployed instead. Any other template must
+// declare every parameter, or an error describing the flags it can't honor is returned before anything is deployed.
+// A template that is deployed by reference, like a Template Spec, can't be checked here, so Azure Resource Manager is
+// left to reject it.
+func checkTemplateParameters(template *resources.DeploymentProperties, location string, params map[string]DeploymentParameter) (*resources.DeploymentProperties, error) {
+	missing, err := undeclaredParameters(template.Template, params)
+	if err != nil || len(missing) == 0 {
+		return template, err
+	}
+
+	if location == TemplateDefaultLink {
+		bundled := &resources.DeploymentProperties{
+			Template: json.RawMessage(defaultTemplate),
+		}
+		if stillMissing, err := undeclaredParameters(bundled.Template, params); err == nil && len(stillMissing) == 0 {
+			log.Infof("the template at %s doesn't declare %s, using the copy distributed with buffalo-azure instead", location, strings.Join(missing, ", "))
+			return bundled, nil
+		}
+	}
+
+	described := make([]string, 0, len(missing))
+	for _, name := range missing {
+		if flag, ok := parameterFlags[name]; ok {
+			name = fmt.Sprintf("%s (set by --%s)", name, flag)
+		}
+		described = append(described, name)
+	}
+	return nil, fmt.Errorf("template %s doesn't declare the parameters: %s", location, strings.Join(described, ", "))
+}
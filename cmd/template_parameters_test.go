@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+)
+
+// templateWithout creates a copy of the bundled template which doesn't declare the named parameters, like the template
+// published at TemplateDefaultLink.
+func templateWithout(t *testing.T, names ...string) json.RawMessage {
+	var template map[string]interface{}
+	if err := json.Unmarshal([]byte(defaultTemplate), &template); err != nil {
+		t.Fatal(err)
+	}
+
+	declared := template["parameters"].(map[string]interface{})
+	for _, name := range names {
+		delete(declared, name)
+	}
+
+	contents, err := json.Marshal(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contents
+}
+
+func Test_checkTemplateParameters(t *testing.T) {
+	params := map[string]DeploymentParameter{
+		"name":    {"contoso"},
+		"planSku": {"S1"},
+	}
+
+	testCases := []struct {
+		name        string
+		template    interface{}
+		location    string
+		wantBundled bool
+		wantErr     string
+	}{
+		{"declared", json.RawMessage(defaultTemplate), "./template.json", false, ""},
+		{"link", nil, "./template.json", false, ""},
+		{"undeclared", templateWithout(t, "planSku"), "./template.json", false, "planSku (set by --" + PlanSKUName},
+		{"undeclared default", templateWithout(t, "planSku"), TemplateDefaultLink, true, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := checkTemplateParameters(&resources.DeploymentProperties{Template: tc.template}, tc.location, params)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Logf("got error: %v want one mentioning: %q", err, tc.wantErr)
+					t.Fail()
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			raw, _ := got.Template.(json.RawMessage)
+			if isBundled := string(raw) == defaultTemplate; isBundled != tc.wantBundled {
+				t.Logf("got bundled template: %v want: %v", isBundled, tc.wantBundled)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestProvision_templateMissingParameters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_template_parameters_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	postgres := func(opts ProvisionOptions) ProvisionOptions {
+		opts.DatabaseType = "postgres"
+		opts.DatabaseAdmin = DatabaseAdminDefault
+		opts.DatabaseName = "contoso_production"
+		opts.DatabasePasswordLength = DatabasePasswordLengthDefault
+		return opts
+	}
+
+	testCases := []struct {
+		name       string
+		undeclared []string
+		opts       ProvisionOptions
+	}{
+		{"planSku", []string{"planSku"}, ProvisionOptions{PlanSKU: "S1"}},
+		{"planCapacity", []string{"planCapacity"}, ProvisionOptions{PlanCapacity: 2}},
+		{"appSettings", []string{"appSettings"}, ProvisionOptions{AppSettings: map[string]string{"GO_ENV": "staging"}}},
+		{"startupCommand", []string{"startupCommand"}, ProvisionOptions{StartupCommand: "/bin/app migrate"}},
+		{"existingPlanId", []string{"existingPlanId"}, ProvisionOptions{PlanID: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/shared/providers/Microsoft.Web/serverfarms/shared-plan"}},
+		{"identityType", []string{"identityType", "userAssignedIdentityId"}, ProvisionOptions{SystemIdentity: true}},
+		{"userAssignedIdentityId", []string{"userAssignedIdentityId"}, ProvisionOptions{UserIdentityID: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/shared/providers/Microsoft.ManagedIdentity/userAssignedIdentities/contoso"}},
+		{"hostOS", []string{"hostOS"}, ProvisionOptions{HostOS: HostOSWindows}},
+		{"windows planSku", []string{"planSku"}, ProvisionOptions{HostOS: HostOSWindows}},
+		{"runtimeStack", []string{"runtimeStack"}, ProvisionOptions{Source: "https://contoso.blob.core.windows.net/releases/contoso.zip"}},
+		{"packageUri", []string{"packageUri"}, ProvisionOptions{Source: "https://contoso.blob.core.windows.net/releases/contoso.zip"}},
+		{"databaseVersion", []string{"databaseVersion"}, postgres(ProvisionOptions{DatabaseVersion: "11"})},
+		{"databaseSku", []string{"databaseSku"}, postgres(ProvisionOptions{DatabaseSKU: "GP_Gen5_2"})},
+		{"databaseStorageGB", []string{"databaseStorageGB"}, postgres(ProvisionOptions{DatabaseStorageGB: 32})},
+		{"databaseAllowedIPs", []string{"databaseAllowedIPs"}, postgres(ProvisionOptions{DatabaseAllowedIPs: []string{"203.0.113.0-203.0.113.255"}})},
+		{"databaseAllowAzureServices", []string{"databaseAllowAzureServices"}, postgres(ProvisionOptions{DatabaseBlockAzure: true})},
+		{"keyVaultName", []string{"keyVaultName"}, postgres(ProvisionOptions{KeyVault: "contoso-vault"})},
+		{"createKeyVault", []string{"createKeyVault"}, postgres(ProvisionOptions{KeyVault: "contoso-vault", CreateKeyVault: true})},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			templateLocation := filepath.Join(dir, tc.name+".json")
+			if err := ioutil.WriteFile(templateLocation, templateWithout(t, tc.undeclared...), 0644); err != nil {
+				t.Error(err)
+				return
+			}
+
+			opts := tc.opts
+			opts.SiteName = "contoso"
+			opts.ResourceGroup = "contoso"
+			opts.Image = ImageDefault
+			if opts.DatabaseType == "" {
+				opts.DatabaseType = "none"
+			}
+			opts.TemplateLocation = templateLocation
+			opts.CacheDir = dir
+			opts.SkipTemplateCache = true
+			opts.SkipDeployment = true
+
+			_, err := Provision(context.Background(), opts)
+			if err == nil {
+				t.Log("expected an error deploying a template which doesn't declare: ", tc.undeclared)
+				t.Fail()
+				return
+			}
+			for _, name := range tc.undeclared {
+				if !strings.Contains(err.Error(), name) {
+					t.Logf("got error: %v want one mentioning: %q", err, name)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+func TestProvision_customDomainUndeclared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffalo-azure_template_parameters_test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	originalCNAME := lookupCNAME
+	defer func() {
+		lookupCNAME = originalCNAME
+	}()
+
+	var looked bool
+	lookupCNAME = func(host string) (string, error) {
+		looked = true
+		return "contoso.azurewebsites.net.", nil
+	}
+
+	templateLocation := filepath.Join(dir, "template.json")
+	if err := ioutil.WriteFile(templateLocation, templateWithout(t, "customHostname", "managedCertificate"), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = Provision(context.Background(), ProvisionOptions{
+		SiteName:           "contoso",
+		ResourceGroup:      "contoso",
+		Image:              ImageDefault,
+		DatabaseType:       "none",
+		CustomDomain:       "www.contoso.com",
+		ManagedCertificate: true,
+		TemplateLocation:   templateLocation,
+		CacheDir:           dir,
+		SkipTemplateCache:  true,
+		SkipDeployment:     true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "--"+CustomDomainName) {
+		t.Logf("got error: %v want one mentioning --%s", err, CustomDomainName)
+		t.Fail()
+	}
+
+	if looked {
+		t.Log("DNS was consulted for a custom domain the template can't bind")
+		t.Fail()
+	}
+}
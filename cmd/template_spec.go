@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"regexp"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+)
+
+// templateSpecDeploymentsAPIVersion is the version of the Azure Resource Manager API used to deploy Template Specs. The
+// version used by `resources.DeploymentsClient` predates them, and has no way to refer to one.
+const templateSpecDeploymentsAPIVersion = "2021-04-01"
+
+var templateSpecPattern = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Resources/templateSpecs/[^/]+/versions/[^/]+$`)
+
+// isTemplateSpecID decides if a string is the resource ID of a particular version of a Template Spec, for example:
+// /subscriptions/{subscription}/resourceGroups/{group}/providers/Microsoft.Resources/templateSpecs/{name}/versions/{version}
+func isTemplateSpecID(subject string) bool {
+	return templateSpecPattern.MatchString(subject)
+}
+
+// templateSpecLink finds the resource ID of the Template Spec version that a deployment refers to, if it refers to one.
+//
+// `resources.TemplateLink` has no field for the ID, so it is carried in URI until the deployment is sent by
+// `waitingDeploymentsClient`, which moves it to where the newer API expects it.
+func templateSpecLink(properties *resources.DeploymentProperties) (string, bool) {
+	if properties == nil || properties.TemplateLink == nil || properties.TemplateLink.URI == nil {
+		return "", false
+	}
+	id := *properties.TemplateLink.URI
+	return id, isTemplateSpecID(id)
+}
+
+// templateSpecDeployment is the body of a request to deploy a Template Spec version, using
+// templateSpecDeploymentsAPIVersion.
+type templateSpecDeployment struct {
+	Properties struct {
+		TemplateLink struct {
+			ID string `json:"id"`
+		} `json:"templateLink"`
+		Parameters interface{}              `json:"parameters,omitempty"`
+		Mode       resources.DeploymentMode `json:"mode,omitempty"`
+	} `json:"properties"`
+}
+
+// newTemplateSpecDeployment describes a deployment of the Template Spec version with the given resource ID, with the
+// parameters and mode of properties.
+func newTemplateSpecDeployment(id string, properties *resources.DeploymentProperties) templateSpecDeployment {
+	var deployment templateSpecDeployment
+	deployment.Properties.TemplateLink.ID = id
+	deployment.Properties.Parameters = properties.Parameters
+	deployment.Properties.Mode = properties.Mode
+	return deployment
+}
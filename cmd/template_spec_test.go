@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func Test_isTemplateSpecID(t *testing.T) {
+	testCases := []struct {
+		subject string
+		want    bool
+	}{
+		{"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/templates/providers/Microsoft.Resources/templateSpecs/buffalo/versions/1.0", true},
+		{"/SUBSCRIPTIONS/00000000-0000-0000-0000-000000000000/RESOURCEGROUPS/templates/PROVIDERS/microsoft.resources/TEMPLATESPECS/buffalo/VERSIONS/1.0", true},
+		{"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/templates/providers/Microsoft.Resources/templateSpecs/buffalo", false},
+		{"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/templates/providers/Microsoft.Web/sites/buffalo", false},
+		{"https://aka.ms/buffalo-template", false},
+		{"./azuredeploy.json", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.subject, func(t *testing.T) {
+			if got := isTemplateSpecID(tc.subject); got != tc.want {
+				t.Logf("got: %v want: %v", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func Test_getDeploymentTemplate_templateSpec(t *testing.T) {
+	const id = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/templates/providers/Microsoft.Resources/templateSpecs/buffalo/versions/1.0"
+
+	s := newSession(azure.PublicCloud, nil)
+
+	result, err := s.getDeploymentTemplate(context.Background(), id, "", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if result.Template != nil {
+		t.Log("a template spec should be deployed by reference, not inlined")
+		t.Fail()
+	}
+
+	if got, ok := templateSpecLink(result); !ok || got != id {
+		t.Logf("got link: %q want: %q", got, id)
+		t.Fail()
+	}
+
+	if _, err := s.getDeploymentTemplate(context.Background(), id, TemplateDefaultSHA256, nil); err == nil {
+		t.Log("expected an error when asked to verify the digest of a template spec")
+		t.Fail()
+	}
+}
+
+func Test_waitingDeploymentsClient_templateSpec(t *testing.T) {
+	const id = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/templates/providers/Microsoft.Resources/templateSpecs/buffalo/versions/1.0"
+
+	var gotVersion string
+	var sent struct {
+		Properties struct {
+			Template     json.RawMessage `json:"template"`
+			TemplateLink struct {
+				ID  string `json:"id"`
+				URI string `json:"uri"`
+			} `json:"templateLink"`
+			Parameters map[string]DeploymentParameter `json:"parameters"`
+			Mode       string                         `json:"mode"`
+		} `json:"properties"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/subscriptions/sub/resourcegroups/contoso/providers/Microsoft.Resources/deployments/contoso-deployment" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		gotVersion = r.URL.Query().Get("api-version")
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "contoso-deployment", "properties": {"provisioningState": "Succeeded"}}`))
+	}))
+	defer server.Close()
+
+	deployments := resources.NewDeploymentsClientWithBaseURI(server.URL, "sub")
+	deployments.Authorizer = autorest.NullAuthorizer{}
+	client := waitingDeploymentsClient{deployments}
+
+	properties := &resources.DeploymentProperties{
+		TemplateLink: &resources.TemplateLink{URI: to.StringPtr(id)},
+		Parameters:   map[string]DeploymentParameter{"name": {"contoso"}},
+		Mode:         resources.Incremental,
+	}
+	if err := doDeployment(context.Background(), client, "contoso", "contoso-deployment", properties); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if gotVersion != templateSpecDeploymentsAPIVersion {
+		t.Logf("got api-version: %q want: %q", gotVersion, templateSpecDeploymentsAPIVersion)
+		t.Fail()
+	}
+
+	if got := sent.Properties.TemplateLink; got.ID != id || got.URI != "" {
+		t.Logf("got template link: %+v want the id: %q", got, id)
+		t.Fail()
+	}
+
+	if len(sent.Properties.Template) != 0 {
+		t.Logf("the template was inlined: %s", sent.Properties.Template)
+		t.Fail()
+	}
+
+	if got := sent.Properties.Parameters["name"].Value; got != "contoso" || sent.Properties.Mode != string(resources.Incremental) {
+		t.Logf("got parameters: %v mode: %q", sent.Properties.Parameters, sent.Properties.Mode)
+		t.Fail()
+	}
+}
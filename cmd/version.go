@@ -22,6 +22,8 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"runtime"
 
 	"github.com/spf13/cobra"
@@ -34,15 +36,37 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information about the Buffalo-Azure plugin.",
 	Run: func(cmd *cobra.Command, args []string) {
-		if version == "" {
-			version = "unknown version"
-		}
-		fmt.Println("Buffalo-Azure Version: ", version)
-		fmt.Println("Go version: ", runtime.Version())
+		writeVersion(os.Stdout)
 	},
 	Args: cobra.NoArgs,
 }
 
+// writeVersion describes this build of buffalo-azure, and the environment it is running in, for inclusion in bug
+// reports. Credentials are only ever described by where they came from, never by their values.
+func writeVersion(out io.Writer) {
+	shownVersion := version
+	if shownVersion == "" {
+		shownVersion = "unknown version"
+	}
+
+	digest := TemplateDefaultSHA256
+	if digest == "" {
+		digest = "not set"
+	}
+
+	credentials := "none detected, device auth will be used"
+	if provisionConfig.GetString(ClientIDName) != "" && provisionConfig.GetString(ClientSecretName) != "" {
+		credentials = "service principal"
+	}
+
+	fmt.Fprintln(out, "Buffalo-Azure Version: ", shownVersion)
+	fmt.Fprintln(out, "Go version: ", runtime.Version())
+	fmt.Fprintln(out, "OS/Arch: ", runtime.GOOS+"/"+runtime.GOARCH)
+	fmt.Fprintln(out, "Default template: ", "bundled, published at "+TemplateDefaultLink)
+	fmt.Fprintln(out, "Default template SHA-256: ", digest)
+	fmt.Fprintln(out, "Credentials: ", credentials)
+}
+
 func init() {
 	azureCmd.AddCommand(versionCmd)
 
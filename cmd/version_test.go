@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_writeVersion(t *testing.T) {
+	originalVersion := version
+	defer func() {
+		version = originalVersion
+	}()
+	version = "v1.2.3"
+
+	buf := &bytes.Buffer{}
+	writeVersion(buf)
+	output := buf.String()
+
+	for _, want := range []string{"v1.2.3", runtime.Version(), runtime.GOOS + "/" + runtime.GOARCH, TemplateDefaultLink} {
+		if !strings.Contains(output, want) {
+			t.Logf("output did not contain %q:\n%s", want, output)
+			t.Fail()
+		}
+	}
+
+	if secret := provisionConfig.GetString(ClientSecretName); secret != "" && strings.Contains(output, secret) {
+		t.Log("output contained the client secret")
+		t.Fail()
+	}
+}
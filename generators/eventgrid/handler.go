@@ -0,0 +1,76 @@
+package eventgrid
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gobuffalo/buffalo/meta"
+	"github.com/gobuffalo/makr"
+	"github.com/markbates/inflect"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// HandlerGenerator adds a single `eventgrid.EventHandler` to an existing `buffalo.App`, along with a function which
+// binds it to a `TypeDispatchSubscriber` and a stub for its test.
+type HandlerGenerator struct{}
+
+// Run writes the handler for Events of type eventType, and its test, to the actions package of app. Existing files
+// are never overwritten.
+func (hg *HandlerGenerator) Run(app meta.App, eventType string) error {
+	name, err := HandlerName(eventType)
+	if err != nil {
+		return err
+	}
+
+	actionsDir := filepath.Join(app.Root, path.Base(app.ActionsPkg))
+	handlerFilepath := filepath.Join(path.Base(app.ActionsPkg), fmt.Sprintf("eventgrid_%s.go", name.File()))
+	testFilepath := filepath.Join(path.Base(app.ActionsPkg), fmt.Sprintf("eventgrid_%s_test.go", name.File()))
+
+	for _, f := range []string{handlerFilepath, testFilepath} {
+		if _, err := os.Stat(filepath.Join(app.Root, f)); err == nil {
+			return fmt.Errorf("%s already exists", f)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(actionsDir); err != nil {
+		return fmt.Errorf("unable to find actions package: %v", err)
+	}
+
+	g := makr.New()
+	defer g.Fmt(app.Root)
+
+	g.Add(makr.NewFile(handlerFilepath, string(staticTemplates["templates/actions/eventgrid_handler.go.tmpl"])))
+	g.Add(makr.NewFile(testFilepath, string(staticTemplates["templates/actions/eventgrid_handler_test.go.tmpl"])))
+
+	d := make(makr.Data)
+	d["name"] = name
+	d["eventType"] = eventType
+
+	return g.Run(app.Root, d)
+}
+
+// HandlerName finds the name a generated handler should be given, based on the last segment of the Event Type it
+// receives. For example, "Microsoft.Storage.BlobCreated" is handled by "HandleBlobCreated".
+func HandlerName(eventType string) (inflect.Name, error) {
+	if eventType == "" {
+		return "", fmt.Errorf("an Event Type is required")
+	}
+
+	if strings.ContainsAny(eventType, "\"\\\n") {
+		return "", fmt.Errorf("unsupported characters in Event Type %q", eventType)
+	}
+
+	last := eventType[strings.LastIndex(eventType, ".")+1:]
+	name := inflect.Name(last)
+	if !identifierPattern.MatchString(name.Camel()) {
+		return "", fmt.Errorf("unable to derive a Go identifier from Event Type %q", eventType)
+	}
+	return name, nil
+}
@@ -0,0 +1,41 @@
+package eventgrid
+
+import (
+	"testing"
+)
+
+func TestHandlerName(t *testing.T) {
+	testCases := []struct {
+		eventType string
+		want      string
+		wantErr   bool
+	}{
+		{"Microsoft.Storage.BlobCreated", "BlobCreated", false},
+		{"GitHub.PullRequest", "PullRequest", false},
+		{"deployment_finished", "DeploymentFinished", false},
+		{"", "", true},
+		{"Contoso.", "", true},
+		{"Contoso.1stEvent", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.eventType, func(t *testing.T) {
+			got, err := HandlerName(tc.eventType)
+			if tc.wantErr {
+				if err == nil {
+					t.Logf("expected an error, got name: %q", got.Camel())
+					t.Fail()
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got.Camel() != tc.want {
+				t.Logf("got: %q want: %q", got.Camel(), tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
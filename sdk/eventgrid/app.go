@@ -14,6 +14,7 @@ func RegisterSubscriber(app *buffalo.App, route string, s Subscriber) *buffalo.A
 	route = "/"
 
 	group.POST(route, SubscriptionValidationMiddleware(s.Receive))
+	group.OPTIONS(route, ReceiveWebHookValidationRequest)
 	group.GET(route, s.List)
 	group.GET(route+"{event_id}", s.Show)
 
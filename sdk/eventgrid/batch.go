@@ -0,0 +1,147 @@
+package eventgrid
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/sirupsen/logrus"
+)
+
+// batchReceiver holds the settings which TypeDispatchSubscriber and SubjectDispatchSubscriber share for
+// receiving a batch of Events. See TypeDispatchSubscriber for what each of them does.
+type batchReceiver struct {
+	maxBodyBytes      int64
+	maxEvents         int
+	filter            func(Event) bool
+	dedup             *Deduplicator
+	processed         ProcessedStore
+	begin             TransactionBeginner
+	maxConcurrency    int
+	logger            logrus.FieldLogger
+	bestEffort        bool
+	successStatusCode int
+}
+
+// receive binds the batch of Events sent in a request, hands each of them to dispatch, and reports the
+// outcome. Events rejected by filter, recently seen by dedup, or already marked in processed, are skipped.
+func (r batchReceiver) receive(c buffalo.Context, dispatch func(buffalo.Context, Event) error) error {
+	events, err := bindEvents(c, r.maxBodyBytes, r.maxEvents)
+	if err != nil {
+		return c.Error(bindErrorStatus(err), err)
+	}
+
+	processed := r.processed
+	if r.dedup != nil {
+		events = uniqueEvents(events)
+		if processed == nil {
+			processed = dedupStore{r.dedup}
+		} else {
+			processed = processedStores{processed, dedupStore{r.dedup}}
+		}
+	}
+
+	logger := r.logger
+	if logger == nil {
+		logger = discardLogger
+	}
+
+	failures, err := dispatchEvents(c, events, processed, r.begin, r.maxConcurrency, func(ctx buffalo.Context, event Event) error {
+		if r.filter != nil && !r.filter(event) {
+			return nil
+		}
+		return dispatch(ctx, event)
+	})
+	if err != nil {
+		logger.WithError(err).Error("batch failed to be processed")
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	for _, failure := range failures.Failures {
+		logger.WithFields(eventFields(failure.Event)).WithError(failure.Err).Error("event failed to be processed")
+	}
+	return respondToBatch(c, failures, r.bestEffort, r.successStatusCode)
+}
+
+// dispatchBatch hands each Event to dispatch concurrently, each with its own Context derived from c, and
+// collects those which fail. An Event fails when dispatch returns an error, or responds with a Status Code
+// indicating failure. When maxConcurrency is positive, no more than that many Events are dispatched at once.
+//
+// When processed is not nil, Events it has already seen are skipped, and Events which succeed are marked in it.
+func dispatchBatch(c buffalo.Context, events []Event, processed ProcessedStore, maxConcurrency int, dispatch func(buffalo.Context, Event) error) *BatchError {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var slots chan struct{}
+	if maxConcurrency > 0 {
+		slots = make(chan struct{}, maxConcurrency)
+	}
+
+	failures := &BatchError{}
+	batch := NewContext(c)
+	for _, event := range events {
+		wg.Add(1)
+		if slots != nil {
+			slots <- struct{}{}
+		}
+		go func(event Event) {
+			defer wg.Done()
+			if slots != nil {
+				defer func() { <-slots }()
+			}
+
+			if processed != nil && processed.Seen(event.ID) {
+				return
+			}
+
+			ctx := batch.ForEvent(event)
+			err := dispatch(ctx, event)
+			if err == nil && ctx.ResponseHasFailure() {
+				err = ErrHandlerFailed
+			}
+
+			if err != nil {
+				mu.Lock()
+				failures.Failures = append(failures.Failures, EventError{Event: event, Err: err})
+				mu.Unlock()
+			} else if processed != nil {
+				processed.Mark(event.ID)
+			}
+		}(event)
+	}
+	wg.Wait()
+	return failures
+}
+
+// uniqueEvents removes each Event which has the same ID as one earlier in the batch.
+func uniqueEvents(events []Event) []Event {
+	seen := make(map[string]bool, len(events))
+	unique := make([]Event, 0, len(events))
+	for _, event := range events {
+		if seen[event.ID] {
+			continue
+		}
+		seen[event.ID] = true
+		unique = append(unique, event)
+	}
+	return unique
+}
+
+// respondToBatch reports the outcome of a batch to the Event Grid Topic that sent it. Should any Event have
+// failed, an HTTP 500 is returned so that the batch is redelivered, unless bestEffort is set. Otherwise,
+// successStatusCode is written, or an HTTP 200 when it is zero.
+func respondToBatch(c buffalo.Context, failures *BatchError, bestEffort bool, successStatusCode int) error {
+	if len(failures.Failures) > 0 {
+		if !bestEffort {
+			return c.Error(http.StatusInternalServerError, failures)
+		}
+		if logger := c.Logger(); logger != nil {
+			logger.Warn(failures, ", ignoring")
+		}
+	}
+
+	if successStatusCode == 0 {
+		successStatusCode = http.StatusOK
+	}
+	c.Response().WriteHeader(successStatusCode)
+	return nil
+}
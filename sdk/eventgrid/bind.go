@@ -0,0 +1,164 @@
+package eventgrid
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+)
+
+// DefaultMaxBodyBytes is the largest request body that will be read from an Event Grid Topic, unless
+// a subscriber is configured otherwise. It is MaxPayloadSize, as no larger batch is ever delivered.
+const DefaultMaxBodyBytes int64 = MaxPayloadSize
+
+// DefaultMaxEvents is the largest number of Events that will be accepted in a single batch, unless a subscriber is
+// configured otherwise. It matches the largest batch an Event Grid Topic can be configured to deliver.
+const DefaultMaxEvents = 5000
+
+// bindEvents reads the batch of Events in a request sent from an Event Grid Topic. When maxBytes is zero,
+// DefaultMaxBodyBytes is used. When it is negative, the size of the body is not limited. Bodies larger
+// than the limit result in ErrBodyTooLarge. Likewise, maxEvents limits the number of Events in the batch, defaulting
+// to DefaultMaxEvents, and batches with more result in ErrBatchTooLarge.
+//
+// A body which is empty, or only whitespace, is treated as an empty batch rather than as malformed. This allows
+// monitoring tools that send empty pings to be answered successfully. Any other body which is not a JSON array of
+// Events is reported as an error.
+//
+// Bodies compressed with gzip or deflate, as indicated by the "Content-Encoding" header, are decompressed. The
+// limit on their size applies after decompression. Other encodings result in ErrUnsupportedEncoding.
+//
+// The body is decoded one Event at a time, so that the JSON of each Event can be kept in `Event.Raw` without
+// holding onto the entire body as well.
+func bindEvents(c buffalo.Context, maxBytes int64, maxEvents int) ([]Event, error) {
+	req := c.Request()
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	if maxBytes == 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+
+	if maxEvents == 0 {
+		maxEvents = DefaultMaxEvents
+	}
+
+	var reader io.Reader
+	switch encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		reader = req.Body
+	case "gzip", "x-gzip":
+		decompressed, err := gzip.NewReader(req.Body)
+		if err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		defer decompressed.Close()
+		reader = decompressed
+	case "deflate":
+		decompressed, err := zlib.NewReader(req.Body)
+		if err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		defer decompressed.Close()
+		reader = decompressed
+	default:
+		return nil, ErrUnsupportedEncoding
+	}
+
+	var limited *maxBytesReader
+	if maxBytes > 0 {
+		limited = &maxBytesReader{Reader: reader, remaining: maxBytes}
+		reader = limited
+	}
+
+	events, err := decodeEvents(json.NewDecoder(reader), maxEvents)
+	if limited != nil && limited.remaining < 0 {
+		return nil, ErrBodyTooLarge
+	}
+	return events, err
+}
+
+// bindErrorStatus chooses the HTTP Status Code that is reported to an Event Grid Topic when bindEvents fails.
+func bindErrorStatus(err error) int {
+	switch err {
+	case ErrBodyTooLarge, ErrBatchTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrUnsupportedEncoding:
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// decodeEvents reads a JSON array of Events, keeping the JSON each was read from. Should the array hold more than
+// maxEvents, decoding stops with ErrBatchTooLarge. When maxEvents is negative, any number of Events is read.
+func decodeEvents(dec *json.Decoder, maxEvents int) ([]Event, error) {
+	start, err := dec.Token()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if start != json.Delim('[') {
+		return nil, errors.New("expected a JSON array of Events")
+	}
+
+	var events []Event
+	for dec.More() {
+		if maxEvents >= 0 && len(events) >= maxEvents {
+			return nil, ErrBatchTooLarge
+		}
+
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+
+		var event Event
+		if err = json.Unmarshal(raw, &event); err != nil {
+			return nil, err
+		}
+		event.Raw = raw
+
+		events = append(events, event)
+	}
+
+	if _, err = dec.Token(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// maxBytesReader reads from another `io.Reader`, returning ErrBodyTooLarge once more than a set number
+// of bytes have been read.
+type maxBytesReader struct {
+	io.Reader
+	remaining int64
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+
+	// Read one byte more than permitted, so that a body of exactly the maximum size can be told apart
+	// from one which is too large.
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+
+	n, err := r.Reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, ErrBodyTooLarge
+	}
+	return n, err
+}
@@ -24,7 +24,7 @@ func ExampleCache() {
 	myCache.Clear()
 	fmt.Println(myCache.List())
 	// Output:
-	// [{   [] Microsoft.Storage.BlobCreated   } {   [] Contoso.Buffalo.CacheProd   }]
+	// [{   [] Microsoft.Storage.BlobCreated    []} {   [] Contoso.Buffalo.CacheProd    []}]
 	// []
 }
 
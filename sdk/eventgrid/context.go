@@ -6,7 +6,6 @@ import (
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo/render"
-	"github.com/pkg/errors"
 )
 
 // SuccessStatusCodes returns an unordered list of HTTP Status Codes
@@ -25,12 +24,16 @@ var successStatusCodes = map[int]struct{}{
 // and an Event Grid Topic.
 type Context struct {
 	buffalo.Context
-	resp  *ResponseWriter
-	data  map[string]interface{}
-	flash buffalo.Flash
+	resp     *ResponseWriter
+	data     map[string]interface{}
+	flash    buffalo.Flash
+	recorder *statusRecorder
+	tx       Transaction
 }
 
-// NewContext initializes a new `eventgrid.Context`.
+// NewContext initializes a new `eventgrid.Context`. Should parent itself be an `eventgrid.Context`, the two
+// share a record of the Status Codes written for each Event, and any Transaction. See EventStatusCodes and
+// TransactionFrom for details.
 func NewContext(parent buffalo.Context) (created *Context) {
 	created = &Context{
 		Context: parent,
@@ -38,6 +41,13 @@ func NewContext(parent buffalo.Context) (created *Context) {
 		data:    make(map[string]interface{}, len(parent.Data())),
 	}
 
+	if egParent, ok := parent.(*Context); ok {
+		created.recorder = egParent.recorder
+		created.tx = egParent.tx
+	} else {
+		created.recorder = &statusRecorder{}
+	}
+
 	for k, v := range parent.Data() {
 		created.data[k] = v
 	}
@@ -45,6 +55,25 @@ func NewContext(parent buffalo.Context) (created *Context) {
 	return
 }
 
+// ForEvent initializes a new `eventgrid.Context` in which a Handler will process a single Event. The Status
+// Code written to it is reported by EventStatusCodes, keyed by the Event's ID.
+func (c *Context) ForEvent(event Event) *Context {
+	created := NewContext(c)
+	created.recorder.record(event.ID, created.resp)
+	return created
+}
+
+// EventStatusCodes gets the Status Code written while processing each Event, keyed by Event ID, for every
+// Context created by ForEvent from this Context, or from any Context sharing its record. An Event whose
+// Handler wrote no Status Code, which is treated as success, is reported as 0. Should the same Event ID
+// appear more than once, only the most recent Context created for it is reported.
+//
+// Because `TypeDispatchSubscriber.Receive` hands each Event to a Context created this way, a test may pass
+// an `eventgrid.Context` to Receive, then inspect exactly which Events failed.
+func (c *Context) EventStatusCodes() map[string]int {
+	return c.recorder.statusCodes()
+}
+
 // Response fulfills Buffalo's requirement to allow folks to write a response,
 // but it actually just throws away anything you write to it.
 func (c *Context) Response() http.ResponseWriter {
@@ -57,12 +86,14 @@ func (c *Context) ResponseHasFailure() bool {
 	return c.resp.HasFailure()
 }
 
+// Error publishes status to this Context's `ResponseWriter` and logs err, which is returned as is so that it can be
+// compared using `errors.Is` and `errors.As`.
 func (c *Context) Error(status int, err error) error {
 	c.resp.WriteHeader(status)
 	if logger := c.Logger(); logger != nil {
 		logger.Error(err)
 	}
-	return errors.WithStack(err)
+	return err
 }
 
 // Render discards the body that is populated by the renderer, but takes the status
@@ -86,10 +117,39 @@ func (c *Context) Redirect(status int, url string, args ...interface{}) error {
 	return nil
 }
 
+// statusRecorder tracks the ResponseWriter each Event was processed with, so that their Status Codes can be
+// reported together.
+type statusRecorder struct {
+	sync.Mutex
+	writers map[string]*ResponseWriter
+}
+
+func (r *statusRecorder) record(eventID string, w *ResponseWriter) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.writers == nil {
+		r.writers = make(map[string]*ResponseWriter)
+	}
+	r.writers[eventID] = w
+}
+
+func (r *statusRecorder) statusCodes() map[string]int {
+	r.Lock()
+	defer r.Unlock()
+
+	codes := make(map[string]int, len(r.writers))
+	for eventID, w := range r.writers {
+		codes[eventID] = w.StatusCode()
+	}
+	return codes
+}
+
 // ResponseWriter looks like an `http.ResponseWriter`, but
 type ResponseWriter struct {
 	sync.RWMutex
 	failureSeen bool
+	status      int
 	header      http.Header
 }
 
@@ -126,6 +186,15 @@ func (w *ResponseWriter) HasFailure() bool {
 	return w.failureSeen
 }
 
+// StatusCode gets the first Status Code written to this ResponseWriter that indicates failure. Should there
+// be none, the most recently written Status Code is returned, or 0 if none have been written.
+func (w *ResponseWriter) StatusCode() int {
+	w.RLock()
+	defer w.RUnlock()
+
+	return w.status
+}
+
 // SetFailure indicates that a Status Code outside of ones an Event Grid Topic
 // accepts as meaning not to retry was present in one of Handlers writing to this
 // ResponseWriter.
@@ -147,4 +216,10 @@ func (w *ResponseWriter) WriteHeader(s int) {
 	if _, ok := SuccessStatusCodes()[s]; !w.HasFailure() && !ok {
 		w.SetFailure()
 	}
+
+	w.Lock()
+	defer w.Unlock()
+	if _, ok := SuccessStatusCodes()[w.status]; ok || w.status == 0 {
+		w.status = s
+	}
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
 	"sync"
 	"testing"
 
@@ -52,6 +53,63 @@ func TestContext_ResponseWriter_Mask(t *testing.T) {
 	}
 }
 
+func TestContext_EventStatusCodes(t *testing.T) {
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+			switch e.ID {
+			case "1":
+				c.Response().WriteHeader(http.StatusCreated)
+			case "2":
+				return c.Error(http.StatusNotFound, errors.New("item not found"))
+			}
+			return nil
+		})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "2", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "3", "eventType": "Contoso.Items.ItemReceived", "data": {}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	ctx := eventgrid.NewContext(NewMockContext(req))
+	if err = subject.Receive(ctx); err == nil {
+		t.Log("expected an error")
+		t.Fail()
+	}
+
+	want := map[string]int{
+		"1": http.StatusCreated,
+		"2": http.StatusNotFound,
+		"3": 0,
+	}
+	if got := ctx.EventStatusCodes(); !reflect.DeepEqual(got, want) {
+		t.Logf("got: %v want: %v", got, want)
+		t.Fail()
+	}
+
+	if !ctx.ResponseHasFailure() {
+		t.Log("expected the batch to have been reported as a failure")
+		t.Fail()
+	}
+}
+
+func TestResponseWriter_StatusCode(t *testing.T) {
+	w := eventgrid.NewResponseWriter()
+	for _, status := range []int{http.StatusOK, http.StatusBadRequest, http.StatusCreated} {
+		w.WriteHeader(status)
+	}
+
+	if got := w.StatusCode(); got != http.StatusBadRequest {
+		t.Logf("got: %d want: %d", got, http.StatusBadRequest)
+		t.Fail()
+	}
+}
+
 type MockContext struct {
 	buffalo.Context
 	request *http.Request
@@ -0,0 +1,106 @@
+package eventgrid
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gobuffalo/buffalo"
+)
+
+// DeadLetterSink receives Events which could not be processed, along with the reason why.
+// Implementations may forward the Event to another Event Grid Topic, a Service Bus Queue,
+// or anywhere else it can be inspected later.
+type DeadLetterSink interface {
+	DeadLetter(c buffalo.Context, e Event, reason error) error
+}
+
+// DeadLetterSinkFunc allows a function to be used as a `DeadLetterSink`.
+type DeadLetterSinkFunc func(c buffalo.Context, e Event, reason error) error
+
+// DeadLetter calls the underlying function.
+func (f DeadLetterSinkFunc) DeadLetter(c buffalo.Context, e Event, reason error) error {
+	return f(c, e, reason)
+}
+
+// DeadLetterSubscriber hands each Event in a batch to a `Dispatcher`. Events which fail to be
+// processed are given to a `DeadLetterSink` instead of causing the whole batch to fail, so that
+// an Event Grid Topic will not redeliver them.
+type DeadLetterSubscriber struct {
+	Subscriber
+	Dispatcher
+	Sink DeadLetterSink
+
+	// MaxBodyBytes is the largest request body Receive will read. When it is zero, DefaultMaxBodyBytes
+	// is used. When it is negative, the size of the body is not limited.
+	MaxBodyBytes int64
+
+	// MaxEvents is the largest number of Events Receive will accept in a batch. When it is zero,
+	// DefaultMaxEvents is used. When it is negative, the number of Events is not limited.
+	MaxEvents int
+}
+
+// NewDeadLetterSubscriber initializes a DeadLetterSubscriber which dispatches Events using
+// dispatcher, and defers to parent for all actions other than "Receive".
+func NewDeadLetterSubscriber(parent Subscriber, dispatcher Dispatcher, sink DeadLetterSink) *DeadLetterSubscriber {
+	return &DeadLetterSubscriber{
+		Subscriber: parent,
+		Dispatcher: dispatcher,
+		Sink:       sink,
+	}
+}
+
+// Receive is a `buffalo.Handler` which dispatches each event in a batch sent from an Event Grid Topic.
+// Each Event that fails to be processed is handed to the `DeadLetterSink`. Only when an Event can't be
+// handed to the `DeadLetterSink` will this handler respond with an HTTP 500, causing the Event Grid
+// Topic to redeliver the batch.
+func (s DeadLetterSubscriber) Receive(c buffalo.Context) error {
+	events, err := bindEvents(c, s.MaxBodyBytes, s.MaxEvents)
+	if err != nil {
+		return c.Error(bindErrorStatus(err), err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failures := &BatchError{}
+
+	for _, event := range events {
+		wg.Add(1)
+		go func(event Event) {
+			defer wg.Done()
+
+			ctx := NewContext(c)
+			err := s.Dispatch(ctx, event)
+			if err == nil && ctx.ResponseHasFailure() {
+				err = ErrHandlerFailed
+			}
+
+			if err == nil {
+				return
+			}
+
+			if logger := c.Logger(); logger != nil {
+				logger.Warnf("dead-lettering event %q: %v", event.ID, err)
+			}
+
+			if s.Sink == nil {
+				err = fmt.Errorf("no DeadLetterSink configured: %v", err)
+			} else if sinkErr := s.Sink.DeadLetter(c, event, err); sinkErr != nil {
+				err = fmt.Errorf("unable to dead-letter: %v: %v", sinkErr, err)
+			} else {
+				return
+			}
+
+			mu.Lock()
+			failures.Failures = append(failures.Failures, EventError{Event: event, Err: err})
+			mu.Unlock()
+		}(event)
+	}
+	wg.Wait()
+
+	if len(failures.Failures) > 0 {
+		return c.Error(http.StatusInternalServerError, failures)
+	}
+	c.Response().WriteHeader(http.StatusOK)
+	return nil
+}
@@ -0,0 +1,93 @@
+package eventgrid_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Azure/buffalo-azure/sdk/eventgrid"
+	"github.com/gobuffalo/buffalo"
+)
+
+func TestDeadLetterSubscriber_Receive(t *testing.T) {
+	dispatcher := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+	dispatcher.Bind("Contoso.Succeed", func(c buffalo.Context, e eventgrid.Event) error {
+		c.Response().WriteHeader(http.StatusOK)
+		return nil
+	})
+	dispatcher.Bind("Contoso.Fail", func(c buffalo.Context, e eventgrid.Event) error {
+		return c.Error(http.StatusInternalServerError, errors.New("unable to process event"))
+	})
+
+	var mu sync.Mutex
+	deadLettered := make(map[string]error)
+	sink := eventgrid.DeadLetterSinkFunc(func(c buffalo.Context, e eventgrid.Event, reason error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deadLettered[e.ID] = reason
+		return nil
+	})
+
+	subject := eventgrid.NewDeadLetterSubscriber(eventgrid.BaseSubscriber{}, dispatcher, sink)
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "eventType": "Contoso.Succeed", "data": {}},
+	{"id": "2", "eventType": "Contoso.Fail", "data": {}},
+	{"id": "3", "eventType": "Contoso.Unbound", "data": {}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	ctx := NewMockContext(req)
+
+	if err = subject.Receive(ctx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := ctx.Status(); got != http.StatusOK {
+		t.Logf("got status: %d want: %d", got, http.StatusOK)
+		t.Fail()
+	}
+
+	if len(deadLettered) != 2 {
+		t.Logf("got %d dead-lettered events, want 2", len(deadLettered))
+		t.Fail()
+	}
+
+	for _, id := range []string{"2", "3"} {
+		if reason, ok := deadLettered[id]; !ok || reason == nil {
+			t.Logf("expected event %q to be dead-lettered with a reason", id)
+			t.Fail()
+		}
+	}
+}
+
+func TestDeadLetterSubscriber_Receive_SinkFailure(t *testing.T) {
+	dispatcher := eventgrid.EventHandler(func(c buffalo.Context, e eventgrid.Event) error {
+		return c.Error(http.StatusInternalServerError, errors.New("unable to process event"))
+	})
+
+	sink := eventgrid.DeadLetterSinkFunc(func(c buffalo.Context, e eventgrid.Event, reason error) error {
+		return errors.New("sink unavailable")
+	})
+
+	subject := eventgrid.NewDeadLetterSubscriber(eventgrid.BaseSubscriber{}, dispatcher, sink)
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[{"id": "1", "eventType": "Contoso.Fail", "data": {}}]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	if err = subject.Receive(NewMockContext(req)); err == nil {
+		t.Log("expected an error when the sink can't accept an event")
+		t.Fail()
+	}
+}
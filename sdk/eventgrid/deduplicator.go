@@ -0,0 +1,159 @@
+package eventgrid
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DeduplicatorDefaultMaxSize is the maximum number of Event IDs that will be
+// remembered, before the least recently seen begin to be forgotten.
+const DeduplicatorDefaultMaxSize uint = 10000
+
+// DeduplicatorDefaultTTL is the default length of time that an Event ID will be
+// remembered. It matches the length of time Event Grid will retry delivering an Event.
+const DeduplicatorDefaultTTL = time.Hour * 24
+
+// Deduplicator remembers the IDs of recently seen Events, so that Events which are
+// delivered more than once can be ignored. The zero value is ready to use.
+type Deduplicator struct {
+	sync.Mutex
+	maxSize uint
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type deduplicatorEntry struct {
+	id         string
+	expiration time.Time
+}
+
+// MaxSize gets the largest number of Event IDs that this `Deduplicator` will remember.
+func (d *Deduplicator) MaxSize() uint {
+	d.Lock()
+	defer d.Unlock()
+
+	return d._MaxSize()
+}
+
+func (d *Deduplicator) _MaxSize() uint {
+	if d.maxSize == 0 {
+		return DeduplicatorDefaultMaxSize
+	}
+	return d.maxSize
+}
+
+// SetMaxSize changes the largest number of Event IDs that this `Deduplicator` will remember.
+func (d *Deduplicator) SetMaxSize(size uint) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.maxSize = size
+	d.evict(time.Now())
+}
+
+// TTL gets the amount of time each Event ID will be remembered.
+func (d *Deduplicator) TTL() time.Duration {
+	d.Lock()
+	defer d.Unlock()
+
+	return d._TTL()
+}
+
+func (d *Deduplicator) _TTL() time.Duration {
+	if d.ttl <= 0 {
+		return DeduplicatorDefaultTTL
+	}
+	return d.ttl
+}
+
+// SetTTL sets the amount of time each Event ID will be remembered.
+func (d *Deduplicator) SetTTL(ttl time.Duration) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.ttl = ttl
+}
+
+// Seen reports whether an Event with the given ID has been seen recently. If it has not,
+// the ID is remembered so that subsequent calls will report true. It is equivalent to calling
+// Contains, then Mark, as one step.
+//
+// Because the ID is remembered before the Event is processed, an Event which then fails would be
+// skipped when it is redelivered. Subscribers instead call Contains before processing an Event,
+// and Mark only once it has succeeded.
+func (d *Deduplicator) Seen(id string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	now := time.Now()
+	if d.contains(id, now) {
+		return true
+	}
+	d.mark(id, now)
+	return false
+}
+
+// Contains reports whether an Event with the given ID has been seen recently, without remembering it.
+func (d *Deduplicator) Contains(id string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.contains(id, time.Now())
+}
+
+// Mark remembers the ID of an Event, so that Contains and Seen report true for it until it is forgotten.
+func (d *Deduplicator) Mark(id string) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.mark(id, time.Now())
+}
+
+func (d *Deduplicator) contains(id string, now time.Time) bool {
+	existing, ok := d.entries[id]
+	if !ok {
+		return false
+	}
+
+	if existing.Value.(*deduplicatorEntry).expiration.After(now) {
+		d.order.MoveToFront(existing)
+		return true
+	}
+	d.order.Remove(existing)
+	delete(d.entries, id)
+	return false
+}
+
+func (d *Deduplicator) mark(id string, now time.Time) {
+	if d.entries == nil {
+		d.entries = make(map[string]*list.Element)
+		d.order = list.New()
+	}
+
+	if existing, ok := d.entries[id]; ok {
+		d.order.Remove(existing)
+	}
+	d.entries[id] = d.order.PushFront(&deduplicatorEntry{
+		id:         id,
+		expiration: now.Add(d._TTL()),
+	})
+	d.evict(now)
+}
+
+// evict removes expired entries, and the least recently seen entries beyond the maximum size.
+func (d *Deduplicator) evict(now time.Time) {
+	if d.order == nil {
+		return
+	}
+
+	for oldest := d.order.Back(); oldest != nil; oldest = d.order.Back() {
+		entry := oldest.Value.(*deduplicatorEntry)
+		if uint(d.order.Len()) <= d._MaxSize() && entry.expiration.After(now) {
+			break
+		}
+		d.order.Remove(oldest)
+		delete(d.entries, entry.id)
+	}
+}
@@ -0,0 +1,64 @@
+package eventgrid_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Azure/buffalo-azure/sdk/eventgrid"
+)
+
+func ExampleDeduplicator() {
+	myDedup := &eventgrid.Deduplicator{}
+
+	fmt.Println(myDedup.Seen("831e1650-001e-001b-66ab-eeb76e069631"))
+	fmt.Println(myDedup.Seen("831e1650-001e-001b-66ab-eeb76e069631"))
+	fmt.Println(myDedup.Seen("6d79dbfb-0e37-4fc4-981f-442c9ca65760"))
+
+	// Output:
+	// false
+	// true
+	// false
+}
+
+func ExampleDeduplicator_SetTTL() {
+	myDedup := &eventgrid.Deduplicator{}
+	myDedup.SetTTL(time.Second)
+
+	fmt.Println(myDedup.Seen("1"))
+	<-time.After(2 * time.Second)
+	fmt.Println(myDedup.Seen("1"))
+
+	// Output:
+	// false
+	// false
+}
+
+func ExampleDeduplicator_SetMaxSize() {
+	myDedup := &eventgrid.Deduplicator{}
+	myDedup.SetMaxSize(2)
+
+	fmt.Println(myDedup.Seen("1"))
+	fmt.Println(myDedup.Seen("2"))
+	fmt.Println(myDedup.Seen("3"))
+	fmt.Println(myDedup.Seen("1"))
+
+	// Output:
+	// false
+	// false
+	// false
+	// false
+}
+
+func ExampleDeduplicator_Mark() {
+	myDedup := &eventgrid.Deduplicator{}
+
+	fmt.Println(myDedup.Contains("1"))
+	fmt.Println(myDedup.Contains("1"))
+	myDedup.Mark("1")
+	fmt.Println(myDedup.Contains("1"))
+
+	// Output:
+	// false
+	// false
+	// true
+}
@@ -0,0 +1,38 @@
+package eventgrid
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+)
+
+// endpointMethods are the HTTP methods that a subscriber's endpoint responds to. Event Grid delivers Events
+// with POST, and sends the WebHook validation handshake with OPTIONS.
+var endpointMethods = []string{http.MethodOptions, http.MethodPost}
+
+// handleEndpoint routes a request sent to a subscriber's endpoint by its HTTP method. POST requests are
+// handed to post, OPTIONS requests are answered by ReceiveWebHookValidationRequest, and any other method
+// is rejected with an HTTP 405 Status Code.
+func handleEndpoint(c buffalo.Context, post buffalo.Handler) error {
+	switch c.Request().Method {
+	case http.MethodPost:
+		return post(c)
+	case http.MethodOptions:
+		return ReceiveWebHookValidationRequest(c)
+	default:
+		c.Response().Header().Set("Allow", strings.Join(endpointMethods, ", "))
+		return c.Error(http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+	}
+}
+
+// mountEndpoint registers handler for each HTTP method a client might reasonably send to path, so that
+// methods other than those in endpointMethods receive an HTTP 405, rather than an HTTP 404.
+func mountEndpoint(app *buffalo.App, path string, handler buffalo.Handler) *buffalo.RouteInfo {
+	app.OPTIONS(path, handler)
+	app.GET(path, handler)
+	app.PUT(path, handler)
+	app.PATCH(path, handler)
+	app.DELETE(path, handler)
+	return app.POST(path, handler)
+}
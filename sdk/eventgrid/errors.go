@@ -0,0 +1,197 @@
+package eventgrid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// These errors identify the broad reasons a request from an Event Grid Topic could not be processed. The errors
+// returned by this package can be compared against them using `errors.Is`.
+var (
+	// ErrNoHandler indicates that an Event arrived with a type that no Handler was bound to. This is a client error,
+	// which is reported to the Event Grid Topic with an HTTP 400 Status Code.
+	ErrNoHandler = errors.New("no Handler found")
+
+	// ErrHandlerFailed is the reason given when a Handler responded with a Status Code indicating
+	// failure, but did not return an error.
+	ErrHandlerFailed = errors.New("handler responded with a Status Code indicating failure")
+
+	// ErrBodyTooLarge indicates that a request body was larger than a subscriber is willing to read.
+	// It is reported to the Event Grid Topic with an HTTP 413 Status Code.
+	ErrBodyTooLarge = errors.New("request body too large")
+
+	// ErrBatchTooLarge indicates that a request held more Events than a subscriber is willing to process.
+	// It is reported to the Event Grid Topic with an HTTP 413 Status Code.
+	ErrBatchTooLarge = errors.New("batch contains too many events")
+
+	// ErrUnsupportedEncoding indicates that a request body was compressed in a way that can't be read.
+	// It is reported to the Event Grid Topic with an HTTP 415 Status Code.
+	ErrUnsupportedEncoding = errors.New("unsupported Content-Encoding")
+
+	// ErrDuplicateBinding indicates that a Handler was already bound to an Event Type.
+	ErrDuplicateBinding = errors.New("a Handler is already bound")
+
+	// ErrValidationFailed indicates that a subscription validation request could not be answered.
+	ErrValidationFailed = errors.New("subscription validation failed")
+
+	// ErrMethodNotAllowed indicates that a request was sent to a subscriber's endpoint with an HTTP method
+	// other than POST or OPTIONS. It is reported with an HTTP 405 Status Code.
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
+// NoHandlerError is returned when an Event can't be dispatched because no Handler is bound to its type. When the
+// Event was routed by its subject, rather than its type, Subject is populated.
+type NoHandlerError struct {
+	EventType string
+	Subject   string
+}
+
+func (e NoHandlerError) Error() string {
+	if e.Subject != "" {
+		return fmt.Sprintf("no Handler found for subject %q", e.Subject)
+	}
+	return fmt.Sprintf("no Handler found for type %q", e.EventType)
+}
+
+// Is allows `errors.Is` to match a NoHandlerError against ErrNoHandler.
+func (e NoHandlerError) Is(target error) bool {
+	return target == ErrNoHandler
+}
+
+// DuplicateBindingError is returned by `TypeDispatchSubscriber.BindOnce` when a Handler is already
+// bound to an Event Type.
+type DuplicateBindingError struct {
+	EventType string
+}
+
+func (e DuplicateBindingError) Error() string {
+	return fmt.Sprintf("a Handler is already bound to type %q", e.EventType)
+}
+
+// Is allows `errors.Is` to match a DuplicateBindingError against ErrDuplicateBinding.
+func (e DuplicateBindingError) Is(target error) bool {
+	return target == ErrDuplicateBinding
+}
+
+// ValidationError is returned when a subscription validation request is malformed.
+type ValidationError struct {
+	Err error
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrValidationFailed, e.Err)
+}
+
+// Is allows `errors.Is` to match a ValidationError against ErrValidationFailed.
+func (e ValidationError) Is(target error) bool {
+	return target == ErrValidationFailed
+}
+
+// Unwrap returns the reason validation failed.
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// HandlerErrors collects the errors returned by each EventHandler that failed to process an Event,
+// when a `TypeDispatchSubscriber` hands the Event to more than one.
+type HandlerErrors []error
+
+func (e HandlerErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is allows `errors.Is` to match HandlerErrors against anything one of the errors matches.
+func (e HandlerErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As allows `errors.As` to find target in the first of the errors that holds one.
+func (e HandlerErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// EventError describes why a single Event in a batch could not be processed.
+type EventError struct {
+	Event Event
+	Err   error
+}
+
+func (e EventError) Error() string {
+	return fmt.Sprintf("unable to process event %q: %v", e.Event.ID, e.Err)
+}
+
+// Unwrap returns the reason the Event could not be processed.
+func (e EventError) Unwrap() error {
+	return e.Err
+}
+
+// MaxReportedFailures is the largest number of failed Events described by the message of a BatchError. The rest are
+// summarized, so that the message stays a reasonable size however large the batch was.
+const MaxReportedFailures = 10
+
+// BatchError is returned when at least one Event in a batch could not be processed, causing the Event Grid Topic to
+// redeliver the batch. It holds one EventError per failed Event.
+type BatchError struct {
+	Failures []EventError
+}
+
+func (e *BatchError) Error() string {
+	var summary string
+	if len(e.Failures) == 1 {
+		summary = "1 event in this batch failed to be processed"
+	} else {
+		summary = fmt.Sprintf("%d events in this batch failed to be processed", len(e.Failures))
+	}
+
+	reported := e.Failures
+	if len(reported) > MaxReportedFailures {
+		reported = reported[:MaxReportedFailures]
+	}
+	if len(reported) == 0 {
+		return summary
+	}
+
+	details := make([]string, 0, len(reported)+1)
+	for _, failure := range reported {
+		details = append(details, failure.Error())
+	}
+	if remaining := len(e.Failures) - len(reported); remaining > 0 {
+		details = append(details, fmt.Sprintf("and %d more", remaining))
+	}
+	return summary + ": " + strings.Join(details, "; ")
+}
+
+// Is allows `errors.Is` to match a BatchError against anything one of its per-Event failures matches.
+func (e *BatchError) Is(target error) bool {
+	for _, failure := range e.Failures {
+		if errors.Is(failure, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As allows `errors.As` to find target in the first of the per-Event failures that holds one.
+func (e *BatchError) As(target interface{}) bool {
+	for _, failure := range e.Failures {
+		if errors.As(failure, target) {
+			return true
+		}
+	}
+	return false
+}
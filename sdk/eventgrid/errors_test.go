@@ -0,0 +1,128 @@
+package eventgrid_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Azure/buffalo-azure/sdk/eventgrid"
+)
+
+func TestTypeDispatchSubscriber_Dispatch_NoHandler(t *testing.T) {
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := eventgrid.NewContext(NewMockContext(req))
+	err = subject.Dispatch(ctx, eventgrid.Event{ID: "1", EventType: "Contoso.Items.ItemLost"})
+
+	if !errors.Is(err, eventgrid.ErrNoHandler) {
+		t.Logf("got error: %#v want ErrNoHandler", err)
+		t.Fail()
+	}
+
+	var noHandler eventgrid.NoHandlerError
+	if !errors.As(err, &noHandler) || noHandler.EventType != "Contoso.Items.ItemLost" {
+		t.Logf("got error: %#v want a NoHandlerError", err)
+		t.Fail()
+	}
+
+	if !ctx.ResponseHasFailure() {
+		t.Log("expected a failing status to be written")
+		t.Fail()
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	var err error = &eventgrid.BatchError{
+		Failures: []eventgrid.EventError{
+			{Event: eventgrid.Event{ID: "1"}, Err: eventgrid.ErrHandlerFailed},
+			{Event: eventgrid.Event{ID: "2"}, Err: eventgrid.NoHandlerError{EventType: "Contoso.Items.ItemLost"}},
+		},
+	}
+
+	if !errors.Is(err, eventgrid.ErrNoHandler) {
+		t.Log("expected a BatchError to match ErrNoHandler")
+		t.Fail()
+	}
+
+	if !errors.Is(err, eventgrid.ErrHandlerFailed) {
+		t.Log("expected a BatchError to match ErrHandlerFailed")
+		t.Fail()
+	}
+
+	var eventErr eventgrid.EventError
+	if !errors.As(err, &eventErr) || eventErr.Event.ID != "1" {
+		t.Logf("got: %v want the failure of event 1", eventErr)
+		t.Fail()
+	}
+
+	var noHandler eventgrid.NoHandlerError
+	if !errors.As(err, &noHandler) || noHandler.EventType != "Contoso.Items.ItemLost" {
+		t.Logf("got: %v want the NoHandlerError of event 2", noHandler)
+		t.Fail()
+	}
+
+	if errors.Is(err, eventgrid.ErrValidationFailed) {
+		t.Log("a BatchError should not match ErrValidationFailed")
+		t.Fail()
+	}
+}
+
+func TestBatchError_Error(t *testing.T) {
+	single := &eventgrid.BatchError{
+		Failures: []eventgrid.EventError{
+			{Event: eventgrid.Event{ID: "1"}, Err: eventgrid.ErrHandlerFailed},
+		},
+	}
+	if got, want := single.Error(), `1 event in this batch failed to be processed: unable to process event "1": `+eventgrid.ErrHandlerFailed.Error(); got != want {
+		t.Logf("\ngot:  %s\nwant: %s", got, want)
+		t.Fail()
+	}
+
+	many := &eventgrid.BatchError{}
+	for i := 0; i < eventgrid.MaxReportedFailures+5; i++ {
+		many.Failures = append(many.Failures, eventgrid.EventError{
+			Event: eventgrid.Event{ID: strconv.Itoa(i)},
+			Err:   eventgrid.ErrHandlerFailed,
+		})
+	}
+
+	got := many.Error()
+	if want := fmt.Sprintf("%d events in this batch failed to be processed: ", len(many.Failures)); !strings.HasPrefix(got, want) {
+		t.Logf("got: %q want prefix: %q", got, want)
+		t.Fail()
+	}
+
+	if count := strings.Count(got, "unable to process event"); count != eventgrid.MaxReportedFailures {
+		t.Logf("got %d failures described, want %d", count, eventgrid.MaxReportedFailures)
+		t.Fail()
+	}
+
+	if !strings.HasSuffix(got, "; and 5 more") {
+		t.Logf("got: %q want a summary of the remaining failures", got)
+		t.Fail()
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	reason := errors.New("malformed validation code")
+	var err error = eventgrid.ValidationError{Err: reason}
+
+	if !errors.Is(err, eventgrid.ErrValidationFailed) {
+		t.Log("expected a ValidationError to match ErrValidationFailed")
+		t.Fail()
+	}
+
+	if !errors.Is(err, reason) {
+		t.Log("expected a ValidationError to match its reason")
+		t.Fail()
+	}
+}
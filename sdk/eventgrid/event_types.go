@@ -0,0 +1,47 @@
+package eventgrid
+
+// These constants identify the Event Types published by first-party Azure services, so that
+// they can be handed to `TypeDispatchSubscriber.Bind` without risk of a typo. For the complete
+// and current list, see: https://docs.microsoft.com/en-us/azure/event-grid/event-schema
+const (
+	// Azure Blob Storage
+	StorageBlobCreated = "Microsoft.Storage.BlobCreated"
+	StorageBlobDeleted = "Microsoft.Storage.BlobDeleted"
+
+	// Azure Resource Manager, for both Subscriptions and Resource Groups
+	ResourceWriteSuccess  = "Microsoft.Resources.ResourceWriteSuccess"
+	ResourceWriteFailure  = "Microsoft.Resources.ResourceWriteFailure"
+	ResourceWriteCancel   = "Microsoft.Resources.ResourceWriteCancel"
+	ResourceDeleteSuccess = "Microsoft.Resources.ResourceDeleteSuccess"
+	ResourceDeleteFailure = "Microsoft.Resources.ResourceDeleteFailure"
+	ResourceDeleteCancel  = "Microsoft.Resources.ResourceDeleteCancel"
+
+	// Azure Key Vault
+	KeyVaultCertificateNewVersionCreated = "Microsoft.KeyVault.CertificateNewVersionCreated"
+	KeyVaultCertificateNearExpiry        = "Microsoft.KeyVault.CertificateNearExpiry"
+	KeyVaultCertificateExpired           = "Microsoft.KeyVault.CertificateExpired"
+	KeyVaultKeyNewVersionCreated         = "Microsoft.KeyVault.KeyNewVersionCreated"
+	KeyVaultKeyNearExpiry                = "Microsoft.KeyVault.KeyNearExpiry"
+	KeyVaultKeyExpired                   = "Microsoft.KeyVault.KeyExpired"
+	KeyVaultSecretNewVersionCreated      = "Microsoft.KeyVault.SecretNewVersionCreated"
+	KeyVaultSecretNearExpiry             = "Microsoft.KeyVault.SecretNearExpiry"
+	KeyVaultSecretExpired                = "Microsoft.KeyVault.SecretExpired"
+
+	// Azure Service Bus
+	ServiceBusActiveMessagesAvailableWithNoListeners    = "Microsoft.ServiceBus.ActiveMessagesAvailableWithNoListeners"
+	ServiceBusDeadletterMessagesAvailableWithNoListener = "Microsoft.ServiceBus.DeadletterMessagesAvailableWithNoListener"
+
+	// Azure Media Services
+	MediaJobStateChange    = "Microsoft.Media.JobStateChange"
+	MediaJobScheduled      = "Microsoft.Media.JobScheduled"
+	MediaJobProcessing     = "Microsoft.Media.JobProcessing"
+	MediaJobCanceling      = "Microsoft.Media.JobCanceling"
+	MediaJobFinished       = "Microsoft.Media.JobFinished"
+	MediaJobCanceled       = "Microsoft.Media.JobCanceled"
+	MediaJobErrored        = "Microsoft.Media.JobErrored"
+	MediaJobOutputProgress = "Microsoft.Media.JobOutputProgress"
+
+	// Azure Event Grid
+	EventGridSubscriptionValidation = "Microsoft.EventGrid.SubscriptionValidationEvent"
+	EventGridSubscriptionDeleted    = "Microsoft.EventGrid.SubscriptionDeletedEvent"
+)
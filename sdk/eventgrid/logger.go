@@ -0,0 +1,16 @@
+package eventgrid
+
+import (
+	"io/ioutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+// discardLogger is used by subscribers which have not been given a logger, so that they are quiet by default.
+var discardLogger logrus.FieldLogger = newDiscardLogger()
+
+func newDiscardLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	return logger
+}
@@ -0,0 +1,67 @@
+package eventgrid
+
+// ProcessedStore remembers the IDs of Events which have been processed successfully. Because an Event Grid
+// Topic redelivers an entire batch when any Event in it fails, a subscriber consults a ProcessedStore to
+// skip the Events in a redelivered batch that already succeeded. Only IDs of Events whose Handlers succeeded
+// are marked, so failed Events are still retried.
+//
+// Together with Event Grid's retries, this approximates at-least-once delivery with deduplication. An Event
+// may still be processed more than once, for instance when the process stops between a Handler finishing
+// and its Event being marked, so Handlers should remain idempotent.
+type ProcessedStore interface {
+	// Seen reports whether the Event with the given ID has been marked as processed.
+	Seen(id string) bool
+
+	// Mark records that the Event with the given ID was processed successfully.
+	Mark(id string)
+}
+
+// MemoryProcessedStore is a ProcessedStore which keeps Event IDs in memory. Should a subscriber be scaled
+// beyond one instance, a ProcessedStore shared between them should be used instead, as redelivered batches
+// may arrive at any instance. Entries are forgotten according to the rules of `Deduplicator`. The zero
+// value is ready to use.
+type MemoryProcessedStore struct {
+	ids Deduplicator
+}
+
+// Seen reports whether the Event with the given ID has been marked as processed, and not yet forgotten.
+func (m *MemoryProcessedStore) Seen(id string) bool {
+	return m.ids.Contains(id)
+}
+
+// Mark records that the Event with the given ID was processed successfully.
+func (m *MemoryProcessedStore) Mark(id string) {
+	m.ids.Mark(id)
+}
+
+// dedupStore lets a Deduplicator be consulted, and updated, in the same way as a ProcessedStore.
+type dedupStore struct {
+	ids *Deduplicator
+}
+
+func (d dedupStore) Seen(id string) bool {
+	return d.ids.Contains(id)
+}
+
+func (d dedupStore) Mark(id string) {
+	d.ids.Mark(id)
+}
+
+// processedStores is a ProcessedStore which reports an Event as seen when any of its members has seen it,
+// and marks Events in all of them.
+type processedStores []ProcessedStore
+
+func (p processedStores) Seen(id string) bool {
+	for _, store := range p {
+		if store.Seen(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p processedStores) Mark(id string) {
+	for _, store := range p {
+		store.Mark(id)
+	}
+}
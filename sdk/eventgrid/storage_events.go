@@ -0,0 +1,85 @@
+package eventgrid
+
+import (
+	"fmt"
+
+	"github.com/gobuffalo/buffalo"
+)
+
+// StorageBlobCreatedData is the payload of an Event with type StorageBlobCreated. For the complete schema, see:
+// https://docs.microsoft.com/en-us/azure/event-grid/event-schema-blob-storage
+type StorageBlobCreatedData struct {
+	API             string `json:"api"`
+	ClientRequestID string `json:"clientRequestId"`
+	RequestID       string `json:"requestId"`
+	ETag            string `json:"eTag"`
+	ContentType     string `json:"contentType"`
+	ContentLength   int64  `json:"contentLength"`
+	BlobType        string `json:"blobType"`
+	URL             string `json:"url"`
+	Sequencer       string `json:"sequencer"`
+}
+
+// StorageBlobDeletedData is the payload of an Event with type StorageBlobDeleted. For the complete schema, see:
+// https://docs.microsoft.com/en-us/azure/event-grid/event-schema-blob-storage
+type StorageBlobDeletedData struct {
+	API             string `json:"api"`
+	ClientRequestID string `json:"clientRequestId"`
+	RequestID       string `json:"requestId"`
+	ContentType     string `json:"contentType"`
+	BlobType        string `json:"blobType"`
+	URL             string `json:"url"`
+	Sequencer       string `json:"sequencer"`
+}
+
+// StorageBlobCreatedHandler processes a StorageBlobCreated Event, along with its decoded payload.
+type StorageBlobCreatedHandler func(buffalo.Context, Event, StorageBlobCreatedData) error
+
+// StorageBlobDeletedHandler processes a StorageBlobDeleted Event, along with its decoded payload.
+type StorageBlobDeletedHandler func(buffalo.Context, Event, StorageBlobDeletedData) error
+
+// BlobCreated reads the payload of a StorageBlobCreated Event. An error is returned if the Event has any other type,
+// or if its payload is malformed.
+func BlobCreated(e Event) (data StorageBlobCreatedData, err error) {
+	if e.EventType != StorageBlobCreated {
+		err = fmt.Errorf("expected an Event with type %q, got %q", StorageBlobCreated, e.EventType)
+		return
+	}
+	err = e.UnmarshalData(&data)
+	return
+}
+
+// BlobDeleted reads the payload of a StorageBlobDeleted Event. An error is returned if the Event has any other type,
+// or if its payload is malformed.
+func BlobDeleted(e Event) (data StorageBlobDeletedData, err error) {
+	if e.EventType != StorageBlobDeleted {
+		err = fmt.Errorf("expected an Event with type %q, got %q", StorageBlobDeleted, e.EventType)
+		return
+	}
+	err = e.UnmarshalData(&data)
+	return
+}
+
+// HandleBlobCreated creates an EventHandler, suitable for binding to StorageBlobCreated, which decodes each Event's
+// payload before handing it to h. Events which can't be decoded are rejected without h being called.
+func HandleBlobCreated(h StorageBlobCreatedHandler) EventHandler {
+	return func(c buffalo.Context, e Event) error {
+		data, err := BlobCreated(e)
+		if err != nil {
+			return err
+		}
+		return h(c, e, data)
+	}
+}
+
+// HandleBlobDeleted creates an EventHandler, suitable for binding to StorageBlobDeleted, which decodes each Event's
+// payload before handing it to h. Events which can't be decoded are rejected without h being called.
+func HandleBlobDeleted(h StorageBlobDeletedHandler) EventHandler {
+	return func(c buffalo.Context, e Event) error {
+		data, err := BlobDeleted(e)
+		if err != nil {
+			return err
+		}
+		return h(c, e, data)
+	}
+}
@@ -0,0 +1,80 @@
+package eventgrid_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/buffalo-azure/sdk/eventgrid"
+	"github.com/gobuffalo/buffalo"
+)
+
+func TestBlobCreated(t *testing.T) {
+	e := eventgrid.Event{
+		EventType: eventgrid.StorageBlobCreated,
+		Data: json.RawMessage(`{
+	"api": "PutBlob",
+	"eTag": "0x8D4BCC2E4835CD0",
+	"contentType": "image/png",
+	"contentLength": 524288,
+	"blobType": "BlockBlob",
+	"url": "https://contoso.blob.core.windows.net/images/cat.png"
+}`),
+	}
+
+	data, err := eventgrid.BlobCreated(e)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if want := "https://contoso.blob.core.windows.net/images/cat.png"; data.URL != want {
+		t.Logf("got URL: %q want: %q", data.URL, want)
+		t.Fail()
+	}
+
+	if want := int64(524288); data.ContentLength != want {
+		t.Logf("got content length: %d want: %d", data.ContentLength, want)
+		t.Fail()
+	}
+
+	if want := "0x8D4BCC2E4835CD0"; data.ETag != want {
+		t.Logf("got ETag: %q want: %q", data.ETag, want)
+		t.Fail()
+	}
+
+	e.EventType = eventgrid.StorageBlobDeleted
+	if _, err = eventgrid.BlobCreated(e); err == nil {
+		t.Log("expected an error when the Event has another type")
+		t.Fail()
+	}
+}
+
+func TestHandleBlobDeleted(t *testing.T) {
+	var seen string
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		Bind(eventgrid.StorageBlobDeleted, eventgrid.HandleBlobDeleted(func(c buffalo.Context, e eventgrid.Event, data eventgrid.StorageBlobDeletedData) error {
+			seen = data.URL
+			return nil
+		}))
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "eventType": "Microsoft.Storage.BlobDeleted", "data": {"url": "https://contoso.blob.core.windows.net/images/cat.png"}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	if err = subject.Receive(NewMockContext(req)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if want := "https://contoso.blob.core.windows.net/images/cat.png"; seen != want {
+		t.Logf("got URL: %q want: %q", seen, want)
+		t.Fail()
+	}
+}
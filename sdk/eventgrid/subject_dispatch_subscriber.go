@@ -0,0 +1,181 @@
+package eventgrid
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/sirupsen/logrus"
+)
+
+// SubjectDispatchSubscriber offers an indirection for calling a function when an Event Grid Event has a
+// `subject` matching a particular pattern. For instance, Events about blobs in a particular container of
+// a Storage Account can be routed by their path:
+//
+//	s.BindPrefix("/blobServices/default/containers/images/", handleImage)
+//	s.Bind("/blobServices/default/containers/*/blobs/*.json", handleJSON)
+//
+// Batches are processed in the same way as a TypeDispatchSubscriber. Patterns may be bound while Events
+// are being received. A SubjectDispatchSubscriber must not be copied after first use.
+type SubjectDispatchSubscriber struct {
+	Subscriber
+	mu       sync.RWMutex
+	bindings []subjectBinding
+	logger   logrus.FieldLogger
+
+	// SuccessStatusCode is written when every Event in a batch was processed successfully. When it is
+	// zero, an HTTP 200 Status Code is written.
+	SuccessStatusCode int
+
+	// BestEffort causes the SuccessStatusCode to be written even when some Events fail to be
+	// processed, so that the Event Grid Topic never redelivers them.
+	BestEffort bool
+
+	// Filter, when set, is consulted before each Event is dispatched. Events for which it returns
+	// false are skipped, and treated as though they were processed successfully.
+	Filter func(Event) bool
+
+	// Dedup, when set, is used to skip Events whose ID has recently been processed successfully. See
+	// `TypeDispatchSubscriber.Dedup` for details.
+	Dedup *Deduplicator
+
+	// Processed, when set, records the IDs of Events which were processed successfully, so that they are
+	// skipped should the Event Grid Topic redeliver their batch. See ProcessedStore for details.
+	Processed ProcessedStore
+
+	// MaxBodyBytes is the largest request body Receive will read. When it is zero, DefaultMaxBodyBytes
+	// is used. When it is negative, the size of the body is not limited.
+	MaxBodyBytes int64
+
+	// MaxEvents is the largest number of Events Receive will accept in a batch. When it is zero,
+	// DefaultMaxEvents is used. When it is negative, the number of Events is not limited.
+	MaxEvents int
+
+	// BeginTransaction, when set, is called to start a Transaction for each batch, making the batch all or
+	// nothing. See `TypeDispatchSubscriber.BeginTransaction` for details.
+	BeginTransaction TransactionBeginner
+}
+
+type subjectBinding struct {
+	pattern string
+	prefix  bool
+	handler EventHandler
+}
+
+func (b subjectBinding) matches(subject string) bool {
+	if b.prefix {
+		return strings.HasPrefix(subject, b.pattern)
+	}
+	matched, err := path.Match(b.pattern, subject)
+	return err == nil && matched
+}
+
+// NewSubjectDispatchSubscriber initializes a new empty SubjectDispatchSubscriber.
+func NewSubjectDispatchSubscriber(parent Subscriber) *SubjectDispatchSubscriber {
+	return &SubjectDispatchSubscriber{
+		Subscriber: parent,
+	}
+}
+
+// Bind ties together a pattern, in the syntax used by `path.Match`, and a function that should handle
+// Events with a matching subject. As with `path.Match`, a "*" does not match across "/" separators.
+func (s *SubjectDispatchSubscriber) Bind(pattern string, handler EventHandler) *SubjectDispatchSubscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bindings = append(s.bindings, subjectBinding{pattern: pattern, handler: handler})
+	return s
+}
+
+// BindPrefix ties together a prefix and a function that should handle Events with a subject that begins
+// with it.
+func (s *SubjectDispatchSubscriber) BindPrefix(prefix string, handler EventHandler) *SubjectDispatchSubscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bindings = append(s.bindings, subjectBinding{pattern: prefix, prefix: true, handler: handler})
+	return s
+}
+
+// WithLogger sets the logger that describes how each Event is dispatched, as `TypeDispatchSubscriber.WithLogger`
+// does. Until a logger is provided, nothing is logged.
+func (s *SubjectDispatchSubscriber) WithLogger(logger logrus.FieldLogger) *SubjectDispatchSubscriber {
+	s.logger = logger
+	return s
+}
+
+func (s *SubjectDispatchSubscriber) log() logrus.FieldLogger {
+	if s.logger == nil {
+		return discardLogger
+	}
+	return s.logger
+}
+
+// Mount registers Handle to respond to requests sent to path on app. POST requests reach Receive, and
+// OPTIONS requests are answered by ReceiveWebHookValidationRequest. Other methods receive an HTTP 405
+// Status Code. The route always dispatches through s, so patterns bound after Mount are honored.
+func (s *SubjectDispatchSubscriber) Mount(app *buffalo.App, path string) *buffalo.RouteInfo {
+	return mountEndpoint(app, path, func(c buffalo.Context) error {
+		return s.Handle(c)
+	})
+}
+
+// Handle is a `buffalo.Handler` which responds to any request sent to the subscriber's endpoint, according
+// to its HTTP method. See Mount for details.
+func (s *SubjectDispatchSubscriber) Handle(c buffalo.Context) error {
+	return handleEndpoint(c, s.Receive)
+}
+
+// Receive is a `buffalo.Handler` which inspects a request sent from an Event Grid Topic, and hands each
+// Event in the batch to the Handler bound to its subject. See `TypeDispatchSubscriber.Receive` for how
+// failures are reported.
+func (s *SubjectDispatchSubscriber) Receive(c buffalo.Context) error {
+	return batchReceiver{
+		maxBodyBytes:      s.MaxBodyBytes,
+		maxEvents:         s.MaxEvents,
+		filter:            s.Filter,
+		dedup:             s.Dedup,
+		processed:         s.Processed,
+		begin:             s.BeginTransaction,
+		logger:            s.logger,
+		bestEffort:        s.BestEffort,
+		successStatusCode: s.SuccessStatusCode,
+	}.receive(c, s.Dispatch)
+}
+
+// Dispatch hands an Event to the first EventHandler, in the order they were bound, whose pattern matches
+// the Event's subject. When none match, an HTTP 400 Status Code is written to the Context.
+func (s *SubjectDispatchSubscriber) Dispatch(c buffalo.Context, event Event) error {
+	pattern, handler, ok := s.Handler(event.Subject)
+	if !ok {
+		s.log().WithFields(eventFields(event)).WithField("subject", event.Subject).Warn("no handler bound for event")
+		return c.Error(http.StatusBadRequest, NoHandlerError{EventType: event.EventType, Subject: event.Subject})
+	}
+
+	logger := s.log().WithFields(eventFields(event)).WithField("binding", pattern)
+	logger.Debug("dispatching event")
+
+	err := handler(c, event)
+	if err != nil {
+		logger.WithError(err).Debug("handler returned an error")
+	} else {
+		logger.Debug("handler finished")
+	}
+	return err
+}
+
+// Handler gets the pattern and EventHandler that an Event with a particular subject is dispatched to. It is
+// found under mu, so that the EventHandler may itself bind more patterns.
+func (s *SubjectDispatchSubscriber) Handler(subject string) (pattern string, handler EventHandler, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, binding := range s.bindings {
+		if binding.matches(subject) {
+			return binding.pattern, binding.handler, true
+		}
+	}
+	return "", nil, false
+}
@@ -0,0 +1,212 @@
+package eventgrid_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/buffalo-azure/sdk/eventgrid"
+	"github.com/gobuffalo/buffalo"
+)
+
+func TestSubjectDispatchSubscriber_Receive(t *testing.T) {
+	var mu sync.Mutex
+	routed := make(map[string]string)
+	record := func(name string) eventgrid.EventHandler {
+		return func(c buffalo.Context, e eventgrid.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			routed[e.ID] = name
+			return nil
+		}
+	}
+
+	subject := eventgrid.NewSubjectDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		Bind("/blobServices/default/containers/*/blobs/*.json", record("json")).
+		BindPrefix("/blobServices/default/containers/images/", record("images")).
+		BindPrefix("/blobServices/", record("blobs"))
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "eventType": "Microsoft.Storage.BlobCreated", "subject": "/blobServices/default/containers/images/blobs/config.json", "data": {}},
+	{"id": "2", "eventType": "Microsoft.Storage.BlobCreated", "subject": "/blobServices/default/containers/images/blobs/cats/cat.png", "data": {}},
+	{"id": "3", "eventType": "Microsoft.Storage.BlobDeleted", "subject": "/blobServices/default/containers/logs/blobs/today.log", "data": {}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	ctx := NewMockContext(req)
+	if err = subject.Receive(ctx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := ctx.Status(); got != http.StatusOK {
+		t.Logf("got status: %d want: %d", got, http.StatusOK)
+		t.Fail()
+	}
+
+	expected := map[string]string{
+		"1": "json",
+		"2": "images",
+		"3": "blobs",
+	}
+	for id, want := range expected {
+		if got := routed[id]; got != want {
+			t.Logf("event %s got: %q want: %q", id, got, want)
+			t.Fail()
+		}
+	}
+}
+
+func TestSubjectDispatchSubscriber_Receive_NoHandler(t *testing.T) {
+	subject := eventgrid.NewSubjectDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		BindPrefix("/blobServices/", func(c buffalo.Context, e eventgrid.Event) error {
+			return nil
+		})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "eventType": "Microsoft.Resources.ResourceWriteSuccess", "subject": "/subscriptions/contoso", "data": {}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	if err = subject.Receive(NewMockContext(req)); err == nil {
+		t.Log("expected an error when no Handler matches the subject")
+		t.Fail()
+	}
+}
+
+func TestSubjectDispatchSubscriber_Mount_BoundAfterMount(t *testing.T) {
+	app := buffalo.New(buffalo.Options{})
+
+	subject := eventgrid.NewSubjectDispatchSubscriber(eventgrid.BaseSubscriber{})
+	subject.Mount(app, "/events")
+
+	var handled bool
+	subject.BindPrefix("/blobServices/default/containers/images/", func(c buffalo.Context, e eventgrid.Event) error {
+		handled = true
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`[{
+	"id": "1",
+	"eventType": "Microsoft.Storage.BlobCreated",
+	"subject": "/blobServices/default/containers/images/blobs/cat.png"
+}]`))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Aeg-Event-Type", "Notification")
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Logf("got status: %d want: %d", resp.Code, http.StatusOK)
+		t.Fail()
+	}
+
+	if !handled {
+		t.Log("the handler bound after Mount was not called")
+		t.Fail()
+	}
+}
+
+func TestSubjectDispatchSubscriber_Dispatch(t *testing.T) {
+	subject := eventgrid.NewSubjectDispatchSubscriber(eventgrid.BaseSubscriber{})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = subject.Dispatch(eventgrid.NewContext(NewMockContext(req)), eventgrid.Event{Subject: "/subscriptions/contoso"})
+	var noHandler eventgrid.NoHandlerError
+	if !errors.Is(err, eventgrid.ErrNoHandler) || !errors.As(err, &noHandler) || noHandler.Subject != "/subscriptions/contoso" {
+		t.Logf("got error: %#v want a NoHandlerError for the subject", err)
+		t.Fail()
+	}
+}
+
+func TestSubjectDispatchSubscriber_Receive_FilterDedup(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	subject := eventgrid.NewSubjectDispatchSubscriber(eventgrid.BaseSubscriber{})
+	subject.Filter = func(e eventgrid.Event) bool {
+		return e.EventType != "Microsoft.Storage.BlobDeleted"
+	}
+	subject.Dedup = &eventgrid.Deduplicator{}
+	subject.BindPrefix("/blobServices/", func(c buffalo.Context, e eventgrid.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[e.ID]++
+		return nil
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "eventType": "Microsoft.Storage.BlobCreated", "subject": "/blobServices/default/containers/images/blobs/cat.png", "data": {}},
+	{"id": "1", "eventType": "Microsoft.Storage.BlobCreated", "subject": "/blobServices/default/containers/images/blobs/cat.png", "data": {}},
+	{"id": "2", "eventType": "Microsoft.Storage.BlobDeleted", "subject": "/blobServices/default/containers/images/blobs/dog.png", "data": {}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	ctx := NewMockContext(req)
+	if err = subject.Receive(ctx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for id, want := range map[string]int{"1": 1, "2": 0} {
+		if got := handled[id]; got != want {
+			t.Logf("event %q was handled %d times, want %d", id, got, want)
+			t.Fail()
+		}
+	}
+}
+
+func TestSubjectDispatchSubscriber_BindWhileReceiving(t *testing.T) {
+	subject := eventgrid.NewSubjectDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		BindPrefix("/blobServices/", func(c buffalo.Context, e eventgrid.Event) error {
+			return nil
+		})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			subject.Bind("/subscriptions/*", func(c buffalo.Context, e eventgrid.Event) error {
+				return nil
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(`[
+	{"id": "1", "eventType": "Microsoft.Storage.BlobCreated", "subject": "/blobServices/default/containers/images/blobs/cat.png", "data": {}}
+]`))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Add("Content-Type", "application/json")
+
+			if err := subject.Receive(NewMockContext(req)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
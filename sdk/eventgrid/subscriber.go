@@ -29,6 +29,10 @@ type Event struct {
 	EventTime       string          `json:"eventTime"`
 	MetadataVersion string          `json:"metadataVersion"`
 	DataVersion     string          `json:"dataVersion"`
+
+	// Raw is the JSON this Event was read from, as it was sent by the Event Grid Topic. It
+	// includes properties which are not modeled here, like custom extension attributes.
+	Raw json.RawMessage `json:"-"`
 }
 
 // UnmarshalData attempts to read the value associated with the "data" property
@@ -41,6 +45,16 @@ func (e Event) UnmarshalData(v interface{}) error {
 // an `Event`.
 type EventHandler func(buffalo.Context, Event) error
 
+// Dispatch calls the EventHandler, allowing it to be used as a `Dispatcher`.
+func (h EventHandler) Dispatch(c buffalo.Context, e Event) error {
+	return h(c, e)
+}
+
+// Dispatcher hands individual Events to the code responsible for processing them.
+type Dispatcher interface {
+	Dispatch(buffalo.Context, Event) error
+}
+
 // Subscriber allows for quick implementation of RESTful actions while
 // working with Event Grid events.
 type Subscriber interface {
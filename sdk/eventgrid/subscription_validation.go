@@ -2,6 +2,7 @@ package eventgrid
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -16,19 +17,32 @@ type SubscriptionValidationRequest struct {
 	ValidationCode uuid.UUID `json:"validationCode,omitempty"`
 }
 
+// These are the headers exchanged during the abuse protection handshake defined by the CloudEvents WebHook
+// specification: https://github.com/cloudevents/spec/blob/v1.0/http-webhook.md#4-abuse-protection
+const (
+	WebHookRequestOriginHeader = "WebHook-Request-Origin"
+	WebHookRequestRateHeader   = "WebHook-Request-Rate"
+	WebHookAllowedOriginHeader = "WebHook-Allowed-Origin"
+	WebHookAllowedRateHeader   = "WebHook-Allowed-Rate"
+)
+
 // SubscriptionValidationMiddleware provides a `buffalo.Handler` which will triage all incoming requests
 // to either submit it for event processing, or echo back the response the server expects to validate a
-// subscription.
+// subscription. OPTIONS requests are answered as a WebHook validation handshake.
 func SubscriptionValidationMiddleware(next buffalo.Handler) buffalo.Handler {
 	return func(c buffalo.Context) error {
+		if c.Request().Method == http.MethodOptions {
+			return ReceiveWebHookValidationRequest(c)
+		}
+
 		if typeHeader := c.Request().Header.Get("Aeg-Event-Type"); strings.EqualFold(typeHeader, "SubscriptionValidation") {
 			var events []Event
 			if err := c.Bind(&events); err != nil {
-				return c.Error(http.StatusBadRequest, err)
+				return c.Error(http.StatusBadRequest, ValidationError{Err: err})
 			}
 
 			if numEvents := len(events); numEvents != 1 {
-				return c.Error(http.StatusBadRequest, fmt.Errorf("expected exactly 1 event, got %d", numEvents))
+				return c.Error(http.StatusBadRequest, ValidationError{Err: fmt.Errorf("expected exactly 1 event, got %d", numEvents)})
 			}
 
 			return ReceiveSubscriptionValidationRequest(c, events[0])
@@ -43,7 +57,7 @@ func ReceiveSubscriptionValidationRequest(c buffalo.Context, e Event) error {
 	var svr SubscriptionValidationRequest
 	err := json.Unmarshal(e.Data, &svr)
 	if err != nil {
-		return c.Error(http.StatusBadRequest, err)
+		return c.Error(http.StatusBadRequest, ValidationError{Err: err})
 	}
 
 	type SubscriptionValidationResponse struct {
@@ -64,3 +78,25 @@ func ReceiveSubscriptionValidationRequest(c buffalo.Context, e Event) error {
 	c.Response().WriteHeader(http.StatusOK)
 	return nil
 }
+
+// ReceiveWebHookValidationRequest is a `buffalo.Handler` which completes the abuse protection handshake that
+// an Event Grid Topic sends as an OPTIONS request before delivering Events in the CloudEvents schema. The
+// origin named by the WebHook-Request-Origin header is echoed in the WebHook-Allowed-Origin header, which
+// permits it to deliver Events. No limit is placed on the rate it may deliver them at.
+func ReceiveWebHookValidationRequest(c buffalo.Context) error {
+	origin := c.Request().Header.Get(WebHookRequestOriginHeader)
+	if origin == "" {
+		return c.Error(http.StatusBadRequest, ValidationError{Err: errors.New("missing " + WebHookRequestOriginHeader + " header")})
+	}
+
+	if logger := c.Logger(); logger != nil {
+		logger.Info("received webhook validation request from: ", origin)
+	}
+
+	c.Response().Header().Set(WebHookAllowedOriginHeader, origin)
+	if c.Request().Header.Get(WebHookRequestRateHeader) != "" {
+		c.Response().Header().Set(WebHookAllowedRateHeader, "*")
+	}
+	c.Response().WriteHeader(http.StatusOK)
+	return nil
+}
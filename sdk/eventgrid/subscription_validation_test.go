@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/Azure/buffalo-azure/sdk/eventgrid"
@@ -123,3 +124,38 @@ func TestSubscriptionValidationMiddleware_OtherEvent(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestReceiveWebHookValidationRequest(t *testing.T) {
+	app := buffalo.New(buffalo.Options{})
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+	subject.Mount(app, "/events")
+
+	req := httptest.NewRequest(http.MethodOptions, "/events", nil)
+	req.Header.Add(eventgrid.WebHookRequestOriginHeader, "eventemitter.example.com")
+	req.Header.Add(eventgrid.WebHookRequestRateHeader, "120")
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Logf("got status: %d want: %d", resp.Code, http.StatusOK)
+		t.Fail()
+	}
+
+	if got, want := resp.Header().Get(eventgrid.WebHookAllowedOriginHeader), "eventemitter.example.com"; got != want {
+		t.Logf("got allowed origin: %q want: %q", got, want)
+		t.Fail()
+	}
+
+	if got := resp.Header().Get(eventgrid.WebHookAllowedRateHeader); got == "" {
+		t.Log("expected an allowed rate when one was requested")
+		t.Fail()
+	}
+
+	missing := httptest.NewRecorder()
+	app.ServeHTTP(missing, httptest.NewRequest(http.MethodOptions, "/events", nil))
+	if missing.Code != http.StatusBadRequest {
+		t.Logf("got status: %d want: %d", missing.Code, http.StatusBadRequest)
+		t.Fail()
+	}
+}
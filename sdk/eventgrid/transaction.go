@@ -0,0 +1,95 @@
+package eventgrid
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"github.com/pkg/errors"
+)
+
+// Transaction groups the side effects of every Event in a batch, so that either all of them take effect, or
+// none do. A `*sql.Tx` satisfies it.
+type Transaction interface {
+	Commit() error
+	Rollback() error
+}
+
+// TransactionBeginner starts the Transaction a batch of Events is processed in.
+type TransactionBeginner func(buffalo.Context) (Transaction, error)
+
+// TransactionFrom finds the Transaction that the Event being handled with c is part of. It returns nil when c
+// was not created by a subscriber with a TransactionBeginner.
+//
+// A Handler processing Events in a Transaction must make all of its changes through it, and must not commit
+// or roll it back itself. Once every Event in the batch is handled, the subscriber commits the Transaction if
+// they all succeeded, and rolls it back otherwise.
+func TransactionFrom(c buffalo.Context) Transaction {
+	if egCtx, ok := c.(*Context); ok {
+		return egCtx.tx
+	}
+	return nil
+}
+
+// dispatchEvents processes a batch of Events, as described by dispatchBatch. When begin is not nil, the
+// batch is instead processed by dispatchTransaction.
+func dispatchEvents(c buffalo.Context, events []Event, processed ProcessedStore, begin TransactionBeginner, maxConcurrency int, dispatch func(buffalo.Context, Event) error) (*BatchError, error) {
+	if begin == nil {
+		return dispatchBatch(c, events, processed, maxConcurrency, dispatch), nil
+	}
+	return dispatchTransaction(c, events, processed, begin, dispatch)
+}
+
+// dispatchTransaction hands each Event to dispatch in the order they appear in the batch, each with its own
+// Context derived from c, which shares a Transaction started by begin. Events are handled one at a time, as
+// most Transactions can't be used concurrently. Should any Event fail, the Transaction is rolled back, and
+// the failures are returned. Otherwise, it is committed.
+//
+// When processed is not nil, Events it has already seen are skipped. Events are only marked in it once the
+// Transaction has been committed.
+func dispatchTransaction(c buffalo.Context, events []Event, processed ProcessedStore, begin TransactionBeginner, dispatch func(buffalo.Context, Event) error) (*BatchError, error) {
+	tx, err := begin(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to begin transaction")
+	}
+
+	batch := NewContext(c)
+	batch.tx = tx
+
+	failures := &BatchError{}
+	succeeded := make([]string, 0, len(events))
+	for _, event := range events {
+		if processed != nil && processed.Seen(event.ID) {
+			continue
+		}
+
+		ctx := batch.ForEvent(event)
+		err := dispatch(ctx, event)
+		if err == nil && ctx.ResponseHasFailure() {
+			err = ErrHandlerFailed
+		}
+
+		if err != nil {
+			failures.Failures = append(failures.Failures, EventError{Event: event, Err: err})
+		} else {
+			succeeded = append(succeeded, event.ID)
+		}
+	}
+
+	if len(failures.Failures) > 0 {
+		if err = tx.Rollback(); err != nil {
+			if logger := c.Logger(); logger != nil {
+				logger.Error("unable to roll back transaction: ", err)
+			}
+		}
+		return failures, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "unable to commit transaction")
+	}
+
+	if processed != nil {
+		for _, id := range succeeded {
+			processed.Mark(id)
+		}
+	}
+	return failures, nil
+}
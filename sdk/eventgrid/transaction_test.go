@@ -0,0 +1,160 @@
+package eventgrid_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/buffalo-azure/sdk/eventgrid"
+	"github.com/gobuffalo/buffalo"
+)
+
+type fakeTransaction struct {
+	committed  bool
+	rolledBack bool
+	writes     []string
+}
+
+func (tx *fakeTransaction) Commit() error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTransaction) Rollback() error {
+	tx.rolledBack = true
+	return nil
+}
+
+func TestTypeDispatchSubscriber_Receive_Transaction(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		wantErr        bool
+		wantCommitted  bool
+		wantRolledBack bool
+		wantWrites     int
+	}{
+		{"all succeed", `[{"id": "1", "eventType": "Contoso.Items.ItemReceived"}, {"id": "2", "eventType": "Contoso.Items.ItemReceived"}]`, false, true, false, 2},
+		{"one fails", `[{"id": "1", "eventType": "Contoso.Items.ItemReceived"}, {"id": "2", "eventType": "Contoso.Items.ItemRejected"}]`, true, false, true, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := &fakeTransaction{}
+
+			subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+			subject.BeginTransaction = func(buffalo.Context) (eventgrid.Transaction, error) {
+				return tx, nil
+			}
+			subject.Bind("Contoso.Items.ItemReceived", func(c buffalo.Context, e eventgrid.Event) error {
+				current, ok := eventgrid.TransactionFrom(c).(*fakeTransaction)
+				if !ok {
+					return errors.New("no transaction found")
+				}
+				current.writes = append(current.writes, e.ID)
+				return nil
+			})
+			subject.Bind("Contoso.Items.ItemRejected", func(c buffalo.Context, e eventgrid.Event) error {
+				return errors.New("item rejected")
+			})
+
+			req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(tc.body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Add("Content-Type", "application/json")
+
+			err = subject.Receive(NewMockContext(req))
+			if (err != nil) != tc.wantErr {
+				t.Logf("got error: %v want error: %v", err, tc.wantErr)
+				t.Fail()
+			}
+
+			if tx.committed != tc.wantCommitted {
+				t.Logf("got committed: %v want: %v", tx.committed, tc.wantCommitted)
+				t.Fail()
+			}
+
+			if tx.rolledBack != tc.wantRolledBack {
+				t.Logf("got rolled back: %v want: %v", tx.rolledBack, tc.wantRolledBack)
+				t.Fail()
+			}
+
+			if len(tx.writes) != tc.wantWrites {
+				t.Logf("got writes: %v want: %d", tx.writes, tc.wantWrites)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_TransactionDedup(t *testing.T) {
+	const batch = `[{"id": "1", "eventType": "Contoso.Items.ItemReceived"}, {"id": "2", "eventType": "Contoso.Items.ItemReceived"}]`
+
+	var transactions []*fakeTransaction
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+	subject.Dedup = &eventgrid.Deduplicator{}
+	subject.BeginTransaction = func(buffalo.Context) (eventgrid.Transaction, error) {
+		tx := &fakeTransaction{}
+		transactions = append(transactions, tx)
+		return tx, nil
+	}
+
+	// The first delivery fails because of event "2", so its Transaction is rolled back, and the batch is
+	// redelivered. Event "1" must be handled again, or its changes would never be committed.
+	subject.Bind("Contoso.Items.ItemReceived", func(c buffalo.Context, e eventgrid.Event) error {
+		current := eventgrid.TransactionFrom(c).(*fakeTransaction)
+		if e.ID == "2" && len(transactions) == 1 {
+			return errors.New("transient failure")
+		}
+		current.writes = append(current.writes, e.ID)
+		return nil
+	})
+
+	for i, wantErr := range []bool{true, false} {
+		req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(batch))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		req.Header.Add("Content-Type", "application/json")
+
+		err = subject.Receive(NewMockContext(req))
+		if got := err != nil; got != wantErr {
+			t.Logf("delivery %d: got error: %v want error: %v", i, err, wantErr)
+			t.Fail()
+		}
+	}
+
+	if len(transactions) != 2 {
+		t.Logf("got %d transactions, want 2", len(transactions))
+		t.FailNow()
+	}
+
+	if committed := transactions[1]; !committed.committed || len(committed.writes) != 2 {
+		t.Logf("redelivery got committed: %v writes: %v, want both events committed", committed.committed, committed.writes)
+		t.Fail()
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if !subject.Dedup.Contains(id) {
+			t.Logf("expected event %q to be remembered once its Transaction was committed", id)
+			t.Fail()
+		}
+	}
+}
+
+func TestTransactionFrom_NoTransaction(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "localhost", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if tx := eventgrid.TransactionFrom(eventgrid.NewContext(NewMockContext(req))); tx != nil {
+		t.Logf("got transaction: %v want: nil", tx)
+		t.Fail()
+	}
+}
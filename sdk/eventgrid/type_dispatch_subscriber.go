@@ -1,50 +1,302 @@
 package eventgrid
 
 import (
-	"errors"
-	"fmt"
+	"encoding/json"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/gobuffalo/buffalo"
+	"github.com/sirupsen/logrus"
 )
 
 // TypeDispatchSubscriber offers an indirection for calling a function when
 // an Event Grid Event has a particular value for the property `eventType`.
 // While the `EventHandler` interface does not itself has
+//
+// Bindings may be added, removed, or Reset while Events are being received. Once something has been
+// bound, copies of a TypeDispatchSubscriber share its bindings, so it may be passed by value wherever a
+// Subscriber is expected.
 type TypeDispatchSubscriber struct {
 	Subscriber
-	bindings          map[string]EventHandler
-	normalizeTypeCase bool
+	mu             *sync.RWMutex
+	bindings       map[string]EventHandler
+	normalizer     func(string) string
+	logger         logrus.FieldLogger
+	maxConcurrency int
+
+	// SuccessStatusCode is written when every Event in a batch was processed successfully. When it is
+	// zero, an HTTP 200 Status Code is written.
+	SuccessStatusCode int
+
+	// BestEffort causes the SuccessStatusCode to be written even when some Events fail to be
+	// processed, so that the Event Grid Topic never redelivers them.
+	BestEffort bool
+
+	// Filter, when set, is consulted before each Event is dispatched. Events for which it returns
+	// false are skipped, and treated as though they were processed successfully.
+	Filter func(Event) bool
+
+	// Dedup, when set, is used to skip Events whose ID has recently been processed successfully, so
+	// that a Handler isn't invoked twice for an Event delivered more than once. Events sharing an ID
+	// within a single batch are only handed to a Handler once. An Event which fails is not remembered,
+	// so it reaches a Handler again when its batch is redelivered.
+	Dedup *Deduplicator
+
+	// Processed, when set, records the IDs of Events which were processed successfully, so that they are
+	// skipped should the Event Grid Topic redeliver their batch. Unlike Dedup, an Event which fails is not
+	// remembered, so only the failed Events in a redelivered batch reach a Handler again.
+	Processed ProcessedStore
+
+	// DispatchAll causes each Event to be handed to every EventHandler that matches its type, rather
+	// than only the most specific one. See Dispatch for details.
+	DispatchAll bool
+
+	// MaxBodyBytes is the largest request body Receive will read. When it is zero, DefaultMaxBodyBytes
+	// is used. When it is negative, the size of the body is not limited.
+	MaxBodyBytes int64
+
+	// MaxEvents is the largest number of Events Receive will accept in a batch. When it is zero,
+	// DefaultMaxEvents is used. When it is negative, the number of Events is not limited.
+	MaxEvents int
+
+	// BeginTransaction, when set, is called to start a Transaction for each batch, making the batch all or
+	// nothing. Its Events are handled one at a time rather than concurrently, and a Handler can find the
+	// Transaction using TransactionFrom. Should any Event fail, the Transaction is rolled back. Otherwise, it
+	// is committed, and if that fails, the batch fails with an HTTP 500 Status Code. Events are only
+	// remembered by Dedup and Processed once the Transaction has been committed.
+	BeginTransaction TransactionBeginner
+
+	// AutoValidateSubscription causes HandlePost, and so routes registered by Mount, to respond to the
+	// subscription validation handshake sent by an Event Grid Topic.
+	AutoValidateSubscription bool
 }
 
-// NewTypeDispatchSubscriber initializes a new empty TypeDispathSubscriber.
-func NewTypeDispatchSubscriber(parent Subscriber) (created *TypeDispatchSubscriber) {
+// TypeDispatchOption configures a TypeDispatchSubscriber as it is created by NewTypeDispatchSubscriber.
+type TypeDispatchOption func(*TypeDispatchSubscriber)
+
+// NewTypeDispatchSubscriber initializes a new empty TypeDispathSubscriber, then applies each of the options
+// provided, in order. Without any options, it behaves exactly as it did before options were accepted:
+//
+//	eventgrid.NewTypeDispatchSubscriber(parent, eventgrid.WithCaseInsensitiveTypes(), eventgrid.WithMaxConcurrency(8))
+func NewTypeDispatchSubscriber(parent Subscriber, options ...TypeDispatchOption) (created *TypeDispatchSubscriber) {
 	created = &TypeDispatchSubscriber{
 		Subscriber: parent,
+		mu:         new(sync.RWMutex),
 		bindings:   make(map[string]EventHandler),
 	}
+	for _, option := range options {
+		option(created)
+	}
 	return
 }
 
+// WithCaseInsensitiveTypes is a TypeDispatchOption which causes Event Types to be matched without regard to
+// case. It is equivalent to calling WithTypeNormalizer with UpperCaseEventType.
+func WithCaseInsensitiveTypes() TypeDispatchOption {
+	return func(s *TypeDispatchSubscriber) {
+		s.WithTypeNormalizer(UpperCaseEventType)
+	}
+}
+
+// WithMaxConcurrency is a TypeDispatchOption which limits how many Events in a batch are handed to their
+// EventHandlers at once. By default, every Event in a batch is dispatched concurrently. Values less than one
+// leave the number unlimited. Batches processed by BeginTransaction are always handled one Event at a time.
+func WithMaxConcurrency(n int) TypeDispatchOption {
+	return func(s *TypeDispatchSubscriber) {
+		s.maxConcurrency = n
+	}
+}
+
+// WithLogger sets the logger that describes how each Event is dispatched. The type and ID of each Event,
+// the binding chosen for it, and the outcome are logged at the debug level. Failures are logged as errors.
+// Until a logger is provided, nothing is logged.
+func (s *TypeDispatchSubscriber) WithLogger(logger logrus.FieldLogger) *TypeDispatchSubscriber {
+	s.logger = logger
+	return s
+}
+
+func (s TypeDispatchSubscriber) log() logrus.FieldLogger {
+	if s.logger == nil {
+		return discardLogger
+	}
+	return s.logger
+}
+
+// lock takes the write lock guarding bindings. It is created here for a TypeDispatchSubscriber that
+// wasn't made by NewTypeDispatchSubscriber, so such a TypeDispatchSubscriber must have something
+// bound to it before it is shared between goroutines.
+func (s *TypeDispatchSubscriber) lock() {
+	if s.mu == nil {
+		s.mu = new(sync.RWMutex)
+	}
+	s.mu.Lock()
+}
+
+// rlock takes the read lock guarding bindings, and returns the function which releases it. Should
+// nothing have been bound yet, there is no lock, and nothing to guard.
+func (s TypeDispatchSubscriber) rlock() (unlock func()) {
+	if s.mu == nil {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// UpperCaseEventType is a normalizer, for use with WithTypeNormalizer, which causes Event Types to be
+// matched without regard to case.
+func UpperCaseEventType(eventType string) string {
+	return strings.ToUpper(eventType)
+}
+
+// WithTypeNormalizer sets a function that is applied to Event Types both when they are bound, and when
+// they are looked up, so that types which normalize to the same string are treated as the same type.
+// Bindings that already exist are normalized again, so it may be called before or after Bind. Should
+// two existing bindings normalize to the same string, only one of them is kept.
+func (s *TypeDispatchSubscriber) WithTypeNormalizer(normalizer func(string) string) *TypeDispatchSubscriber {
+	s.lock()
+	defer s.mu.Unlock()
+
+	s.normalizer = normalizer
+
+	renormalized := make(map[string]EventHandler, len(s.bindings))
+	for eventType, handler := range s.bindings {
+		renormalized[s.normalize(eventType)] = handler
+	}
+	s.bindings = renormalized
+	return s
+}
+
 // Bind ties together an Event Type identifier string and a function that knows how to handle it.
+// Should a function already be bound to that Event Type, it is replaced. Use BindOnce to detect
+// accidentally binding two functions to the same Event Type.
 func (s *TypeDispatchSubscriber) Bind(eventType string, handler EventHandler) *TypeDispatchSubscriber {
-	s.bindings[s.NormalizeEventType(eventType)] = handler
+	s.lock()
+	defer s.mu.Unlock()
+
+	s.bind(eventType, handler)
 	return s
 }
 
+func (s *TypeDispatchSubscriber) bind(eventType string, handler EventHandler) {
+	if s.bindings == nil {
+		s.bindings = make(map[string]EventHandler)
+	}
+	s.bindings[s.normalize(eventType)] = handler
+}
+
+// BindOnce ties together an Event Type identifier string and a function that knows how to handle it,
+// unless a function is already bound to that Event Type. In that case, the existing binding is left
+// alone and a DuplicateBindingError is returned.
+func (s *TypeDispatchSubscriber) BindOnce(eventType string, handler EventHandler) error {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.bindings[s.normalize(eventType)]; ok {
+		return DuplicateBindingError{EventType: eventType}
+	}
+	s.bind(eventType, handler)
+	return nil
+}
+
 // Unbind removes the mapping between an Event Type string and the associated EventHandler, if
 // such a mapping exists.
 func (s *TypeDispatchSubscriber) Unbind(eventType string) *TypeDispatchSubscriber {
-	delete(s.bindings, s.NormalizeEventType(eventType))
+	s.lock()
+	defer s.mu.Unlock()
+
+	delete(s.bindings, s.normalize(eventType))
+	return s
+}
+
+// UnbindWildcard removes the EventHandler bound to `EventTypeWildcard`, if there is one, so that Events
+// without a Handler bound to their type are once again rejected by Dispatch.
+func (s *TypeDispatchSubscriber) UnbindWildcard() *TypeDispatchSubscriber {
+	return s.Unbind(EventTypeWildcard)
+}
+
+// Reset removes every binding, including the wildcard, leaving the TypeDispatchSubscriber as though
+// nothing had been bound to it. Other settings, like the normalizer set by WithTypeNormalizer, are kept.
+// Events already being dispatched finish with the Handlers they were given.
+func (s *TypeDispatchSubscriber) Reset() *TypeDispatchSubscriber {
+	s.lock()
+	defer s.mu.Unlock()
+
+	for eventType := range s.bindings {
+		delete(s.bindings, eventType)
+	}
 	return s
 }
 
-// NormalizeEventType applies casing rules
+// Mount registers Handle to respond to requests sent to path on app. POST requests reach HandlePost, and
+// OPTIONS requests reach HandleOptions. Other methods receive an HTTP 405 Status Code.
+//
+// The route always dispatches through s, so bindings and settings changed after Mount take effect.
+//
+// Mount covers the common case of one subscriber per endpoint. For more advanced routing, HandlePost and
+// HandleOptions can be registered manually, as they are each a `buffalo.Handler`:
+//
+//	app.POST("/events", subscriber.HandlePost)
+//	app.OPTIONS("/events", subscriber.HandleOptions)
+func (s *TypeDispatchSubscriber) Mount(app *buffalo.App, path string) *buffalo.RouteInfo {
+	return mountEndpoint(app, path, func(c buffalo.Context) error {
+		return s.Handle(c)
+	})
+}
+
+// Handle is a `buffalo.Handler` which responds to any request sent to the subscriber's endpoint, according
+// to its HTTP method. See Mount for details.
+func (s TypeDispatchSubscriber) Handle(c buffalo.Context) error {
+	return handleEndpoint(c, s.HandlePost)
+}
+
+// HandlePost is a `buffalo.Handler` for the POST requests an Event Grid Topic delivers Events with. Should
+// AutoValidateSubscription be set, subscription validation requests are answered before reaching Receive.
+func (s TypeDispatchSubscriber) HandlePost(c buffalo.Context) error {
+	if s.AutoValidateSubscription {
+		return SubscriptionValidationMiddleware(s.Receive)(c)
+	}
+	return s.Receive(c)
+}
+
+// HandleOptions is a `buffalo.Handler` for the OPTIONS requests an Event Grid Topic sends to complete the
+// WebHook validation handshake, which is required of endpoints that receive Events in the CloudEvents
+// schema. See ReceiveWebHookValidationRequest.
+func (s TypeDispatchSubscriber) HandleOptions(c buffalo.Context) error {
+	return ReceiveWebHookValidationRequest(c)
+}
+
+// HealthCheck is a `buffalo.Handler` which always responds with an HTTP 200 Status Code, and a JSON body
+// containing the number of Event Types that have Handlers bound to them. It is meant to be registered
+// separately from Receive, as a cheap target for load balancers and monitoring to probe:
+//
+//	app.GET("/events/health", subscriber.HealthCheck)
+func (s TypeDispatchSubscriber) HealthCheck(c buffalo.Context) error {
+	unlock := s.rlock()
+	bound := len(s.bindings)
+	unlock()
+
+	c.Response().Header().Set("Content-Type", "application/json")
+	c.Response().WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(c.Response()).Encode(struct {
+		Status          string `json:"status"`
+		BoundEventTypes int    `json:"boundEventTypes"`
+	}{"ok", bound})
+}
+
+// NormalizeEventType applies the normalizer set by WithTypeNormalizer, if any, to an Event Type.
 func (s TypeDispatchSubscriber) NormalizeEventType(eventType string) string {
-	if s.normalizeTypeCase {
-		eventType = strings.ToUpper(eventType)
+	defer s.rlock()()
+
+	return s.normalize(eventType)
+}
+
+// normalize is NormalizeEventType for callers already holding mu.
+func (s TypeDispatchSubscriber) normalize(eventType string) string {
+	if s.normalizer != nil {
+		eventType = s.normalizer(eventType)
 	}
 	return eventType
 }
@@ -55,44 +307,134 @@ func (s TypeDispatchSubscriber) NormalizeEventType(eventType string) string {
 // Should no Handler be specifically bound to that Event Type string, a default Handler
 // is called.
 // When no Handler is found, even a default, an HTTP 400 Status Code is returned.
+// An empty batch, whether sent as "[]" or as an empty body, is treated as a success. A body that
+// is not a JSON array of Events results in an HTTP 400.
 // Each Event is handed to exactly one Handler. If even one of those handlers returns a
-// response code that is not an HTTP 200 OR 201, this handler will return an HTTP 500.
+// response code that is not an HTTP 200 OR 201, this handler will return an HTTP 500,
+// unless BestEffort is set.
+// Events rejected by Filter, recently seen by Dedup, or already marked in Processed, are not handed to
+// any Handler. When BeginTransaction is set, the batch is processed in a Transaction, as it describes.
 func (s TypeDispatchSubscriber) Receive(c buffalo.Context) error {
-	var events []Event
+	return batchReceiver{
+		maxBodyBytes:      s.MaxBodyBytes,
+		maxEvents:         s.MaxEvents,
+		filter:            s.Filter,
+		dedup:             s.Dedup,
+		processed:         s.Processed,
+		begin:             s.BeginTransaction,
+		maxConcurrency:    s.maxConcurrency,
+		logger:            s.logger,
+		bestEffort:        s.BestEffort,
+		successStatusCode: s.SuccessStatusCode,
+	}.receive(c, s.Dispatch)
+}
 
-	if err := c.Bind(&events); err != nil {
-		return c.Error(http.StatusBadRequest, err)
+// Dispatch hands an Event to the EventHandler bound to its type. Should no EventHandler be bound to
+// that type, the EventHandler bound to `EventTypeWildcard` is used. When neither is present, an HTTP
+// 400 Status Code is written to the Context.
+//
+// When DispatchAll is set, the Event is instead handed to each of: the EventHandler bound to its
+// type, any EventHandler bound to a prefix of its type ending in ".*" (for instance,
+// "Microsoft.Storage.*"), and the EventHandler bound to `EventTypeWildcard`. Every matching
+// EventHandler runs, and their errors are collected into a HandlerErrors.
+func (s TypeDispatchSubscriber) Dispatch(c buffalo.Context, event Event) error {
+	if s.DispatchAll {
+		return s.dispatchAll(c, event)
 	}
 
-	ctx := NewContext(c)
-	var wg sync.WaitGroup
-	for _, event := range events {
-		wg.Add(1)
-		go func(event Event) {
-			if handler, ok := s.Handler(event.EventType); ok {
-				handler(ctx, event)
-			} else if handler, ok = s.Handler(EventTypeWildcard); ok {
-				handler(ctx, event)
-			} else {
-				ctx.Error(http.StatusBadRequest, fmt.Errorf("no Handler found for type %q", event.EventType))
-			}
-			wg.Done()
-		}(event)
+	binding := event.EventType
+	handler, ok := s.Handler(binding)
+	if !ok {
+		binding = EventTypeWildcard
+		handler, ok = s.Handler(binding)
 	}
-	wg.Wait()
 
-	if ctx.ResponseHasFailure() {
-		return c.Error(http.StatusInternalServerError, errors.New("at least one handler failed to process an event in this batch"))
+	if !ok {
+		s.log().WithFields(eventFields(event)).Warn("no handler bound for event")
+		return c.Error(http.StatusBadRequest, NoHandlerError{EventType: event.EventType})
+	}
+	return s.callHandler(c, event, binding, handler)
+}
+
+// callHandler hands an Event to an EventHandler, logging which binding it was chosen by and the outcome.
+func (s TypeDispatchSubscriber) callHandler(c buffalo.Context, event Event, binding string, handler EventHandler) error {
+	logger := s.log().WithFields(eventFields(event)).WithField("binding", binding)
+	logger.Debug("dispatching event")
+
+	err := handler(c, event)
+	if err != nil {
+		logger.WithError(err).Debug("handler returned an error")
+	} else {
+		logger.Debug("handler finished")
+	}
+	return err
+}
+
+// eventFields describes an Event in log entries.
+func eventFields(event Event) logrus.Fields {
+	return logrus.Fields{
+		"eventType": event.EventType,
+		"eventId":   event.ID,
+	}
+}
+
+func (s TypeDispatchSubscriber) dispatchAll(c buffalo.Context, event Event) error {
+	matches, handlers := s.allHandlers(event.EventType)
+
+	if len(matches) == 0 {
+		s.log().WithFields(eventFields(event)).Warn("no handler bound for event")
+		return c.Error(http.StatusBadRequest, NoHandlerError{EventType: event.EventType})
+	}
+
+	var errs HandlerErrors
+	for i, match := range matches {
+		if err := s.callHandler(c, event, match, handlers[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
 	}
-	c.Response().WriteHeader(http.StatusOK)
 	return nil
 }
 
+// allHandlers finds every binding that DispatchAll hands an Event of a given type to, in the order they
+// are called, alongside their EventHandlers. They are collected under mu so that the EventHandlers may
+// themselves change the bindings.
+func (s TypeDispatchSubscriber) allHandlers(eventType string) (matches []string, handlers []EventHandler) {
+	defer s.rlock()()
+
+	eventType = s.normalize(eventType)
+	wildcard := s.normalize(EventTypeWildcard)
+
+	for bound := range s.bindings {
+		if bound == eventType || bound == wildcard {
+			continue
+		}
+		if strings.HasSuffix(bound, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(bound, "*")) {
+			matches = append(matches, bound)
+		}
+	}
+	sort.Strings(matches)
+
+	if _, ok := s.bindings[eventType]; ok {
+		matches = append([]string{eventType}, matches...)
+	}
+	if _, ok := s.bindings[wildcard]; ok {
+		matches = append(matches, wildcard)
+	}
+
+	for _, match := range matches {
+		handlers = append(handlers, s.bindings[match])
+	}
+	return
+}
+
 // Handler gets the EventHandler meant to process a particular Event Grid Event Type.
 func (s TypeDispatchSubscriber) Handler(eventType string) (handler EventHandler, ok bool) {
-	if s.normalizeTypeCase {
-		eventType = strings.ToUpper(eventType)
-	}
-	handler, ok = s.bindings[eventType]
+	defer s.rlock()()
+
+	handler, ok = s.bindings[s.normalize(eventType)]
 	return
 }
@@ -2,17 +2,28 @@ package eventgrid_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
 
 	"github.com/Azure/buffalo-azure/sdk/eventgrid"
 	"github.com/gobuffalo/buffalo"
+	"github.com/sirupsen/logrus"
 )
 
 func ExampleTypeDispatchSubscriber_Receive() {
 	var mySubscriber eventgrid.Subscriber
 	mySubscriber = eventgrid.BaseSubscriber{}
-	mySubscriber = eventgrid.NewTypeDispatchSubscriber(mySubscriber).Bind("Microsoft.Storage.BlobCreated", func(c buffalo.Context, e eventgrid.Event) (err error) {
+	mySubscriber = eventgrid.NewTypeDispatchSubscriber(mySubscriber).Bind(eventgrid.StorageBlobCreated, func(c buffalo.Context, e eventgrid.Event) (err error) {
 		_, err = fmt.Println(e.ID)
 		return
 	})
@@ -56,3 +67,1061 @@ func ExampleTypeDispatchSubscriber_Receive() {
 
 	// Output: 831e1650-001e-001b-66ab-eeb76e069631
 }
+
+func TestTypeDispatchSubscriber_Receive_SuccessStatusCode(t *testing.T) {
+	testCases := []struct {
+		name       string
+		subject    *eventgrid.TypeDispatchSubscriber
+		handler    eventgrid.EventHandler
+		wantStatus int
+		wantErr    bool
+	}{
+		{
+			name:       "default",
+			subject:    eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}),
+			handler:    func(c buffalo.Context, e eventgrid.Event) error { return nil },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "accepted",
+			subject: &eventgrid.TypeDispatchSubscriber{
+				Subscriber:        eventgrid.BaseSubscriber{},
+				SuccessStatusCode: http.StatusAccepted,
+			},
+			handler:    func(c buffalo.Context, e eventgrid.Event) error { return nil },
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:    "failure",
+			subject: eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}),
+			handler: func(c buffalo.Context, e eventgrid.Event) error {
+				return c.Error(http.StatusInternalServerError, errors.New("unable to process event"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "best effort",
+			subject: &eventgrid.TypeDispatchSubscriber{
+				Subscriber: eventgrid.BaseSubscriber{},
+				BestEffort: true,
+			},
+			handler: func(c buffalo.Context, e eventgrid.Event) error {
+				return c.Error(http.StatusInternalServerError, errors.New("unable to process event"))
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			subject := tc.subject.Bind(eventgrid.EventTypeWildcard, tc.handler)
+
+			req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}}]`)))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Add("Content-Type", "application/json")
+
+			ctx := NewMockContext(req)
+			err = subject.Receive(ctx)
+			if tc.wantErr {
+				if err == nil {
+					t.Log("expected an error")
+					t.Fail()
+				}
+				return
+			} else if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if got := ctx.Status(); got != tc.wantStatus {
+				t.Logf("got status: %d want: %d", got, tc.wantStatus)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_Filter(t *testing.T) {
+	var handled []string
+	subject := &eventgrid.TypeDispatchSubscriber{
+		Subscriber: eventgrid.BaseSubscriber{},
+		Filter: func(e eventgrid.Event) bool {
+			return strings.HasPrefix(e.Subject, "/orders/")
+		},
+	}
+	subject.Bind("Contoso.Items.ItemReceived", func(c buffalo.Context, e eventgrid.Event) error {
+		handled = append(handled, e.ID)
+		return nil
+	})
+
+	// The second event has no bound Handler, but because it is filtered out it should not cause
+	// an HTTP 400 to be returned.
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "subject": "/orders/1", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "2", "subject": "/invoices/1", "eventType": "Contoso.Invoices.InvoiceSent", "data": {}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	ctx := NewMockContext(req)
+	if err = subject.Receive(ctx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := ctx.Status(); got != http.StatusOK {
+		t.Logf("got status: %d want: %d", got, http.StatusOK)
+		t.Fail()
+	}
+
+	if len(handled) != 1 || handled[0] != "1" {
+		t.Logf("got handled: %v want: [1]", handled)
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_Dedup(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	subject := &eventgrid.TypeDispatchSubscriber{
+		Subscriber: eventgrid.BaseSubscriber{},
+		Dedup:      &eventgrid.Deduplicator{},
+	}
+	subject.Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[e.ID]++
+		return nil
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "2", "eventType": "Contoso.Items.ItemReceived", "data": {}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	ctx := NewMockContext(req)
+	if err = subject.Receive(ctx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := ctx.Status(); got != http.StatusOK {
+		t.Logf("got status: %d want: %d", got, http.StatusOK)
+		t.Fail()
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if got := handled[id]; got != 1 {
+			t.Logf("event %q was handled %d times, want 1", id, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_DedupRedelivery(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	subject := &eventgrid.TypeDispatchSubscriber{
+		Subscriber: eventgrid.BaseSubscriber{},
+		Dedup:      &eventgrid.Deduplicator{},
+	}
+	subject.Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[e.ID]++
+		if e.ID == "2" && handled[e.ID] == 1 {
+			return errors.New("transient failure")
+		}
+		return nil
+	})
+
+	const batch = `[
+	{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "2", "eventType": "Contoso.Items.ItemReceived", "data": {}}
+]`
+
+	// The first delivery fails because of event "2", so the batch is redelivered. Only event "1" was
+	// remembered, so event "2" reaches the Handler again.
+	for i, wantErr := range []bool{true, false} {
+		req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(batch))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		req.Header.Add("Content-Type", "application/json")
+
+		ctx := NewMockContext(req)
+		err = subject.Receive(ctx)
+		if got := err != nil; got != wantErr {
+			t.Logf("delivery %d: got error: %v want error: %v", i, err, wantErr)
+			t.Fail()
+		}
+	}
+
+	for id, want := range map[string]int{"1": 1, "2": 2} {
+		if got := handled[id]; got != want {
+			t.Logf("event %q was handled %d times, want %d", id, got, want)
+			t.Fail()
+		}
+	}
+
+	if !subject.Dedup.Contains("2") {
+		t.Log("expected event \"2\" to be remembered once it succeeded")
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_Processed(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	subject := &eventgrid.TypeDispatchSubscriber{
+		Subscriber: eventgrid.BaseSubscriber{},
+		Processed:  &eventgrid.MemoryProcessedStore{},
+	}
+	subject.Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[e.ID]++
+		if e.ID == "2" && handled[e.ID] == 1 {
+			return errors.New("transient failure")
+		}
+		return nil
+	})
+
+	const batch = `[
+	{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "2", "eventType": "Contoso.Items.ItemReceived", "data": {}}
+]`
+
+	// The first delivery fails because of event "2", so the batch is redelivered.
+	for i, wantErr := range []bool{true, false} {
+		req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(batch))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		req.Header.Add("Content-Type", "application/json")
+
+		ctx := NewMockContext(req)
+		err = subject.Receive(ctx)
+		if got := err != nil; got != wantErr {
+			t.Logf("delivery %d: got error: %v want error: %v", i, err, wantErr)
+			t.Fail()
+		}
+	}
+
+	for id, want := range map[string]int{"1": 1, "2": 2} {
+		if got := handled[id]; got != want {
+			t.Logf("event %q was handled %d times, want %d", id, got, want)
+			t.Fail()
+		}
+	}
+}
+
+func TestTypeDispatchSubscriber_Mount(t *testing.T) {
+	app := buffalo.New(buffalo.Options{})
+
+	subject := &eventgrid.TypeDispatchSubscriber{
+		Subscriber:               eventgrid.BaseSubscriber{},
+		AutoValidateSubscription: true,
+	}
+	subject.Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+		return nil
+	})
+	subject.Mount(app, "/events")
+
+	req := httptest.NewRequest(http.MethodPost, "/events", bytes.NewReader([]byte(`[{
+	"id": "2d1781af-3a4c-4d7c-bd0c-e34b19da4e66",
+	"eventType": "Microsoft.EventGrid.SubscriptionValidationEvent",
+	"data": {"validationCode": "512d38b6-c7b8-40c8-89fe-f46f9e9622b6"}
+}]`)))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Aeg-Event-Type", "SubscriptionValidation")
+
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Logf("got status: %d want: %d", resp.Code, http.StatusOK)
+		t.Fail()
+	}
+
+	if got := resp.Body.String(); !strings.Contains(got, "512d38b6-c7b8-40c8-89fe-f46f9e9622b6") {
+		t.Logf("validation code was not echoed, got: %q", got)
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_Mount_ChangedAfterMount(t *testing.T) {
+	app := buffalo.New(buffalo.Options{})
+
+	subject := &eventgrid.TypeDispatchSubscriber{
+		Subscriber: eventgrid.BaseSubscriber{},
+	}
+	subject.Mount(app, "/events")
+
+	subject.AutoValidateSubscription = true
+	subject.BestEffort = true
+	subject.Bind("Contoso.Items.ItemReceived", func(c buffalo.Context, e eventgrid.Event) error {
+		return errors.New("item rejected")
+	})
+
+	testCases := []struct {
+		name   string
+		body   string
+		header string
+		want   int
+	}{
+		{
+			name:   "AutoValidateSubscription",
+			body:   `[{"id": "1", "eventType": "Microsoft.EventGrid.SubscriptionValidationEvent", "data": {"validationCode": "512d38b6-c7b8-40c8-89fe-f46f9e9622b6"}}]`,
+			header: "SubscriptionValidation",
+			want:   http.StatusOK,
+		},
+		{
+			name:   "Bind and BestEffort",
+			body:   `[{"id": "2", "eventType": "Contoso.Items.ItemReceived"}]`,
+			header: "Notification",
+			want:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(tc.body))
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("Aeg-Event-Type", tc.header)
+
+			resp := httptest.NewRecorder()
+			app.ServeHTTP(resp, req)
+
+			if resp.Code != tc.want {
+				t.Logf("got status: %d want: %d", resp.Code, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTypeDispatchSubscriber_Mount_Methods(t *testing.T) {
+	app := buffalo.New(buffalo.Options{})
+
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+	subject.Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+		return nil
+	})
+	subject.Mount(app, "/events")
+
+	testCases := []struct {
+		method string
+		want   int
+	}{
+		{http.MethodPost, http.StatusOK},
+		{http.MethodOptions, http.StatusOK},
+		{http.MethodGet, http.StatusMethodNotAllowed},
+		{http.MethodPut, http.StatusMethodNotAllowed},
+		{http.MethodDelete, http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/events", strings.NewReader(`[{"id": "1", "eventType": "Contoso.Items.ItemReceived"}]`))
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add(eventgrid.WebHookRequestOriginHeader, "eventemitter.example.com")
+
+			resp := httptest.NewRecorder()
+			app.ServeHTTP(resp, req)
+
+			if resp.Code != tc.want {
+				t.Logf("got status: %d want: %d", resp.Code, tc.want)
+				t.Fail()
+			}
+
+			if tc.want == http.StatusMethodNotAllowed && resp.Header().Get("Allow") == "" {
+				t.Log("expected an Allow header")
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_EmptyBatch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"empty array", `[]`, false},
+		{"empty body", ``, false},
+		{"whitespace", " \n", false},
+		{"malformed", `not json`, true},
+		{"object", `{"id": "1"}`, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+
+			req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(tc.body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Add("Content-Type", "application/json")
+
+			ctx := NewMockContext(req)
+			err = subject.Receive(ctx)
+			if tc.wantErr {
+				if err == nil {
+					t.Log("expected an error")
+					t.Fail()
+				}
+				return
+			} else if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if got := ctx.Status(); got != http.StatusOK {
+				t.Logf("got status: %d want: %d", got, http.StatusOK)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_MaxBodyBytes(t *testing.T) {
+	const body = `[{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}}]`
+
+	testCases := []struct {
+		name     string
+		maxBytes int64
+		wantErr  bool
+	}{
+		{"default", 0, false},
+		{"unlimited", -1, false},
+		{"exact", int64(len(body)), false},
+		{"too small", int64(len(body)) - 1, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			subject := &eventgrid.TypeDispatchSubscriber{
+				Subscriber:   eventgrid.BaseSubscriber{},
+				MaxBodyBytes: tc.maxBytes,
+			}
+			subject.Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+				return nil
+			})
+
+			req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Add("Content-Type", "application/json")
+
+			err = subject.Receive(NewMockContext(req))
+			if tc.wantErr && err == nil {
+				t.Log("expected an error")
+				t.Fail()
+			} else if !tc.wantErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_MaxPayloadSize(t *testing.T) {
+	body := `[{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": "` + strings.Repeat("a", eventgrid.MaxPayloadSize) + `"}]`
+
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+			return nil
+		})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(body))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	if err = subject.Receive(NewMockContext(req)); err == nil {
+		t.Logf("expected a body larger than %d bytes to be rejected by default", eventgrid.MaxPayloadSize)
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_MaxEvents(t *testing.T) {
+	const body = `[
+	{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "2", "eventType": "Contoso.Items.ItemReceived", "data": {}}
+]`
+
+	testCases := []struct {
+		name       string
+		maxEvents  int
+		wantStatus int
+	}{
+		{"default", 0, http.StatusOK},
+		{"unlimited", -1, http.StatusOK},
+		{"exact", 2, http.StatusOK},
+		{"too small", 1, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			subject := &eventgrid.TypeDispatchSubscriber{
+				Subscriber: eventgrid.BaseSubscriber{},
+				MaxEvents:  tc.maxEvents,
+			}
+			subject.Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+				return nil
+			})
+
+			req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Add("Content-Type", "application/json")
+
+			ctx := NewMockContext(req)
+			subject.Receive(ctx)
+			if got := ctx.Status(); got != tc.wantStatus {
+				t.Logf("got status: %d want: %d", got, tc.wantStatus)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_Raw(t *testing.T) {
+	const event = `{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}, "contosoExtension": "custom"}`
+
+	var mu sync.Mutex
+	var got []byte
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+	subject.Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = e.Raw
+		return nil
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader("["+event+"]"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	if err = subject.Receive(NewMockContext(req)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if string(got) != event {
+		t.Logf("got raw: %q want: %q", got, event)
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_Gzip(t *testing.T) {
+	var mu sync.Mutex
+	var handled []string
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+	subject.Bind(eventgrid.EventTypeWildcard, func(c buffalo.Context, e eventgrid.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, e.ID)
+		return nil
+	})
+
+	compressed := &bytes.Buffer{}
+	writer := gzip.NewWriter(compressed)
+	if _, err := writer.Write([]byte(`[{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}}]`)); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", compressed)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Content-Encoding", "gzip")
+
+	ctx := NewMockContext(req)
+	if err = subject.Receive(ctx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := ctx.Status(); got != http.StatusOK {
+		t.Logf("got status: %d want: %d", got, http.StatusOK)
+		t.Fail()
+	}
+
+	if len(handled) != 1 || handled[0] != "1" {
+		t.Logf("got handled: %v want: [1]", handled)
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_Receive_UnsupportedEncoding(t *testing.T) {
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(`[]`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Content-Encoding", "br")
+
+	if err = subject.Receive(NewMockContext(req)); err == nil {
+		t.Log("expected an error for an unsupported encoding")
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_BindOnce(t *testing.T) {
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{})
+
+	first := func(c buffalo.Context, e eventgrid.Event) error { return errors.New("first") }
+	second := func(c buffalo.Context, e eventgrid.Event) error { return errors.New("second") }
+
+	if err := subject.BindOnce(eventgrid.StorageBlobCreated, first); err != nil {
+		t.Error(err)
+		return
+	}
+
+	err := subject.BindOnce(eventgrid.StorageBlobCreated, second)
+	if _, ok := err.(eventgrid.DuplicateBindingError); !ok {
+		t.Logf("got error: %v want a DuplicateBindingError", err)
+		t.Fail()
+	}
+
+	handler, ok := subject.Handler(eventgrid.StorageBlobCreated)
+	if !ok {
+		t.Log("expected a handler to be bound")
+		t.FailNow()
+	}
+
+	if got := handler(nil, eventgrid.Event{}); got == nil || got.Error() != "first" {
+		t.Logf("got: %v want the first handler to remain bound", got)
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_Dispatch_DispatchAll(t *testing.T) {
+	var called []string
+	record := func(name string, err error) eventgrid.EventHandler {
+		return func(c buffalo.Context, e eventgrid.Event) error {
+			called = append(called, name)
+			return err
+		}
+	}
+
+	subject := &eventgrid.TypeDispatchSubscriber{
+		Subscriber:  eventgrid.BaseSubscriber{},
+		DispatchAll: true,
+	}
+	subject.Bind(eventgrid.StorageBlobCreated, record("exact", nil))
+	prefixFailed := errors.New("prefix failed")
+	subject.Bind("Microsoft.Storage.*", record("prefix", prefixFailed))
+	subject.Bind("Microsoft.Resources.*", record("unrelated", nil))
+	subject.Bind(eventgrid.EventTypeWildcard, record("wildcard", nil))
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = subject.Dispatch(eventgrid.NewContext(NewMockContext(req)), eventgrid.Event{EventType: eventgrid.StorageBlobCreated})
+
+	if errs, ok := err.(eventgrid.HandlerErrors); !ok || len(errs) != 1 {
+		t.Logf("got error: %v want one failure", err)
+		t.Fail()
+	}
+
+	if !errors.Is(err, prefixFailed) {
+		t.Logf("got error: %v want it to match the failure of the prefix handler", err)
+		t.Fail()
+	}
+
+	if want := []string{"exact", "prefix", "wildcard"}; strings.Join(called, ",") != strings.Join(want, ",") {
+		t.Logf("got called: %v want: %v", called, want)
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_WithLogger(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = output
+	logger.Formatter = &logrus.JSONFormatter{}
+	logger.Level = logrus.DebugLevel
+
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		WithLogger(logger).
+		Bind("Contoso.Items.ItemReceived", func(c buffalo.Context, e eventgrid.Event) error {
+			return nil
+		}).
+		Bind("Contoso.Items.ItemLost", func(c buffalo.Context, e eventgrid.Event) error {
+			return errors.New("unable to find item")
+		})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(`[
+	{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "2", "eventType": "Contoso.Items.ItemLost", "data": {}}
+]`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	subject.Receive(NewMockContext(req))
+
+	var dispatched, failed []string
+	dec := json.NewDecoder(output)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Error(err)
+			return
+		}
+
+		switch entry["msg"] {
+		case "dispatching event":
+			dispatched = append(dispatched, entry["binding"].(string))
+		case "event failed to be processed":
+			if entry["level"] != "error" {
+				t.Logf("got level %v for a failure, want error", entry["level"])
+				t.Fail()
+			}
+			failed = append(failed, entry["eventId"].(string))
+		}
+	}
+
+	sort.Strings(dispatched)
+	if want := []string{"Contoso.Items.ItemLost", "Contoso.Items.ItemReceived"}; !reflect.DeepEqual(dispatched, want) {
+		t.Logf("got dispatched: %v want: %v", dispatched, want)
+		t.Fail()
+	}
+
+	if want := []string{"2"}; !reflect.DeepEqual(failed, want) {
+		t.Logf("got failed: %v want: %v", failed, want)
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_WithTypeNormalizer(t *testing.T) {
+	handler := func(c buffalo.Context, e eventgrid.Event) error {
+		return nil
+	}
+
+	caseInsensitive := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		Bind("Microsoft.Storage.BlobCreated", handler).
+		WithTypeNormalizer(eventgrid.UpperCaseEventType)
+
+	if _, ok := caseInsensitive.Handler("microsoft.storage.blobcreated"); !ok {
+		t.Log("expected a Handler bound before the normalizer was set to be found regardless of case")
+		t.Fail()
+	}
+
+	trimmed := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		WithTypeNormalizer(func(eventType string) string {
+			return strings.TrimPrefix(eventType, "Contoso.")
+		}).
+		Bind("Contoso.Items.ItemReceived", handler)
+
+	if _, ok := trimmed.Handler("Items.ItemReceived"); !ok {
+		t.Log("expected a Handler to be found by its normalized type")
+		t.Fail()
+	}
+
+	trimmed.Unbind("Items.ItemReceived")
+	if _, ok := trimmed.Handler("Contoso.Items.ItemReceived"); ok {
+		t.Log("expected Unbind to remove the Handler bound to the normalized type")
+		t.Fail()
+	}
+}
+
+func TestNewTypeDispatchSubscriber_WithCaseInsensitiveTypes(t *testing.T) {
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}, eventgrid.WithCaseInsensitiveTypes()).
+		Bind("Microsoft.Storage.BlobCreated", func(c buffalo.Context, e eventgrid.Event) error {
+			return nil
+		})
+
+	if _, ok := subject.Handler("MICROSOFT.STORAGE.BLOBCREATED"); !ok {
+		t.Log("expected a Handler to be found regardless of case")
+		t.Fail()
+	}
+}
+
+func TestNewTypeDispatchSubscriber_WithMaxConcurrency(t *testing.T) {
+	const body = `[
+	{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "2", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "3", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "4", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "5", "eventType": "Contoso.Items.ItemReceived", "data": {}},
+	{"id": "6", "eventType": "Contoso.Items.ItemReceived", "data": {}}
+]`
+	const limit = 2
+
+	var mu sync.Mutex
+	var running, peak, handled int
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}, eventgrid.WithMaxConcurrency(limit)).
+		Bind("Contoso.Items.ItemReceived", func(c buffalo.Context, e eventgrid.Event) error {
+			mu.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			handled++
+			mu.Unlock()
+			return nil
+		})
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(body))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	ctx := NewMockContext(req)
+	if err = subject.Receive(ctx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if handled != 6 {
+		t.Logf("got handled: %d want: %d", handled, 6)
+		t.Fail()
+	}
+
+	if peak > limit {
+		t.Logf("got %d Events handled at once, want no more than %d", peak, limit)
+		t.Fail()
+	}
+}
+
+func TestTypeDispatchSubscriber_Reset(t *testing.T) {
+	const body = `[{"id": "1", "eventType": "Contoso.Items.ItemReceived", "data": {}}]`
+
+	handler := func(c buffalo.Context, e eventgrid.Event) error {
+		c.Response().WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	// receive reports the Status Code written while dispatching the only Event in body.
+	receive := func(subject *eventgrid.TypeDispatchSubscriber) int {
+		req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(body))
+		if err != nil {
+			t.Error(err)
+			return 0
+		}
+		req.Header.Add("Content-Type", "application/json")
+
+		ctx := eventgrid.NewContext(NewMockContext(req))
+		subject.Receive(ctx)
+		return ctx.EventStatusCodes()["1"]
+	}
+
+	testCases := []struct {
+		name  string
+		clear func(*eventgrid.TypeDispatchSubscriber)
+	}{
+		{"Reset", func(s *eventgrid.TypeDispatchSubscriber) { s.Reset() }},
+		{"UnbindWildcard", func(s *eventgrid.TypeDispatchSubscriber) { s.Unbind("Contoso.Items.ItemReceived").UnbindWildcard() }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+				Bind("Contoso.Items.ItemReceived", handler).
+				Bind(eventgrid.EventTypeWildcard, handler)
+
+			if got := receive(subject); got != http.StatusOK {
+				t.Logf("before clearing, got status: %d want: %d", got, http.StatusOK)
+				t.Fail()
+			}
+
+			tc.clear(subject)
+
+			if _, ok := subject.Handler(eventgrid.EventTypeWildcard); ok {
+				t.Log("expected the wildcard Handler to be removed")
+				t.Fail()
+			}
+
+			if got := receive(subject); got != http.StatusBadRequest {
+				t.Logf("after clearing, got status: %d want: %d", got, http.StatusBadRequest)
+				t.Fail()
+			}
+
+			subject.Bind("Contoso.Items.ItemReceived", handler)
+			if got := receive(subject); got != http.StatusOK {
+				t.Logf("after binding again, got status: %d want: %d", got, http.StatusOK)
+				t.Fail()
+			}
+		})
+	}
+
+	t.Run("Mounted", func(t *testing.T) {
+		app := buffalo.New(buffalo.Options{})
+
+		subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+			Bind(eventgrid.EventTypeWildcard, handler)
+		subject.Mount(app, "/events")
+
+		// serve reports the Status Code written for the whole batch by the mounted route.
+		serve := func() int {
+			req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body))
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("Aeg-Event-Type", "Notification")
+
+			resp := httptest.NewRecorder()
+			app.ServeHTTP(resp, req)
+			return resp.Code
+		}
+
+		if got := serve(); got != http.StatusOK {
+			t.Logf("before Reset, got status: %d want: %d", got, http.StatusOK)
+			t.Fail()
+		}
+
+		subject.Reset()
+		if got := serve(); got == http.StatusOK {
+			t.Log("after Reset, the mounted route still found a Handler")
+			t.Fail()
+		}
+
+		subject.Bind("Contoso.Items.ItemReceived", handler)
+		if got := serve(); got != http.StatusOK {
+			t.Logf("after binding again, got status: %d want: %d", got, http.StatusOK)
+			t.Fail()
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}, eventgrid.WithCaseInsensitiveTypes())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				subject.Bind("Contoso.Items.ItemReceived", handler).Reset()
+			}()
+			go func() {
+				defer wg.Done()
+				receive(subject)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Copied", func(t *testing.T) {
+		subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+			Bind(eventgrid.EventTypeWildcard, handler)
+
+		// A copy is still a Subscriber, and sees the bindings changed through the original.
+		var copied eventgrid.Subscriber = *subject
+
+		// receiveCopy reports the Status Code written while copied dispatches the only Event in body.
+		receiveCopy := func() int {
+			req, err := http.NewRequest(http.MethodPost, "localhost", strings.NewReader(body))
+			if err != nil {
+				t.Error(err)
+				return 0
+			}
+			req.Header.Add("Content-Type", "application/json")
+
+			ctx := eventgrid.NewContext(NewMockContext(req))
+			copied.Receive(ctx)
+			return ctx.EventStatusCodes()["1"]
+		}
+
+		if got := receiveCopy(); got != http.StatusOK {
+			t.Logf("before Reset, got status: %d want: %d", got, http.StatusOK)
+			t.Fail()
+		}
+
+		subject.Reset()
+		if got := receiveCopy(); got != http.StatusBadRequest {
+			t.Logf("after Reset, got status: %d want: %d", got, http.StatusBadRequest)
+			t.Fail()
+		}
+	})
+}
+
+func TestTypeDispatchSubscriber_HealthCheck(t *testing.T) {
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		Bind(eventgrid.StorageBlobCreated, func(c buffalo.Context, e eventgrid.Event) error {
+			return nil
+		}).
+		Bind(eventgrid.StorageBlobDeleted, func(c buffalo.Context, e eventgrid.Event) error {
+			return nil
+		})
+
+	req, err := http.NewRequest(http.MethodGet, "localhost", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := NewMockContext(req)
+	if err = subject.HealthCheck(ctx); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := ctx.Status(); got != http.StatusOK {
+		t.Logf("got status: %d want: %d", got, http.StatusOK)
+		t.Fail()
+	}
+
+	var body struct {
+		Status          string `json:"status"`
+		BoundEventTypes int    `json:"boundEventTypes"`
+	}
+	if err = json.NewDecoder(ctx.Body()).Decode(&body); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if body.Status != "ok" || body.BoundEventTypes != 2 {
+		t.Logf("got: %+v want status \"ok\" and 2 bound event types", body)
+		t.Fail()
+	}
+}
@@ -0,0 +1,31 @@
+package eventgrid
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/pkg/errors"
+)
+
+// JobMapper creates the `worker.Job` that should be enqueued to process an Event.
+type JobMapper func(Event) (worker.Job, error)
+
+// ForwardToWorker creates an EventHandler which hands Events off to a `worker.Worker`, for instance one
+// backed by a Service Bus queue, so that they are processed asynchronously. Should toJob or the Worker
+// return an error, the EventHandler returns it, and the Event is treated as having failed.
+//
+// Because a batch is redelivered by the Event Grid Topic when any Event in it fails, and because the
+// Worker may have accepted a Job before a failure is reported, a Job may be enqueued more than once for
+// the same Event. Jobs should be idempotent, or use the Event ID to recognize repeats.
+func ForwardToWorker(w worker.Worker, toJob JobMapper) EventHandler {
+	return func(c buffalo.Context, e Event) error {
+		job, err := toJob(e)
+		if err != nil {
+			return errors.Wrapf(err, "unable to create job for event %q", e.ID)
+		}
+
+		if err = w.Perform(job); err != nil {
+			return errors.Wrapf(err, "unable to enqueue job for event %q", e.ID)
+		}
+		return nil
+	}
+}
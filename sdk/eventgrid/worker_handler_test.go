@@ -0,0 +1,81 @@
+package eventgrid_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/buffalo-azure/sdk/eventgrid"
+	"github.com/gobuffalo/buffalo/worker"
+)
+
+func TestForwardToWorker(t *testing.T) {
+	w := worker.NewSimple()
+	performed := make(chan string, 1)
+	w.Register("process-blob", func(args worker.Args) error {
+		performed <- args["url"].(string)
+		return nil
+	})
+
+	toJob := func(e eventgrid.Event) (worker.Job, error) {
+		var data struct {
+			URL string `json:"url"`
+		}
+		if err := e.UnmarshalData(&data); err != nil {
+			return worker.Job{}, err
+		}
+		return worker.Job{Handler: "process-blob", Args: worker.Args{"url": data.URL}}, nil
+	}
+
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		Bind(eventgrid.StorageBlobCreated, eventgrid.ForwardToWorker(w, toJob))
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "eventType": "Microsoft.Storage.BlobCreated", "data": {"url": "https://contoso.blob.core.windows.net/images/cat.png"}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	if err = subject.Receive(NewMockContext(req)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	select {
+	case got := <-performed:
+		if want := "https://contoso.blob.core.windows.net/images/cat.png"; got != want {
+			t.Logf("got: %q want: %q", got, want)
+			t.Fail()
+		}
+	case <-time.After(5 * time.Second):
+		t.Log("timed out waiting for the job to be performed")
+		t.Fail()
+	}
+}
+
+func TestForwardToWorker_Failure(t *testing.T) {
+	toJob := func(e eventgrid.Event) (worker.Job, error) {
+		return worker.Job{Handler: "unregistered"}, nil
+	}
+
+	subject := eventgrid.NewTypeDispatchSubscriber(eventgrid.BaseSubscriber{}).
+		Bind(eventgrid.StorageBlobCreated, eventgrid.ForwardToWorker(worker.NewSimple(), toJob))
+
+	req, err := http.NewRequest(http.MethodPost, "localhost", bytes.NewReader([]byte(`[
+	{"id": "1", "eventType": "Microsoft.Storage.BlobCreated", "data": {}}
+]`)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	if err = subject.Receive(NewMockContext(req)); err == nil {
+		t.Log("expected an error when the job can't be enqueued")
+		t.Fail()
+	}
+}